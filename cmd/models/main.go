@@ -1,15 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/josealecrim/audiototext/internal/models"
-	"github.com/josealecrim/audiototext/internal/models/cache"
 	"github.com/josealecrim/audiototext/internal/models/download"
+	"github.com/josealecrim/audiototext/internal/models/registry"
 )
 
 func main() {
@@ -18,6 +19,7 @@ func main() {
 		CachePath:           filepath.Join(".", "models", "cache"),
 		MaxCacheSize:        "10GB",
 		RetentionPeriod:     "30d",
+		KeepLatest:          5,
 		ConcurrentDownloads: 2,
 		DownloadTimeout:     time.Hour,
 		Retries:             3,
@@ -26,78 +28,85 @@ func main() {
 		TargetPlatform:      "cpu",
 	}
 
-	// Cria diretório de cache
-	if err := os.MkdirAll(config.CachePath, 0755); err != nil {
-		log.Fatalf("Erro ao criar diretório de cache: %v", err)
-	}
-
-	// Inicializa gerenciadores
-	downloader := download.NewManager(config)
-	cacheManager, err := cache.NewManager(config)
+	// Abre o registro de modelos
+	modelRegistry, err := registry.New(config)
 	if err != nil {
-		log.Fatalf("Erro ao criar gerenciador de cache: %v", err)
+		log.Fatalf("Erro ao abrir registro de modelos: %v", err)
 	}
 
-	// Lista de modelos para download
-	modelsToDownload := []struct {
-		Type    models.ModelType
-		Version string
-	}{
-		{models.TypeClaude, "v1"},
-		{models.TypeWhisper, "v1"},
+	// Lista de modelos para download; os de maior prioridade saem primeiro
+	requests := []download.Request{
+		{Type: models.TypeWhisper, Version: "1.0.0", Priority: 1},
+		{Type: models.TypeCTC, Version: "1.0.0"},
 	}
 
-	// Download dos modelos
-	for _, m := range modelsToDownload {
-		fmt.Printf("Baixando modelo %s-%s...\n", m.Type, m.Version)
-
-		// Inicia download
-		model, err := downloader.Download(m.Type, m.Version)
+	// Põe todos na fila; config.ConcurrentDownloads rodam ao mesmo tempo
+	var jobs []*download.Job
+	for _, req := range requests {
+		job, err := modelRegistry.Enqueue(req)
 		if err != nil {
-			log.Printf("Erro ao iniciar download de %s-%s: %v\n", m.Type, m.Version, err)
+			log.Printf("Erro ao enfileirar %s@%s: %v\n", req.Type, req.Version, err)
 			continue
 		}
+		jobs = append(jobs, job)
+	}
 
-		// Monitora progresso
-		for {
-			progress, err := downloader.GetProgress(model.Info.ID)
-			if err != nil {
-				log.Printf("Erro ao obter progresso: %v\n", err)
-				break
-			}
-
-			fmt.Printf("\rProgresso: %.2f%% (%s)", progress.Percentage, progress.Status)
-
-			if progress.Status == "concluído" || progress.Status == "erro" {
-				fmt.Println()
-				break
+	// Mostra o progresso de cada download conforme ele chega
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		updates, _ := job.Subscribe()
+		wg.Add(1)
+		go func(modelID string) {
+			defer wg.Done()
+			// Só imprime mudanças de status ou de ponto percentual
+			var last models.Progress
+			for progress := range updates {
+				if progress.Status == last.Status && int(progress.Percentage) == int(last.Percentage) {
+					continue
+				}
+				last = progress
+				fmt.Printf("%s: %.0f%% (%s)\n", modelID, progress.Percentage, progress.Status)
 			}
+		}(job.ID())
+	}
 
-			time.Sleep(time.Second)
-		}
-
-		// Armazena no cache
-		if err := cacheManager.Store(model); err != nil {
-			log.Printf("Erro ao armazenar modelo no cache: %v\n", err)
+	for _, job := range jobs {
+		model, err := job.Wait(context.Background())
+		if err != nil {
+			log.Printf("Erro ao baixar %s: %v\n", job.ID(), err)
 			continue
 		}
+		fmt.Printf("Modelo %s registrado (sha256 %s)\n", model.Info.ID, model.Info.Checksum)
 	}
+	wg.Wait()
 
-	// Lista modelos no cache
-	fmt.Println("\nModelos no cache:")
-	for _, info := range cacheManager.List() {
-		fmt.Printf("- %s (v%s): baixado em %s\n",
-			info.Type, info.Version, info.Downloaded.Format(time.RFC3339))
+	// Lista modelos registrados
+	fmt.Println("\nModelos registrados:")
+	registered, err := modelRegistry.ListModels()
+	if err != nil {
+		log.Fatalf("Erro ao listar modelos: %v", err)
 	}
-
-	// Limpa cache antigo
-	policy := models.CleanPolicy{
-		MaxSize:    1024 * 1024 * 1024 * 10, // 10GB
-		MaxAge:     30 * 24 * time.Hour,     // 30 dias
-		KeepLatest: 5,
+	for _, model := range registered {
+		fmt.Printf("- %s (%s v%s): baixado em %s\n",
+			model.ID, model.Info.Type, model.Info.Version, model.Info.Downloaded.Format(time.RFC3339))
+		if graph := model.Graph; graph != nil {
+			opset, _ := graph.Opset("")
+			fmt.Printf("  opset %d, IR %d, %d nós, %d bytes de pesos\n", opset, graph.IRVersion, graph.Nodes, graph.InitializerBytes)
+			for _, input := range graph.Inputs {
+				fmt.Printf("  entrada %s\n", input)
+			}
+			for _, output := range graph.Outputs {
+				fmt.Printf("  saída %s\n", output)
+			}
+		}
 	}
 
-	if err := cacheManager.Clean(policy); err != nil {
+	// Limpa o cache segundo a política da configuração
+	decisions, err := modelRegistry.Collect()
+	for _, decision := range decisions {
+		log.Println(decision)
+	}
+	if err != nil {
 		log.Printf("Erro ao limpar cache: %v\n", err)
 	}
 }
@@ -2,19 +2,25 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 
 	"github.com/josealecrim/audiototext/internal/hardware"
 	"github.com/josealecrim/audiototext/internal/inference"
 	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/internal/models/registry"
 )
 
 func main() {
+	modelsDir := flag.String("models-dir", "models", "directory holding one subdirectory per model")
+	modelID := flag.String("model", "test-model", "model to run")
+	flag.Parse()
+
 	// Initialize hardware detector
-	hwDetector := hardware.NewDetector()
-	if err := hwDetector.Initialize(); err != nil {
-		log.Fatalf("Failed to initialize hardware detector: %v", err)
+	hwDetector, err := hardware.NewDetector()
+	if err != nil {
+		log.Fatalf("Failed to create hardware detector: %v", err)
 	}
 
 	// Print hardware information
@@ -23,15 +29,14 @@ func main() {
 	// Create session manager
 	manager := inference.NewManager(hwDetector)
 
-	// Create a test model
-	model := &models.ONNXModel{
-		Model: &models.Model{
-			Path:     "models/whisper.onnx",
-			Checksum: "test-checksum",
-		},
-		OptimizationLevel: 1,
-		IsQuantized:       false,
-		ID:                "test-model",
+	// Look up the model in the registry
+	modelRegistry, err := registry.New(models.Config{CachePath: *modelsDir})
+	if err != nil {
+		log.Fatalf("Failed to open model registry: %v", err)
+	}
+	model, err := modelRegistry.GetModel(*modelID)
+	if err != nil {
+		log.Fatalf("Failed to find model: %v", err)
 	}
 
 	// Create session configuration
@@ -59,7 +64,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to create session: %v", err)
 	}
-	defer manager.CloseSession(model.ID)
+	defer manager.CloseSession(session)
 
 	// Create inference handler
 	inf := inference.NewInference(session)
@@ -100,34 +105,26 @@ func printHardwareInfo(hwDetector *hardware.Detector) {
 	fmt.Println("Hardware Information:")
 
 	// Print CPU info
-	cpuInfo := hwDetector.GetCPUInfo()
 	fmt.Printf("\nCPU:\n")
-	fmt.Printf("  Model: %s\n", cpuInfo.Model)
-	fmt.Printf("  Cores: %d\n", cpuInfo.Cores)
-	fmt.Printf("  Frequency: %.2f MHz\n", cpuInfo.Frequency)
-	fmt.Printf("  Cache Size: %d bytes\n", cpuInfo.CacheSize)
+	fmt.Printf("  Model: %s\n", hwDetector.GetCPUModelName())
+	fmt.Printf("  Cores: %d\n", hwDetector.GetCPUCores())
+	fmt.Printf("  Frequency: %.2f MHz\n", hwDetector.GetCPUFrequency())
 
 	// Print GPU info
 	gpuInfo := hwDetector.GetGPUInfo()
-	fmt.Printf("\nGPUs:\n")
-	if len(gpuInfo) == 0 {
-		fmt.Println("  No GPUs detected")
-	} else {
-		for i, gpu := range gpuInfo {
-			fmt.Printf("  GPU %d:\n", i+1)
-			fmt.Printf("    Name: %s\n", gpu.Name)
-			fmt.Printf("    Memory: %d bytes\n", gpu.Memory)
-			fmt.Printf("    CUDA Support: %v\n", gpu.HasCUDA)
-			fmt.Printf("    OpenCL Support: %v\n", gpu.HasOpenCL)
-		}
+	fmt.Printf("\nGPU:\n")
+	fmt.Printf("  CUDA Support: %v\n", gpuInfo.HasCUDA)
+	fmt.Printf("  OpenCL Support: %v\n", gpuInfo.HasOpenCL)
+	fmt.Printf("  Intel GPU: %v\n", gpuInfo.HasIntelGPU)
+	if gpuInfo.AvailableMemory > 0 {
+		fmt.Printf("  Memory: %d bytes\n", gpuInfo.AvailableMemory)
 	}
 
 	// Print memory info
-	memInfo := hwDetector.GetMemoryInfo()
 	fmt.Printf("\nMemory:\n")
-	fmt.Printf("  Total: %d bytes\n", memInfo.Total)
-	fmt.Printf("  Available: %d bytes\n", memInfo.Available)
-	fmt.Printf("  Used: %d bytes\n", memInfo.Used)
+	fmt.Printf("  Total: %d bytes\n", hwDetector.GetTotalMemory())
+	fmt.Printf("  Available: %d bytes\n", hwDetector.GetAvailableMemory())
+	fmt.Printf("  Usage: %.1f%%\n", hwDetector.GetMemoryUsagePercent())
 }
 
 func printResult(result *inference.Result) {
@@ -143,6 +140,8 @@ func printStats(stats inference.Stats) {
 	fmt.Printf("  Total Processing Time: %.3f seconds\n", stats.TotalProcessingTime)
 	fmt.Printf("  Total Batches: %d\n", stats.TotalBatches)
 	fmt.Printf("  Average Batch Size: %.2f\n", stats.AverageBatchSize)
+	fmt.Printf("  Real-Time Factor: %.3f\n", stats.RealTimeFactor)
+	fmt.Printf("  Latency p50/p90/p99: %.3f/%.3f/%.3f seconds\n", stats.LatencyP50, stats.LatencyP90, stats.LatencyP99)
 	if stats.PeakMemoryUsage > 0 {
 		fmt.Printf("  Peak Memory Usage: %d bytes\n", stats.PeakMemoryUsage)
 	}
@@ -2,54 +2,147 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/josealecrim/audiototext/internal/hardware"
 	"github.com/josealecrim/audiototext/internal/inference"
 	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/internal/models/cache"
+	"github.com/josealecrim/audiototext/internal/models/registry"
+	"github.com/josealecrim/audiototext/internal/resultcache"
 	"github.com/josealecrim/audiototext/internal/server"
 	pb "github.com/josealecrim/audiototext/pkg/transcription"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
 )
 
 func main() {
+	modelsDir := flag.String("models-dir", "models", "directory holding one subdirectory per model")
+	modelID := flag.String("model", "test-model", "model to warm up before serving")
+	profile := flag.Bool("profile", false, "record per-request stage traces")
+	adminAddr := flag.String("admin-addr", "", "address for the model admin HTTP endpoints, such as localhost:6061 (empty to disable)")
+	debugAddr := flag.String("debug-addr", ":6060", "address for the debug HTTP endpoints (empty to disable)")
+	cacheEntries := flag.Int("result-cache-entries", 1000, "results kept in memory for repeated audio (0 to disable the cache)")
+	cacheDir := flag.String("result-cache-dir", "", "directory for on-disk cached results (empty to keep them in memory only)")
+	cacheTTL := flag.Duration("result-cache-ttl", 24*time.Hour, "how long cached results stay valid")
+	memoryHeadroom := flag.Int64("memory-headroom-mb", 0, "memory kept out of the model budget in MiB (0 keeps a fifth of the system memory)")
+	retryAfter := flag.Duration("retry-after", 5*time.Second, "retry hint given to requests rejected for lack of memory")
+	maxCacheSize := flag.String("max-cache-size", "", "size the model directory is kept under, such as 50GB or 100GiB (empty for no limit)")
+	retention := flag.String("retention", "", "how long unused models are kept, such as 30d or 2w (empty to keep them)")
+	cleanInterval := flag.Duration("clean-interval", time.Hour, "how often models outside the retention policy are removed (0 to disable)")
+	backupRetention := flag.String("backup-retention", "", "how long model backups are kept, such as 90d (empty to keep them; the latest backup of each model is always kept)")
+	keepBackups := flag.Int("keep-backups", 5, "backups kept per model (0 for no limit)")
+	verifyInterval := flag.Duration("verify-interval", 24*time.Hour, "how often model checksums are re-verified (0 to only verify changed files on load)")
+	flag.Parse()
+
 	// Initialize hardware detector
 	hwDetector, err := hardware.NewDetector()
 	if err != nil {
 		log.Fatalf("Failed to create hardware detector: %v", err)
 	}
 
-	if err := hwDetector.Start(); err != nil {
-		log.Fatalf("Failed to start hardware detector: %v", err)
+	// Open the model registry
+	modelsConfig := models.Config{
+		CachePath:       *modelsDir,
+		DownloadTimeout: time.Hour,
+		MaxCacheSize:    *maxCacheSize,
+		RetentionPeriod: *retention,
+		KeepLatest:      1,
+		CleanInterval:   *cleanInterval,
+		BackupRetention: *backupRetention,
+		KeepBackups:     *keepBackups,
 	}
-
-	// Create model manager
-	modelManager := models.NewManager()
-
-	// Add test model
-	testModel := &models.ONNXModel{
-		Model: &models.Model{
-			Path:     "models/whisper.onnx",
-			Checksum: "test-checksum",
-		},
-		OptimizationLevel: 1,
-		IsQuantized:       false,
-		ID:                "test-model",
+	modelRegistry, err := registry.New(modelsConfig)
+	if err != nil {
+		log.Fatalf("Failed to open model registry: %v", err)
 	}
-	if err := modelManager.AddModel(testModel); err != nil {
-		log.Fatalf("Failed to add test model: %v", err)
+	for invalidID, err := range modelRegistry.Invalid() {
+		log.Printf("Not serving model %s: %v", invalidID, err)
+	}
+	if _, err := modelRegistry.GetModel(*modelID); err != nil {
+		log.Fatalf("Model %s is not in %s: %v", *modelID, *modelsDir, err)
+	}
+
+	// Periodically re-verify checksums, quarantining corrupt models
+	if *verifyInterval > 0 {
+		go modelRegistry.VerifyEvery(context.Background(), *verifyInterval, func(failed map[string]error) {
+			for failedID, err := range failed {
+				log.Printf("Quarantined model %s: %v", failedID, err)
+			}
+		})
 	}
 
 	// Create inference manager
 	inferenceManager := inference.NewManager(hwDetector)
+	inferenceManager.SetProfiling(*profile)
+	inferenceManager.SetMemoryConfig(inference.MemoryConfig{
+		Headroom:   *memoryHeadroom << 20,
+		RetryAfter: *retryAfter,
+	})
+
+	// Remove models outside the retention policy, never ones with open sessions
+	modelRegistry.SetInUse(inferenceManager.HasSessions)
+	// Restored or re-registered models replace the version their sessions loaded
+	modelRegistry.SetOnReplace(inferenceManager.ReplaceModel)
+	if modelsConfig.CleanInterval > 0 {
+		go modelRegistry.CollectEvery(context.Background(), modelsConfig.CleanInterval, func(decisions []cache.Decision, err error) {
+			for _, decision := range decisions {
+				log.Println(decision)
+			}
+			if err != nil {
+				log.Printf("Model cache cleanup failed: %v", err)
+			}
+		})
+	}
 
 	// Create gRPC server
-	srv := server.NewServer(inferenceManager, modelManager)
+	srv := server.NewServer(inferenceManager, modelRegistry)
+
+	// Cache results of repeated audio
+	if *cacheEntries > 0 {
+		resultCache, err := resultcache.New(resultcache.Config{
+			MaxEntries:   *cacheEntries,
+			MaxBytes:     256 << 20,
+			TTL:          *cacheTTL,
+			Dir:          *cacheDir,
+			MaxDiskBytes: 2 << 30,
+		})
+		if err != nil {
+			log.Fatalf("Failed to create result cache: %v", err)
+		}
+		srv.SetResultCache(resultCache)
+	}
+
+	// Serve traces and metrics for debugging
+	if *debugAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/debug/", srv.DebugHandler())
+		mux.Handle("/metrics", promhttp.Handler())
+		go func() {
+			log.Printf("Starting debug HTTP server on %s", *debugAddr)
+			if err := http.ListenAndServe(*debugAddr, mux); err != nil {
+				log.Printf("Debug HTTP server stopped: %v", err)
+			}
+		}()
+	}
+
+	// Serve the model admin endpoints, used to retarget and roll back aliases
+	if *adminAddr != "" {
+		go func() {
+			log.Printf("Starting model admin HTTP server on %s", *adminAddr)
+			if err := http.ListenAndServe(*adminAddr, server.AdminHandler(modelRegistry)); err != nil {
+				log.Printf("Model admin HTTP server stopped: %v", err)
+			}
+		}()
+	}
 
 	// Create gRPC server instance
 	grpcServer := grpc.NewServer()
@@ -70,6 +163,15 @@ func main() {
 		grpcServer.GracefulStop()
 	}()
 
+	// Warm up in the background; requests get Unavailable until it completes
+	go func() {
+		if err := srv.Warmup(context.Background(), []string{*modelID}); err != nil {
+			log.Printf("Warmup failed, server will stay unready: %v", err)
+			return
+		}
+		log.Printf("Warmup complete, server is ready")
+	}()
+
 	// Start server
 	log.Printf("Starting gRPC server on :50051")
 	if err := grpcServer.Serve(lis); err != nil {
@@ -0,0 +1,157 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Audio holds mono samples normalized to [-1, 1]
+type Audio struct {
+	// Samples are the mono samples
+	Samples []float32
+	// SampleRate is the number of samples per second
+	SampleRate int
+}
+
+// Duration returns the length of the audio in seconds
+func (a *Audio) Duration() float64 {
+	if a.SampleRate == 0 {
+		return 0
+	}
+	return float64(len(a.Samples)) / float64(a.SampleRate)
+}
+
+// WAV format codes from the fmt chunk
+const (
+	wavFormatPCM        = 1
+	wavFormatIEEEFloat  = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+// IsWAV reports whether data starts with a RIFF/WAVE header
+func IsWAV(data []byte) bool {
+	return len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WAVE"
+}
+
+// DecodeWAV decodes a PCM (8, 16, 24 or 32 bit) or IEEE float WAV file,
+// mixing multiple channels down to mono
+func DecodeWAV(data []byte) (*Audio, error) {
+	if !IsWAV(data) {
+		return nil, errors.New("not a RIFF/WAVE file")
+	}
+
+	var (
+		format        uint16
+		channels      int
+		sampleRate    int
+		bitsPerSample int
+		payload       []byte
+		haveFormat    bool
+	)
+
+	// Walk the chunks; fmt must come before data
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		body := data[offset+8:]
+		if size > len(body) {
+			// Streaming writers leave the size unset; take what is there
+			size = len(body)
+		}
+		body = body[:size]
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, errors.New("fmt chunk too short")
+			}
+			format = binary.LittleEndian.Uint16(body[0:2])
+			channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+			bitsPerSample = int(binary.LittleEndian.Uint16(body[14:16]))
+			if format == wavFormatExtensible && size >= 26 {
+				format = binary.LittleEndian.Uint16(body[24:26])
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return nil, errors.New("data chunk before fmt chunk")
+			}
+			payload = body
+		}
+
+		// Chunks are padded to an even size
+		offset += 8 + size + size%2
+	}
+
+	if !haveFormat {
+		return nil, errors.New("missing fmt chunk")
+	}
+	if payload == nil {
+		return nil, errors.New("missing data chunk")
+	}
+	if channels < 1 || sampleRate < 1 {
+		return nil, errors.Errorf("invalid WAV header: %d channels at %d Hz", channels, sampleRate)
+	}
+
+	decode, err := sampleDecoder(format, bitsPerSample)
+	if err != nil {
+		return nil, err
+	}
+
+	bytesPerSample := bitsPerSample / 8
+	frameSize := bytesPerSample * channels
+	frames := len(payload) / frameSize
+	samples := make([]float32, frames)
+	for i := 0; i < frames; i++ {
+		frame := payload[i*frameSize : (i+1)*frameSize]
+		var sum float32
+		for c := 0; c < channels; c++ {
+			sum += decode(frame[c*bytesPerSample : (c+1)*bytesPerSample])
+		}
+		samples[i] = sum / float32(channels)
+	}
+
+	return &Audio{Samples: samples, SampleRate: sampleRate}, nil
+}
+
+// DecodePCM16 decodes headerless little-endian signed 16-bit mono PCM
+func DecodePCM16(data []byte, sampleRate int) *Audio {
+	samples := make([]float32, len(data)/2)
+	for i := range samples {
+		samples[i] = float32(int16(binary.LittleEndian.Uint16(data[i*2:]))) / 32768
+	}
+	return &Audio{Samples: samples, SampleRate: sampleRate}
+}
+
+// sampleDecoder returns a function converting one encoded sample to a float
+func sampleDecoder(format uint16, bitsPerSample int) (func([]byte) float32, error) {
+	switch {
+	case format == wavFormatPCM && bitsPerSample == 8:
+		return func(b []byte) float32 { return (float32(b[0]) - 128) / 128 }, nil
+	case format == wavFormatPCM && bitsPerSample == 16:
+		return func(b []byte) float32 {
+			return float32(int16(binary.LittleEndian.Uint16(b))) / 32768
+		}, nil
+	case format == wavFormatPCM && bitsPerSample == 24:
+		return func(b []byte) float32 {
+			v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+			return float32(v) / 8388608
+		}, nil
+	case format == wavFormatPCM && bitsPerSample == 32:
+		return func(b []byte) float32 {
+			return float32(int32(binary.LittleEndian.Uint32(b))) / 2147483648
+		}, nil
+	case format == wavFormatIEEEFloat && bitsPerSample == 32:
+		return func(b []byte) float32 {
+			return math.Float32frombits(binary.LittleEndian.Uint32(b))
+		}, nil
+	case format == wavFormatIEEEFloat && bitsPerSample == 64:
+		return func(b []byte) float32 {
+			return float32(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		}, nil
+	}
+	return nil, errors.Errorf("unsupported WAV encoding: format %d, %d bits", format, bitsPerSample)
+}
@@ -0,0 +1,53 @@
+package audio
+
+// Resample converts audio to the target sample rate using linear
+// interpolation. Downsampling averages the samples under each output
+// sample first so high frequencies do not fold back as noise.
+func Resample(a *Audio, targetRate int) *Audio {
+	if a.SampleRate == targetRate || len(a.Samples) == 0 {
+		return &Audio{Samples: a.Samples, SampleRate: targetRate}
+	}
+
+	ratio := float64(a.SampleRate) / float64(targetRate)
+	input := a.Samples
+	if ratio > 1 {
+		input = boxFilter(input, int(ratio+0.5))
+	}
+
+	n := int(float64(len(input)) / ratio)
+	out := make([]float32, n)
+	for i := range out {
+		pos := float64(i) * ratio
+		j := int(pos)
+		frac := float32(pos - float64(j))
+		if j+1 < len(input) {
+			out[i] = input[j]*(1-frac) + input[j+1]*frac
+		} else {
+			out[i] = input[len(input)-1]
+		}
+	}
+
+	return &Audio{Samples: out, SampleRate: targetRate}
+}
+
+// boxFilter returns the moving average of samples over width samples
+func boxFilter(samples []float32, width int) []float32 {
+	if width <= 1 {
+		return samples
+	}
+
+	out := make([]float32, len(samples))
+	var sum float32
+	for i, s := range samples {
+		sum += s
+		if i >= width {
+			sum -= samples[i-width]
+		}
+		count := width
+		if i+1 < width {
+			count = i + 1
+		}
+		out[i] = sum / float32(count)
+	}
+	return out
+}
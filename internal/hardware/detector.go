@@ -25,6 +25,7 @@ type CacheInfo struct {
 type GPUInfo struct {
 	HasCUDA         bool
 	HasOpenCL       bool
+	HasIntelGPU     bool
 	CUDAVersion     string
 	OpenCLVersion   string
 	AvailableMemory int64
@@ -40,6 +41,7 @@ type MemoryInfo struct {
 type Detector struct {
 	cpuInfo    []cpu.InfoStat
 	memoryInfo *mem.VirtualMemoryStat
+	gpuInfo    GPUInfo
 }
 
 // NewDetector creates a new hardware detector
@@ -57,6 +59,7 @@ func NewDetector() (*Detector, error) {
 	return &Detector{
 		cpuInfo:    cpuInfo,
 		memoryInfo: memInfo,
+		gpuInfo:    detectGPU(),
 	}, nil
 }
 
@@ -70,6 +73,21 @@ func (d *Detector) GetMemoryInfo() (*mem.VirtualMemoryStat, error) {
 	return d.memoryInfo, nil
 }
 
+// GetGPUInfo returns GPU information
+func (d *Detector) GetGPUInfo() GPUInfo {
+	return d.gpuInfo
+}
+
+// HasCUDAGPU reports whether an NVIDIA GPU with a CUDA driver was detected
+func (d *Detector) HasCUDAGPU() bool {
+	return d.gpuInfo.HasCUDA
+}
+
+// HasIntelGPU reports whether an Intel GPU usable by OpenVINO was detected
+func (d *Detector) HasIntelGPU() bool {
+	return d.gpuInfo.HasIntelGPU
+}
+
 // GetNumCPUs returns the number of CPUs
 func (d *Detector) GetNumCPUs() int {
 	return runtime.NumCPU()
@@ -127,5 +145,10 @@ func (d *Detector) GetHardwareInfo() map[string]interface{} {
 			"available": d.GetAvailableMemory(),
 			"usage":     d.GetMemoryUsagePercent(),
 		},
+		"gpu": map[string]interface{}{
+			"cuda":   d.gpuInfo.HasCUDA,
+			"intel":  d.gpuInfo.HasIntelGPU,
+			"opencl": d.gpuInfo.HasOpenCL,
+		},
 	}
 }
@@ -0,0 +1,41 @@
+package hardware
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// intelVendorID is the PCI vendor ID reported by Intel devices
+const intelVendorID = "0x8086"
+
+// detectGPU probes the system for GPUs usable by the ONNX Runtime execution providers
+func detectGPU() GPUInfo {
+	info := GPUInfo{}
+
+	// NVIDIA driver exposes its version under /proc on Linux; nvidia-smi covers other platforms
+	if data, err := os.ReadFile("/proc/driver/nvidia/version"); err == nil {
+		info.HasCUDA = true
+		info.CUDAVersion = strings.TrimSpace(strings.SplitN(string(data), "\n", 2)[0])
+	} else if _, err := exec.LookPath("nvidia-smi"); err == nil {
+		info.HasCUDA = true
+	}
+
+	// Intel GPUs show up as DRM devices with Intel's PCI vendor ID
+	vendors, _ := filepath.Glob("/sys/class/drm/card*/device/vendor")
+	for _, path := range vendors {
+		data, err := os.ReadFile(path)
+		if err == nil && strings.TrimSpace(string(data)) == intelVendorID {
+			info.HasIntelGPU = true
+			break
+		}
+	}
+
+	// Installable client drivers are registered under /etc/OpenCL/vendors
+	if icds, _ := filepath.Glob("/etc/OpenCL/vendors/*.icd"); len(icds) > 0 {
+		info.HasOpenCL = true
+	}
+
+	return info
+}
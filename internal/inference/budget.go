@@ -0,0 +1,192 @@
+package inference
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/models"
+)
+
+const (
+	// footprintFactor scales a model's weights to the memory its session
+	// holds, covering the runtime's arena and activations
+	footprintFactor = 2
+	// sessionOverhead is the memory every session holds regardless of its model
+	sessionOverhead = 32 << 20
+	// defaultHeadroomFraction is the share of the detected memory left to
+	// the OS, the server and request buffers when no headroom is configured
+	defaultHeadroomFraction = 0.2
+	// defaultRetryAfter is the retry hint given to rejected work
+	defaultRetryAfter = 5 * time.Second
+)
+
+// MemoryConfig controls the memory budget sessions are admitted against
+type MemoryConfig struct {
+	// Headroom is the memory kept out of the budget; 0 keeps a fifth of the
+	// detected memory
+	Headroom int64
+	// Limit caps the budget below the detected memory; 0 means no cap
+	Limit int64
+	// RetryAfter is the hint given to work rejected while sessions are busy
+	RetryAfter time.Duration
+}
+
+// MemoryStatus reports the memory budget and the part reserved by open sessions
+type MemoryStatus struct {
+	Budget   int64
+	Reserved int64
+}
+
+// MemoryExhaustedError rejects a session whose model doesn't fit in the
+// memory budget even after idle sessions were evicted
+type MemoryExhaustedError struct {
+	ModelID string
+	// Required is the model's estimated footprint
+	Required int64
+	// Available is the unreserved part of the budget
+	Available int64
+	// RetryAfter suggests when to try again; 0 when the model is larger
+	// than the whole budget and can never be loaded
+	RetryAfter time.Duration
+}
+
+// Error implements error
+func (e *MemoryExhaustedError) Error() string {
+	return fmt.Sprintf("model %s needs %d MiB but only %d MiB of the memory budget is free",
+		e.ModelID, e.Required>>20, e.Available>>20)
+}
+
+// EstimateFootprint estimates the memory a session of model holds from the
+// size of its weights; a bare handle only counts the session overhead
+func EstimateFootprint(model *models.ONNXModel) int64 {
+	if model.Model == nil {
+		return sessionOverhead
+	}
+
+	size := model.Info.Size
+	if info, err := os.Stat(model.Path); err == nil {
+		size = info.Size()
+	}
+	return size*footprintFactor + sessionOverhead
+}
+
+// memoryBudget tracks the memory reserved by open sessions
+type memoryBudget struct {
+	mu sync.Mutex
+	// limit is the memory sessions may reserve
+	limit int64
+	// reserved is the memory reserved by open sessions
+	reserved int64
+}
+
+// reserve takes bytes from the budget, reporting whether they fit
+func (b *memoryBudget) reserve(bytes int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.reserved+bytes > b.limit {
+		return false
+	}
+	b.reserved += bytes
+	return true
+}
+
+// release returns bytes to the budget
+func (b *memoryBudget) release(bytes int64) {
+	b.mu.Lock()
+	b.reserved -= bytes
+	b.mu.Unlock()
+}
+
+// status returns the budget and the memory reserved from it
+func (b *memoryBudget) status() MemoryStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return MemoryStatus{Budget: b.limit, Reserved: b.reserved}
+}
+
+// setLimit changes the memory sessions may reserve; sessions already open
+// keep their reservations
+func (b *memoryBudget) setLimit(limit int64) {
+	b.mu.Lock()
+	b.limit = limit
+	b.mu.Unlock()
+}
+
+// SetMemoryConfig sets the memory budget from the detected memory and the
+// given headroom; it applies to sessions opened afterwards
+func (m *Manager) SetMemoryConfig(config MemoryConfig) {
+	total := int64(m.hwDetector.GetTotalMemory())
+	if config.Headroom <= 0 {
+		config.Headroom = int64(float64(total) * defaultHeadroomFraction)
+	}
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = defaultRetryAfter
+	}
+
+	limit := total - config.Headroom
+	if config.Limit > 0 && config.Limit < limit {
+		limit = config.Limit
+	}
+	if limit < 0 {
+		limit = 0
+	}
+
+	m.mu.Lock()
+	m.memoryConfig = config
+	m.mu.Unlock()
+	m.budget.setLimit(limit)
+}
+
+// MemoryStatus returns the memory budget and the part reserved by open sessions
+func (m *Manager) MemoryStatus() MemoryStatus {
+	return m.budget.status()
+}
+
+// admit reserves a session's footprint, evicting idle pooled sessions from
+// least to most recently used until it fits
+func (m *Manager) admit(model *models.ONNXModel, footprint int64) error {
+	if m.budget.reserve(footprint) {
+		return nil
+	}
+
+	m.mu.RLock()
+	retryAfter := m.memoryConfig.RetryAfter
+	pools := make([]*sessionPool, 0, len(m.pools))
+	for _, pool := range m.pools {
+		pools = append(pools, pool)
+	}
+	m.mu.RUnlock()
+
+	if status := m.budget.status(); footprint > status.Budget {
+		return &MemoryExhaustedError{ModelID: model.ID, Required: footprint, Available: status.Budget - status.Reserved}
+	}
+
+	var idle []idleSession
+	for _, pool := range pools {
+		idle = append(idle, pool.idleSessions()...)
+	}
+	sort.Slice(idle, func(i, j int) bool { return idle[i].lastUsed.Before(idle[j].lastUsed) })
+
+	for _, candidate := range idle {
+		pool := candidate.session.pool
+		if pool.evict(candidate.session) {
+			// Close errors only affect the session being discarded
+			_ = pool.close(candidate.session)
+		}
+		if m.budget.reserve(footprint) {
+			return nil
+		}
+	}
+
+	status := m.budget.status()
+	return &MemoryExhaustedError{
+		ModelID:    model.ID,
+		Required:   footprint,
+		Available:  status.Budget - status.Reserved,
+		RetryAfter: retryAfter,
+	}
+}
@@ -0,0 +1,234 @@
+package inference
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// calibrationFile is the name of the calibration settings in a model's directory
+const calibrationFile = "calibration.json"
+
+// Calibrator maps raw confidences, geometric means of token probabilities,
+// to probabilities that the text is correct
+type Calibrator interface {
+	// Calibrate returns the calibrated confidence of a raw confidence in [0, 1]
+	Calibrate(confidence float64) float64
+}
+
+// TemperatureCalibrator scales a confidence's logit by 1/Temperature;
+// temperatures above 1 soften overconfident models
+type TemperatureCalibrator struct {
+	Temperature float64
+}
+
+// Calibrate implements Calibrator
+func (c TemperatureCalibrator) Calibrate(confidence float64) float64 {
+	if c.Temperature <= 0 || confidence <= 0 || confidence >= 1 {
+		return confidence
+	}
+	logit := math.Log(confidence / (1 - confidence))
+	return 1 / (1 + math.Exp(-logit/c.Temperature))
+}
+
+// CalibrationPoint maps a raw confidence to its calibrated value
+type CalibrationPoint struct {
+	Raw        float64 `json:"raw"`
+	Calibrated float64 `json:"calibrated"`
+}
+
+// IsotonicCalibrator interpolates a non-decreasing table fitted with
+// isotonic regression; confidences outside the table take the nearest end
+type IsotonicCalibrator struct {
+	// Points are sorted by Raw
+	Points []CalibrationPoint
+}
+
+// NewIsotonicCalibrator validates a table, which must be non-decreasing once sorted
+func NewIsotonicCalibrator(points []CalibrationPoint) (*IsotonicCalibrator, error) {
+	if len(points) == 0 {
+		return nil, errors.New("isotonic calibration table is empty")
+	}
+
+	sorted := append([]CalibrationPoint{}, points...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Raw < sorted[j].Raw })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Calibrated < sorted[i-1].Calibrated {
+			return nil, errors.Errorf("isotonic calibration table decreases at raw confidence %.3f", sorted[i].Raw)
+		}
+	}
+	return &IsotonicCalibrator{Points: sorted}, nil
+}
+
+// Calibrate implements Calibrator
+func (c *IsotonicCalibrator) Calibrate(confidence float64) float64 {
+	points := c.Points
+	i := sort.Search(len(points), func(i int) bool { return points[i].Raw >= confidence })
+	switch {
+	case i == 0:
+		return points[0].Calibrated
+	case i == len(points):
+		return points[len(points)-1].Calibrated
+	}
+
+	lo, hi := points[i-1], points[i]
+	if hi.Raw == lo.Raw {
+		return hi.Calibrated
+	}
+	t := (confidence - lo.Raw) / (hi.Raw - lo.Raw)
+	return lo.Calibrated + t*(hi.Calibrated-lo.Calibrated)
+}
+
+// calibrationSettings is the format of calibration.json:
+//
+//	{"method": "temperature", "temperature": 1.5}
+//	{"method": "isotonic", "points": [{"raw": 0.5, "calibrated": 0.3}, ...]}
+type calibrationSettings struct {
+	Method      string             `json:"method"`
+	Temperature float64            `json:"temperature"`
+	Points      []CalibrationPoint `json:"points"`
+}
+
+// LoadCalibration loads the calibration.json of a model directory; it
+// returns nil when the model has none, leaving confidences uncalibrated
+func LoadCalibration(modelDir string) (Calibrator, error) {
+	path := filepath.Join(modelDir, calibrationFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read calibration")
+	}
+
+	var settings calibrationSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse calibration %s", path)
+	}
+
+	switch settings.Method {
+	case "temperature":
+		if settings.Temperature <= 0 {
+			return nil, errors.Errorf("calibration %s: temperature must be positive", path)
+		}
+		return TemperatureCalibrator{Temperature: settings.Temperature}, nil
+	case "isotonic":
+		calibrator, err := NewIsotonicCalibrator(settings.Points)
+		if err != nil {
+			return nil, errors.Wrapf(err, "calibration %s", path)
+		}
+		return calibrator, nil
+	default:
+		return nil, errors.Errorf("calibration %s: unknown method %q", path, settings.Method)
+	}
+}
+
+// calibrate applies an optional calibrator
+func calibrate(calibrator Calibrator, confidence float64) float32 {
+	if calibrator != nil {
+		confidence = calibrator.Calibrate(confidence)
+	}
+	return float32(confidence)
+}
+
+// WordScore is a decoded word with the mean log-probability of its tokens
+type WordScore struct {
+	Word       string
+	AvgLogProb float64
+	// Start and End are the word's times within the window in seconds,
+	// both 0 when the decoder doesn't know them
+	Start, End float32
+}
+
+// scoreWords groups a hypothesis's tokens into the words of its text. A
+// token belongs to the last word of the text decoded up to and including it.
+func scoreWords(tokenizer Tokenizer, hypothesis *Hypothesis) []WordScore {
+	words := strings.Fields(hypothesis.Text)
+	if len(words) == 0 {
+		return nil
+	}
+
+	sums := make([]float64, len(words))
+	counts := make([]int, len(words))
+	for i := range hypothesis.Tokens {
+		word := len(strings.Fields(tokenizer.Decode(hypothesis.Tokens[:i+1]))) - 1
+		if word < 0 {
+			word = 0
+		}
+		if word >= len(words) {
+			word = len(words) - 1
+		}
+		sums[word] += float64(hypothesis.LogProbs[i])
+		counts[word]++
+	}
+
+	scores := make([]WordScore, len(words))
+	for i, word := range words {
+		scores[i] = WordScore{Word: word}
+		if counts[i] > 0 {
+			scores[i].AvgLogProb = sums[i] / float64(counts[i])
+		}
+	}
+	return scores
+}
+
+// wordResults scores the words of a window's final text, which the
+// safeguards may have cut down from the decoded words. Words without token
+// scores take the window's confidence. Times come from the decoder when it
+// knows them and are otherwise estimated by spreading the window's duration
+// over the words by character count.
+func wordResults(text string, scores []WordScore, windowConfidence float32, duration float32, calibrator Calibrator) []WordResult {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return nil
+	}
+
+	total := 0
+	for _, word := range words {
+		total += utf8.RuneCountInString(word)
+	}
+
+	results := make([]WordResult, len(words))
+	next, chars := 0, 0
+	for i, word := range words {
+		start := duration * float32(chars) / float32(total)
+		chars += utf8.RuneCountInString(word)
+		results[i] = WordResult{
+			Word:       word,
+			Start:      start,
+			End:        duration * float32(chars) / float32(total),
+			Confidence: windowConfidence,
+		}
+
+		// Final words appear in the decoded words in order
+		for j := next; j < len(scores); j++ {
+			if scores[j].Word == word {
+				results[i].Confidence = calibrate(calibrator, math.Exp(scores[j].AvgLogProb))
+				if scores[j].End > 0 {
+					results[i].Start, results[i].End = scores[j].Start, scores[j].End
+				}
+				next = j + 1
+				break
+			}
+		}
+	}
+	return results
+}
+
+// MeanWordConfidence returns the mean confidence of words, or fallback when there are none
+func MeanWordConfidence(words []WordResult, fallback float32) float32 {
+	if len(words) == 0 {
+		return fallback
+	}
+	var sum float32
+	for _, word := range words {
+		sum += word.Confidence
+	}
+	return sum / float32(len(words))
+}
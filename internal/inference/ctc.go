@@ -0,0 +1,507 @@
+package inference
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ctcConfigFile is the name of the CTC decoding settings in a model's directory
+	ctcConfigFile = "ctc.json"
+	// ctcPruneLogProb skips labels this far below a frame's best label during beam search
+	ctcPruneLogProb = 8
+)
+
+// StageCTCDecode is the trace stage of CTC decoding
+const StageCTCDecode = "ctc_decode"
+
+// Emissions are a CTC acoustic model's outputs for one window
+type Emissions struct {
+	// LogProbs holds a row of label log-probabilities per frame
+	LogProbs [][]float32
+	// FrameDuration is the audio time each frame covers, in seconds
+	FrameDuration float64
+}
+
+// CTCRuntime is implemented by runtimes of CTC acoustic models such as
+// wav2vec2, which take normalized waveforms and emit per-frame label scores
+type CTCRuntime interface {
+	ONNXRuntime
+	// Emissions runs the acoustic model over a batch of normalized waveforms
+	Emissions(ctx context.Context, waveforms [][]float32) ([]*Emissions, error)
+	// Labels returns the output labels, indexed by ID
+	Labels() []string
+}
+
+// CTCConfig controls CTC decoding; it is read from ctc.json in the model
+// directory, with defaults matching wav2vec2 vocabularies
+type CTCConfig struct {
+	// Blank is the CTC blank label
+	Blank string `json:"blank"`
+	// WordDelimiter is the label separating words
+	WordDelimiter string `json:"word_delimiter"`
+	// BeamWidth overrides the request's beam size; 1 decodes greedily
+	BeamWidth int `json:"beam_width"`
+	// LM is the ARPA language model file, relative to the model directory;
+	// a missing file decodes without a language model
+	LM string `json:"lm"`
+	// LMWeight scales language model scores against acoustic scores
+	LMWeight float64 `json:"lm_weight"`
+	// WordBonus is added per word to offset the language model's length penalty
+	WordBonus float64 `json:"word_bonus"`
+}
+
+// DefaultCTCConfig returns the settings used when a model has no ctc.json
+func DefaultCTCConfig() CTCConfig {
+	return CTCConfig{
+		Blank:         "<pad>",
+		WordDelimiter: "|",
+		LM:            "lm.arpa",
+		LMWeight:      0.5,
+		WordBonus:     1.0,
+	}
+}
+
+// ctcModel holds the decoding settings and language model of a CTC model
+type ctcModel struct {
+	config CTCConfig
+	lm     *NgramLM
+}
+
+// isCTC reports whether a model is a CTC acoustic model
+func isCTC(model *models.ONNXModel) bool {
+	return model.Model != nil && model.Info.Type == models.TypeCTC
+}
+
+// loadCTCModel reads a CTC model's settings and language model from its directory
+func loadCTCModel(modelDir string) (*ctcModel, error) {
+	config := DefaultCTCConfig()
+	path := filepath.Join(modelDir, ctcConfigFile)
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse CTC settings %s", path)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to read CTC settings")
+	}
+
+	model := &ctcModel{config: config}
+	if config.LM != "" {
+		lmPath := filepath.Join(modelDir, config.LM)
+		if _, err := os.Stat(lmPath); err == nil {
+			if model.lm, err = LoadARPA(lmPath); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return model, nil
+}
+
+// normalizeWaveform scales samples to zero mean and unit variance, the
+// input wav2vec2-style models are trained on
+func normalizeWaveform(samples []float32) []float32 {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	var mean float64
+	for _, s := range samples {
+		mean += float64(s)
+	}
+	mean /= float64(len(samples))
+
+	var variance float64
+	for _, s := range samples {
+		d := float64(s) - mean
+		variance += d * d
+	}
+	variance /= float64(len(samples))
+
+	scale := 1 / math.Sqrt(variance+1e-7)
+	normalized := make([]float32, len(samples))
+	for i, s := range samples {
+		normalized[i] = float32((float64(s) - mean) * scale)
+	}
+	return normalized
+}
+
+// runCTC normalizes the windows, runs the acoustic model once for the whole
+// batch and decodes each window's emissions
+func (i *Inference) runCTC(ctx context.Context, runtime CTCRuntime, windows []*Window) ([]*InferenceResult, error) {
+	end := StartSpan(ctx, StageFeatureExtraction, map[string]interface{}{"windows": len(windows)})
+	waveforms := make([][]float32, len(windows))
+	var waveformBytes int64
+	for j, window := range windows {
+		waveforms[j] = normalizeWaveform(window.Audio)
+		waveformBytes += int64(len(waveforms[j])) * bytesPerSample
+	}
+	end()
+
+	i.session.allocate(waveformBytes)
+	defer i.session.free(waveformBytes)
+
+	end = StartSpan(ctx, StageEncoder, map[string]interface{}{"windows": len(windows)})
+	emissions, err := runtime.Emissions(ctx, waveforms)
+	end()
+	if err != nil {
+		return nil, errors.Wrap(err, "acoustic model failed")
+	}
+	if len(emissions) != len(windows) {
+		return nil, errors.Errorf("acoustic model returned %d outputs for %d windows", len(emissions), len(windows))
+	}
+
+	decoder, err := newCTCDecoder(runtime.Labels(), i.session.ctc)
+	if err != nil {
+		return nil, err
+	}
+
+	end = StartSpan(ctx, StageCTCDecode, map[string]interface{}{"windows": len(windows)})
+	defer end()
+	outputs := make([]*InferenceResult, len(windows))
+	for j, window := range windows {
+		width := window.Options.BeamSize
+		if decoder.config.BeamWidth > 0 {
+			width = decoder.config.BeamWidth
+		}
+
+		var path []ctcToken
+		if width <= 1 {
+			path = decoder.greedy(emissions[j].LogProbs)
+		} else {
+			path = decoder.beamSearch(emissions[j].LogProbs, width)
+		}
+		outputs[j] = decoder.result(path, emissions[j].FrameDuration)
+	}
+	return outputs, nil
+}
+
+// ctcToken is a label emitted on a CTC path
+type ctcToken struct {
+	label int
+	// start and end are the first and one past the last frame of the label
+	start, end int
+	// logProb is the label's log-probability at its first frame
+	logProb float32
+}
+
+// ctcDecoder turns emissions into text
+type ctcDecoder struct {
+	config    CTCConfig
+	lm        *NgramLM
+	labels    []string
+	blank     int
+	delimiter int
+	// skip marks labels left out of the text, such as <s> and <unk>
+	skip []bool
+}
+
+// newCTCDecoder resolves the blank and delimiter labels of a vocabulary
+func newCTCDecoder(labels []string, model *ctcModel) (*ctcDecoder, error) {
+	if model == nil {
+		model = &ctcModel{config: DefaultCTCConfig()}
+	}
+	d := &ctcDecoder{
+		config:    model.config,
+		lm:        model.lm,
+		labels:    labels,
+		blank:     -1,
+		delimiter: -1,
+		skip:      make([]bool, len(labels)),
+	}
+	for id, label := range labels {
+		switch {
+		case label == d.config.Blank:
+			d.blank = id
+		case label == d.config.WordDelimiter:
+			d.delimiter = id
+		case isControlLabel(label):
+			d.skip[id] = true
+		}
+	}
+	if d.blank < 0 {
+		return nil, errors.Errorf("CTC vocabulary has no blank label %q", d.config.Blank)
+	}
+	return d, nil
+}
+
+// isControlLabel reports whether a label is a control label such as <s>
+func isControlLabel(label string) bool {
+	return len(label) > 2 && strings.HasPrefix(label, "<") && strings.HasSuffix(label, ">")
+}
+
+// greedy takes the best label of every frame, merging repeats and dropping blanks
+func (d *ctcDecoder) greedy(logProbs [][]float32) []ctcToken {
+	var path []ctcToken
+	previous := d.blank
+	for t, row := range logProbs {
+		best := argmax(row)
+		switch {
+		case best == d.blank:
+		case best == previous:
+			path[len(path)-1].end = t + 1
+		default:
+			path = append(path, ctcToken{label: best, start: t, end: t + 1, logProb: row[best]})
+		}
+		previous = best
+	}
+	return path
+}
+
+// ctcNode is a label in a beam's path, linked to the labels before it
+type ctcNode struct {
+	token  ctcToken
+	parent *ctcNode
+}
+
+// ctcPrefix identifies a label sequence by the number of the sequence
+// before its last label and that label
+type ctcPrefix struct {
+	parent uint64
+	label  int
+}
+
+// ctcBeam is a prefix kept by the beam search
+type ctcBeam struct {
+	// id numbers the prefix's label sequence; 0 is the empty prefix
+	id   uint64
+	path *ctcNode
+	// blank and nonBlank are the log-probabilities of the prefix's
+	// alignments ending in a blank and in its last label
+	blank, nonBlank float64
+	// lm is the language model score of the prefix's words
+	lm float64
+	// history are the prefix's complete words, word the one being spelled
+	history []string
+	word    string
+}
+
+// lastLabel returns the prefix's last label, or -1 for the empty prefix
+func (b *ctcBeam) lastLabel() int {
+	if b.path == nil {
+		return -1
+	}
+	return b.path.token.label
+}
+
+// total returns the prefix's acoustic log-probability
+func (b *ctcBeam) total() float64 {
+	return logAddExp(b.blank, b.nonBlank)
+}
+
+// beamSearch runs a CTC prefix beam search, scoring words with the language
+// model, if any, as their delimiters are emitted
+func (d *ctcDecoder) beamSearch(logProbs [][]float32, width int) []ctcToken {
+	negInf := math.Inf(-1)
+	beams := []*ctcBeam{{blank: 0, nonBlank: negInf}}
+	// prefixes numbers the label sequences seen so far, so extensions that
+	// reach the same sequence merge into one beam
+	prefixes := make(map[ctcPrefix]uint64)
+
+	for t, row := range logProbs {
+		next := make(map[uint64]*ctcBeam)
+		get := func(parent *ctcBeam, label int) *ctcBeam {
+			if label < 0 {
+				if b, ok := next[parent.id]; ok {
+					return b
+				}
+				b := &ctcBeam{id: parent.id, path: parent.path, blank: negInf, nonBlank: negInf,
+					lm: parent.lm, history: parent.history, word: parent.word}
+				next[parent.id] = b
+				return b
+			}
+
+			id, ok := prefixes[ctcPrefix{parent: parent.id, label: label}]
+			if !ok {
+				id = uint64(len(prefixes)) + 1
+				prefixes[ctcPrefix{parent: parent.id, label: label}] = id
+			}
+			if b, ok := next[id]; ok {
+				return b
+			}
+			b := &ctcBeam{
+				id:       id,
+				path:     &ctcNode{token: ctcToken{label: label, start: t, end: t + 1, logProb: row[label]}, parent: parent.path},
+				blank:    negInf,
+				nonBlank: negInf,
+				lm:       parent.lm,
+				history:  parent.history,
+				word:     parent.word,
+			}
+			d.extendWords(b, label)
+			next[id] = b
+			return b
+		}
+
+		best := row[argmax(row)]
+		for _, beam := range beams {
+			for label, lp := range row {
+				if lp < best-ctcPruneLogProb {
+					continue
+				}
+				logProb := float64(lp)
+				switch {
+				case label == d.blank:
+					b := get(beam, -1)
+					b.blank = logAddExp(b.blank, beam.total()+logProb)
+				case label == beam.lastLabel():
+					// A repeat without a blank between collapses into the same label
+					b := get(beam, -1)
+					b.nonBlank = logAddExp(b.nonBlank, beam.nonBlank+logProb)
+					b = get(beam, label)
+					b.nonBlank = logAddExp(b.nonBlank, beam.blank+logProb)
+				default:
+					b := get(beam, label)
+					b.nonBlank = logAddExp(b.nonBlank, beam.total()+logProb)
+				}
+			}
+		}
+
+		beams = beams[:0]
+		for _, b := range next {
+			beams = append(beams, b)
+		}
+		sort.Slice(beams, func(i, j int) bool {
+			return beams[i].total()+beams[i].lm > beams[j].total()+beams[j].lm
+		})
+		if len(beams) > width {
+			beams = beams[:width]
+		}
+	}
+
+	// Score the word each prefix ends with before picking the best
+	best, bestScore := beams[0], math.Inf(-1)
+	for _, b := range beams {
+		score := b.total() + b.lm
+		if d.lm != nil && b.word != "" {
+			score += d.config.LMWeight*d.lm.Score(b.history, b.word) + d.config.WordBonus
+		}
+		if score > bestScore {
+			best, bestScore = b, score
+		}
+	}
+
+	var path []ctcToken
+	for node := best.path; node != nil; node = node.parent {
+		path = append(path, node.token)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	d.alignEnds(path, logProbs)
+	return path
+}
+
+// extendWords tracks the words of a prefix extended by label, scoring each
+// word with the language model once its delimiter is emitted
+func (d *ctcDecoder) extendWords(b *ctcBeam, label int) {
+	switch {
+	case label == d.delimiter:
+		if b.word == "" {
+			return
+		}
+		if d.lm != nil {
+			b.lm += d.config.LMWeight*d.lm.Score(b.history, b.word) + d.config.WordBonus
+		}
+		b.history = append(append([]string{}, b.history...), b.word)
+		b.word = ""
+	case !d.skip[label]:
+		b.word += d.labels[label]
+	}
+}
+
+// alignEnds extends each label over the following frames where it is still
+// likelier than a blank, up to the next label
+func (d *ctcDecoder) alignEnds(path []ctcToken, logProbs [][]float32) {
+	for i := range path {
+		limit := len(logProbs)
+		if i+1 < len(path) {
+			limit = path[i+1].start
+		}
+		end := path[i].start + 1
+		for end < limit && logProbs[end][path[i].label] >= logProbs[end][d.blank] {
+			end++
+		}
+		path[i].end = end
+	}
+}
+
+// result converts a decoded path into text with frame-based word timings
+func (d *ctcDecoder) result(path []ctcToken, frameDuration float64) *InferenceResult {
+	var words []WordScore
+	var current *WordScore
+	var sum float64
+	var count int
+	var labelSum float64
+
+	finish := func() {
+		if current != nil && current.Word != "" {
+			current.AvgLogProb = sum / float64(count)
+			words = append(words, *current)
+		}
+		current, sum, count = nil, 0, 0
+	}
+
+	for _, token := range path {
+		labelSum += float64(token.logProb)
+		if token.label == d.delimiter {
+			finish()
+			continue
+		}
+		if d.skip[token.label] {
+			continue
+		}
+		if current == nil {
+			current = &WordScore{Start: float32(float64(token.start) * frameDuration)}
+		}
+		current.Word += d.labels[token.label]
+		current.End = float32(float64(token.end) * frameDuration)
+		sum += float64(token.logProb)
+		count++
+	}
+	finish()
+
+	texts := make([]string, len(words))
+	for i, word := range words {
+		texts[i] = word.Word
+	}
+
+	result := &InferenceResult{Text: strings.Join(texts, " "), Confidence: 1, Words: words}
+	if len(path) > 0 {
+		result.AvgLogProb = labelSum / float64(len(path))
+		result.Confidence = math.Exp(result.AvgLogProb)
+	}
+	return result
+}
+
+// argmax returns the index of the largest value
+func argmax(row []float32) int {
+	best := 0
+	for i, v := range row {
+		if v > row[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// logAddExp returns log(exp(a) + exp(b))
+func logAddExp(a, b float64) float64 {
+	if math.IsInf(a, -1) {
+		return b
+	}
+	if math.IsInf(b, -1) {
+		return a
+	}
+	if a < b {
+		a, b = b, a
+	}
+	return a + math.Log1p(math.Exp(b-a))
+}
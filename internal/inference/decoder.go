@@ -0,0 +1,399 @@
+package inference
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxDecodeTokens caps the tokens generated for a window
+	maxDecodeTokens = 224
+	// maxPromptTokens caps the previous-context tokens ahead of the transcript
+	maxPromptTokens = 223
+	// defaultHintBoost is the log-probability boost of a hint given without one
+	defaultHintBoost = 2.0
+)
+
+// PhraseHint is a phrase the decoder should favor
+type PhraseHint struct {
+	// Phrase is the text to favor
+	Phrase string
+	// Boost is added to the log-probability of each of the phrase's tokens
+	// as it is being decoded; zero means defaultHintBoost
+	Boost float32
+}
+
+// DecodeOptions controls how a window is decoded
+type DecodeOptions struct {
+	// BeamSize is the number of hypotheses kept per step; 0 or 1 decodes greedily
+	BeamSize int
+	// Language is the expected language, used to filter known hallucinations
+	Language string
+	// Prompt is fed to the decoder as previous context, steering vocabulary and style
+	Prompt string
+	// PreviousText is the transcript of the preceding window, fed as context after Prompt
+	PreviousText string
+	// Hints are phrases whose tokens are boosted while decoding
+	Hints []PhraseHint
+	// MaxAlternatives is the number of best hypotheses returned as
+	// alternatives, the best one first; 0 returns none
+	MaxAlternatives int
+}
+
+// EncoderOutput is the encoder's hidden state for one window
+type EncoderOutput struct {
+	// Data holds the hidden state values
+	Data []float32
+	// Shape is the tensor shape of Data
+	Shape []int64
+}
+
+// StagedRuntime is implemented by runtimes that expose the encoder and the
+// decoder separately, letting the pipeline drive decoding token by token.
+// Runtimes that only implement ONNXRuntime run each window in one call.
+type StagedRuntime interface {
+	ONNXRuntime
+	// Encode runs the encoder over a batch of feature windows
+	Encode(ctx context.Context, features []*Features) ([]*EncoderOutput, error)
+	// DecodeStep returns log-probabilities over the vocabulary for the next
+	// token of each sequence, given the tokens so far
+	DecodeStep(ctx context.Context, encoded []*EncoderOutput, tokens [][]int) ([][]float32, error)
+	// Tokenizer returns the model's tokenizer
+	Tokenizer() Tokenizer
+}
+
+// Hypothesis is a decoded token sequence for one window
+type Hypothesis struct {
+	// Tokens are the generated tokens, excluding the prompt and end of text
+	Tokens []int
+	// LogProbs are the model's log-probabilities of Tokens, before hint boosts
+	LogProbs []float32
+	// Score is the cumulative log-probability including hint boosts
+	Score float64
+	// NoSpeechProb is the probability of the no-speech token at the first step
+	NoSpeechProb float64
+	// Text is the decoded text
+	Text string
+}
+
+// AvgLogProb returns the mean token log-probability, 0 for an empty hypothesis
+func (h *Hypothesis) AvgLogProb() float64 {
+	if len(h.LogProbs) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, lp := range h.LogProbs {
+		sum += float64(lp)
+	}
+	return sum / float64(len(h.LogProbs))
+}
+
+// NormalizedScore returns the score divided by the sequence length, which
+// is how hypotheses are ranked
+func (h *Hypothesis) NormalizedScore() float64 {
+	return h.Score / float64(len(h.Tokens)+1)
+}
+
+// beam is a hypothesis being extended
+type beam struct {
+	tokens   []int
+	logProbs []float32
+	score    float64
+}
+
+// candidate is a possible one-token extension of a beam
+type candidate struct {
+	parent  *beam
+	token   int
+	logProb float32
+	score   float64
+}
+
+// windowDecoder holds the beam search state of one window
+type windowDecoder struct {
+	encoded      *EncoderOutput
+	noSpeechProb float64
+	prefix       []int
+	suppressed   map[int]bool
+	hints        [][]int
+	boosts       []float32
+	beamSize     int
+	live         []*beam
+	finished     []*beam
+	candidates   []candidate
+	done         bool
+}
+
+// newWindowDecoder prepares the decoder prefix and hint tokens for a window
+func newWindowDecoder(tokenizer Tokenizer, encoded *EncoderOutput, options DecodeOptions) *windowDecoder {
+	special := tokenizer.SpecialTokens()
+
+	beamSize := options.BeamSize
+	if beamSize < 1 {
+		beamSize = 1
+	}
+
+	d := &windowDecoder{
+		encoded:  encoded,
+		beamSize: beamSize,
+		live:     []*beam{{}},
+	}
+
+	// Control tokens other than end of text are never generated
+	d.suppressed = map[int]bool{
+		special.StartOfTranscript: true,
+		special.StartOfPrevious:   true,
+		special.NoSpeech:          true,
+	}
+
+	if special.StartOfPrevious >= 0 {
+		if previous := promptTokens(tokenizer, options.Prompt, options.PreviousText); len(previous) > 0 {
+			d.prefix = append([]int{special.StartOfPrevious}, previous...)
+		}
+	}
+	d.prefix = append(d.prefix, special.StartOfTranscript)
+
+	for _, hint := range options.Hints {
+		tokens := tokenizer.Encode(" " + strings.TrimSpace(hint.Phrase))
+		if len(tokens) == 0 {
+			continue
+		}
+		boost := hint.Boost
+		if boost == 0 {
+			boost = defaultHintBoost
+		}
+		d.hints = append(d.hints, tokens)
+		d.boosts = append(d.boosts, boost)
+	}
+
+	return d
+}
+
+// promptTokens encodes the prompt followed by the previous window's text,
+// keeping the prompt whole when possible and the end of each part otherwise
+func promptTokens(tokenizer Tokenizer, prompt, previous string) []int {
+	var tokens []int
+	if prompt = strings.TrimSpace(prompt); prompt != "" {
+		tokens = tokenizer.Encode(" " + prompt)
+	}
+	if len(tokens) >= maxPromptTokens {
+		return tokens[len(tokens)-maxPromptTokens:]
+	}
+
+	if previous = strings.TrimSpace(previous); previous != "" {
+		previousTokens := tokenizer.Encode(" " + previous)
+		if budget := maxPromptTokens - len(tokens); len(previousTokens) > budget {
+			previousTokens = previousTokens[len(previousTokens)-budget:]
+		}
+		tokens = append(tokens, previousTokens...)
+	}
+	return tokens
+}
+
+// sequence returns the full decoder input for a beam
+func (d *windowDecoder) sequence(b *beam) []int {
+	sequence := make([]int, 0, len(d.prefix)+len(b.tokens))
+	sequence = append(sequence, d.prefix...)
+	return append(sequence, b.tokens...)
+}
+
+// hintBoosts returns the boost for each token that starts or continues a
+// hint phrase given the beam's tokens so far; the longest partial match of
+// each hint decides which of its tokens is boosted, and when hints share a
+// token the largest boost wins
+func (d *windowDecoder) hintBoosts(b *beam) map[int]float32 {
+	if len(d.hints) == 0 {
+		return nil
+	}
+
+	boosts := make(map[int]float32)
+	for i, hint := range d.hints {
+		k := len(hint) - 1
+		if k > len(b.tokens) {
+			k = len(b.tokens)
+		}
+		for ; k > 0; k-- {
+			if endsWith(b.tokens, hint[:k]) {
+				break
+			}
+		}
+		// A negative boost suppresses the token unless another hint favors it
+		if current, ok := boosts[hint[k]]; !ok || d.boosts[i] > current {
+			boosts[hint[k]] = d.boosts[i]
+		}
+	}
+	return boosts
+}
+
+// expand adds the best one-token extensions of a beam to the candidates
+func (d *windowDecoder) expand(b *beam, logProbs []float32) {
+	boosts := d.hintBoosts(b)
+
+	// Keep one more than the beam size so an end of text cannot crowd out all live beams
+	top := make([]candidate, 0, d.beamSize+1)
+	for token, logProb := range logProbs {
+		if d.suppressed[token] {
+			continue
+		}
+		score := b.score + float64(logProb) + float64(boosts[token])
+		if len(top) == cap(top) && score <= top[len(top)-1].score {
+			continue
+		}
+		c := candidate{parent: b, token: token, logProb: logProb, score: score}
+		if len(top) < cap(top) {
+			top = append(top, c)
+		} else {
+			top[len(top)-1] = c
+		}
+		for i := len(top) - 1; i > 0 && top[i].score > top[i-1].score; i-- {
+			top[i], top[i-1] = top[i-1], top[i]
+		}
+	}
+	d.candidates = append(d.candidates, top...)
+}
+
+// advance replaces the live beams with the best candidates, moving those
+// that ended to the finished list, and marks the window done once it has
+// enough finished hypotheses or nothing left to extend
+func (d *windowDecoder) advance(endOfText int) {
+	sort.SliceStable(d.candidates, func(i, j int) bool {
+		return d.candidates[i].score > d.candidates[j].score
+	})
+
+	live := make([]*beam, 0, d.beamSize)
+	for _, c := range d.candidates {
+		if len(live) == d.beamSize {
+			break
+		}
+		if c.token == endOfText {
+			d.finished = append(d.finished, &beam{tokens: c.parent.tokens, logProbs: c.parent.logProbs, score: c.score})
+			continue
+		}
+		live = append(live, &beam{
+			tokens:   append(append([]int{}, c.parent.tokens...), c.token),
+			logProbs: append(append([]float32{}, c.parent.logProbs...), c.logProb),
+			score:    c.score,
+		})
+	}
+
+	d.live = live
+	d.candidates = d.candidates[:0]
+	d.done = len(d.finished) >= d.beamSize || len(d.live) == 0
+}
+
+// hypotheses returns the window's hypotheses, best first by length-normalized score
+func (d *windowDecoder) hypotheses(tokenizer Tokenizer) []*Hypothesis {
+	beams := d.finished
+	if len(beams) == 0 {
+		beams = d.live
+	}
+
+	normalized := func(b *beam) float64 {
+		return b.score / float64(len(b.tokens)+1)
+	}
+	sorted := append([]*beam{}, beams...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return normalized(sorted[i]) > normalized(sorted[j])
+	})
+
+	hypotheses := make([]*Hypothesis, len(sorted))
+	for i, b := range sorted {
+		hypotheses[i] = &Hypothesis{
+			Tokens:       b.tokens,
+			LogProbs:     b.logProbs,
+			Score:        b.score,
+			NoSpeechProb: d.noSpeechProb,
+			Text:         tokenizer.Decode(b.tokens),
+		}
+	}
+	return hypotheses
+}
+
+// beamRow ties a row of a decoder batch to its window and beam
+type beamRow struct {
+	decoder *windowDecoder
+	beam    *beam
+}
+
+// beamDecode decodes every window of a batch at once with beam search. Each
+// step runs the live beams of all windows through the decoder together.
+// Hypotheses are returned per window, best first.
+func beamDecode(ctx context.Context, runtime StagedRuntime, encoded []*EncoderOutput, options []DecodeOptions) ([][]*Hypothesis, error) {
+	tokenizer := runtime.Tokenizer()
+	special := tokenizer.SpecialTokens()
+
+	decoders := make([]*windowDecoder, len(encoded))
+	for i := range encoded {
+		decoders[i] = newWindowDecoder(tokenizer, encoded[i], options[i])
+	}
+
+	for step := 0; step < maxDecodeTokens; step++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var rows []beamRow
+		var batchEncoded []*EncoderOutput
+		var batchTokens [][]int
+		for _, d := range decoders {
+			if d.done {
+				continue
+			}
+			for _, b := range d.live {
+				rows = append(rows, beamRow{decoder: d, beam: b})
+				batchEncoded = append(batchEncoded, d.encoded)
+				batchTokens = append(batchTokens, d.sequence(b))
+			}
+		}
+		if len(rows) == 0 {
+			break
+		}
+
+		end := StartSpan(ctx, StageDecoderStep, map[string]interface{}{"step": step, "sequences": len(rows)})
+		logProbs, err := runtime.DecodeStep(ctx, batchEncoded, batchTokens)
+		end()
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoder step %d", step)
+		}
+		if len(logProbs) != len(rows) {
+			return nil, errors.Errorf("decoder step %d returned %d rows for %d sequences", step, len(logProbs), len(rows))
+		}
+
+		for j, row := range rows {
+			// The first step has a single beam per window and predicts whether there is speech
+			if step == 0 && special.NoSpeech >= 0 && special.NoSpeech < len(logProbs[j]) {
+				row.decoder.noSpeechProb = math.Exp(float64(logProbs[j][special.NoSpeech]))
+			}
+			row.decoder.expand(row.beam, logProbs[j])
+		}
+		for _, d := range decoders {
+			if !d.done {
+				d.advance(special.EndOfText)
+			}
+		}
+	}
+
+	results := make([][]*Hypothesis, len(decoders))
+	for i, d := range decoders {
+		results[i] = d.hypotheses(tokenizer)
+	}
+	return results, nil
+}
+
+// endsWith reports whether tokens ends with suffix
+func endsWith(tokens, suffix []int) bool {
+	if len(suffix) > len(tokens) {
+		return false
+	}
+	offset := len(tokens) - len(suffix)
+	for i, token := range suffix {
+		if tokens[offset+i] != token {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,512 @@
+// Package fake provides a deterministic scripted inference backend for tests.
+// It implements the same runtime interfaces as the ONNX runtime, so the
+// server, streaming and batching layers can be exercised without a model.
+package fake
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/inference"
+	"github.com/pkg/errors"
+)
+
+// ErrInjected is returned by calls chosen to fail by Config.FailEvery
+var ErrInjected = errors.New("injected inference failure")
+
+// DefaultConfidence is the probability given to scripted tokens without one
+const DefaultConfidence = 0.95
+
+// FrameDuration is the time each CTC emission frame covers, as in wav2vec2
+const FrameDuration = 0.02
+
+// Script is the output produced for matching audio
+type Script struct {
+	// Text is the transcript
+	Text string
+	// Tokens are the byte-level tokens of the transcript; derived from Text,
+	// one token per word, when empty
+	Tokens []string
+	// Confidences are the probabilities of Tokens; DefaultConfidence when empty
+	Confidences []float64
+	// Timestamps are returned as is by RunInference
+	Timestamps []float64
+	// NoSpeechProb is the probability given to the no-speech token
+	NoSpeechProb float64
+	// Alternatives are other transcripts the beam decoder can reach; give
+	// them lower Confidences so they rank below Text. Only Text, Tokens and
+	// Confidences of an alternative are used.
+	Alternatives []Script
+	// Err, when set, fails every call for matching audio
+	Err error
+}
+
+// Config controls latency and failure injection
+type Config struct {
+	// Latency is added to every RunInference, Encode and Emissions call
+	Latency time.Duration
+	// StepLatency is added to every DecodeStep call
+	StepLatency time.Duration
+	// FailEvery makes every Nth RunInference, Encode or Emissions call fail with ErrInjected; 0 disables it
+	FailEvery int
+	// ProviderErrors maps execution providers to the error SetExecutionProvider returns for them
+	ProviderErrors map[string]error
+}
+
+// Backend holds the scripts shared by every runtime it creates
+type Backend struct {
+	config Config
+	// calls counts RunInference, Encode and Emissions calls
+	calls int64
+
+	mu sync.RWMutex
+	// scripts holds every script; index 0 is the default
+	scripts []*scriptEntry
+	// fingerprints maps audio fingerprints to script indexes
+	fingerprints map[string]int
+	// ranges maps sample count ranges to script indexes, first match wins
+	ranges []sampleRange
+	// tokens is the append-only vocabulary token list
+	tokens []string
+	// tokenIDs maps tokens to their IDs
+	tokenIDs map[string]int
+	// vocab is the tokenizer over tokens
+	vocab *inference.Vocabulary
+	// labels is the append-only CTC label list
+	labels []string
+	// labelIDs maps CTC labels to their IDs
+	labelIDs map[string]int
+}
+
+// scriptEntry is a script with its token IDs resolved
+type scriptEntry struct {
+	script   Script
+	tokenIDs []int
+	// labelIDs spell the text in CTC labels, words separated by the delimiter
+	labelIDs []int
+	// labelWords maps each of labelIDs to its word
+	labelWords []int
+	// alternatives are the resolved Script.Alternatives
+	alternatives []*scriptEntry
+}
+
+// sampleRange matches audio by its number of samples
+type sampleRange struct {
+	min, max int
+	index    int
+}
+
+// Control tokens every fake vocabulary starts with
+var controlTokens = []string{"<|startoftranscript|>", "<|endoftext|>", "<|startofprev|>", "<|nospeech|>"}
+
+// endOfText is the ID of the end of text control token
+const endOfText = 1
+
+// Labels every fake CTC vocabulary starts with; the first is the blank and
+// the last the word delimiter
+var controlLabels = []string{"<pad>", "<s>", "</s>", "<unk>", "|"}
+
+// ctcDelimiter is the ID of the word delimiter label
+const ctcDelimiter = 4
+
+// New creates a backend whose default script produces no text
+func New(config Config) *Backend {
+	b := &Backend{
+		config:       config,
+		fingerprints: make(map[string]int),
+		tokenIDs:     make(map[string]int),
+		labelIDs:     make(map[string]int),
+	}
+	for _, token := range controlTokens {
+		b.tokenIDs[token] = len(b.tokens)
+		b.tokens = append(b.tokens, token)
+	}
+	for _, label := range controlLabels {
+		b.labelIDs[label] = len(b.labels)
+		b.labels = append(b.labels, label)
+	}
+	b.scripts = []*scriptEntry{b.entry(Script{})}
+	b.vocab = inference.NewVocabulary(b.tokens)
+	return b
+}
+
+// Fingerprint identifies audio by a hash of its samples
+func Fingerprint(samples []float32) string {
+	h := sha256.New()
+	buf := make([]byte, 4)
+	for _, sample := range samples {
+		binary.LittleEndian.PutUint32(buf, math.Float32bits(sample))
+		h.Write(buf)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// SetDefault sets the script used when no fingerprint or range matches
+func (b *Backend) SetDefault(script Script) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.scripts[0] = b.entry(script)
+	b.vocab = inference.NewVocabulary(b.tokens)
+}
+
+// AddFingerprint scripts the output for audio with exactly these samples
+func (b *Backend) AddFingerprint(samples []float32, script Script) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.fingerprints[Fingerprint(samples)] = b.add(script)
+}
+
+// AddRange scripts the output for audio with between min and max samples, inclusive
+func (b *Backend) AddRange(min, max int, script Script) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ranges = append(b.ranges, sampleRange{min: min, max: max, index: b.add(script)})
+}
+
+// Calls returns the number of RunInference, Encode and Emissions calls made
+func (b *Backend) Calls() int {
+	return int(atomic.LoadInt64(&b.calls))
+}
+
+// Factory returns a runtime factory for inference.Manager.SetRuntimeFactory
+func (b *Backend) Factory() inference.RuntimeFactory {
+	return func(modelPath string) (inference.ONNXRuntime, error) {
+		return &runtime{backend: b}, nil
+	}
+}
+
+// add stores a script and returns its index; the caller holds mu
+func (b *Backend) add(script Script) int {
+	b.scripts = append(b.scripts, b.entry(script))
+	b.vocab = inference.NewVocabulary(b.tokens)
+	return len(b.scripts) - 1
+}
+
+// entry resolves a script's tokens, growing the vocabulary; the caller holds mu
+func (b *Backend) entry(script Script) *scriptEntry {
+	tokens := script.Tokens
+	if len(tokens) == 0 {
+		for _, word := range strings.Fields(script.Text) {
+			tokens = append(tokens, inference.ByteLevel(" "+word))
+		}
+	}
+
+	entry := &scriptEntry{script: script}
+	for _, token := range tokens {
+		id, exists := b.tokenIDs[token]
+		if !exists {
+			id = len(b.tokens)
+			b.tokenIDs[token] = id
+			b.tokens = append(b.tokens, token)
+		}
+		entry.tokenIDs = append(entry.tokenIDs, id)
+	}
+
+	for i, word := range strings.Fields(script.Text) {
+		if i > 0 {
+			entry.labelIDs = append(entry.labelIDs, ctcDelimiter)
+			entry.labelWords = append(entry.labelWords, i)
+		}
+		for _, r := range word {
+			label := string(r)
+			id, exists := b.labelIDs[label]
+			if !exists {
+				id = len(b.labels)
+				b.labelIDs[label] = id
+				b.labels = append(b.labels, label)
+			}
+			entry.labelIDs = append(entry.labelIDs, id)
+			entry.labelWords = append(entry.labelWords, i)
+		}
+	}
+
+	for _, alternative := range script.Alternatives {
+		entry.alternatives = append(entry.alternatives, b.entry(Script{
+			Text:        alternative.Text,
+			Tokens:      alternative.Tokens,
+			Confidences: alternative.Confidences,
+		}))
+	}
+	return entry
+}
+
+// match returns the index of the script for the given samples
+func (b *Backend) match(samples []float32) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.fingerprints) > 0 {
+		if index, ok := b.fingerprints[Fingerprint(samples)]; ok {
+			return index
+		}
+	}
+	for _, r := range b.ranges {
+		if len(samples) >= r.min && len(samples) <= r.max {
+			return r.index
+		}
+	}
+	return 0
+}
+
+// script returns the script at index
+func (b *Backend) script(index int) *scriptEntry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.scripts[index]
+}
+
+// call applies latency and failure injection to a RunInference, Encode or Emissions call
+func (b *Backend) call(ctx context.Context) error {
+	n := atomic.AddInt64(&b.calls, 1)
+	if err := sleep(ctx, b.config.Latency); err != nil {
+		return err
+	}
+	if b.config.FailEvery > 0 && n%int64(b.config.FailEvery) == 0 {
+		return ErrInjected
+	}
+	return nil
+}
+
+// sleep waits for d unless ctx is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runtime is a session's view of the backend
+type runtime struct {
+	backend  *Backend
+	provider string
+}
+
+// SetExecutionProvider fails for providers listed in Config.ProviderErrors
+func (r *runtime) SetExecutionProvider(provider string) error {
+	if err := r.backend.config.ProviderErrors[provider]; err != nil {
+		return err
+	}
+	r.provider = provider
+	return nil
+}
+
+// HasGPUSupport reports false; the fake runs anywhere
+func (r *runtime) HasGPUSupport() bool {
+	return false
+}
+
+// Close does nothing
+func (r *runtime) Close() error {
+	return nil
+}
+
+// Tokenizer returns the vocabulary covering every script added so far
+func (r *runtime) Tokenizer() inference.Tokenizer {
+	r.backend.mu.RLock()
+	defer r.backend.mu.RUnlock()
+	return r.backend.vocab
+}
+
+// RunInference returns the matching script's text in one call
+func (r *runtime) RunInference(ctx context.Context, samples []float32) (*inference.InferenceResult, error) {
+	if err := r.backend.call(ctx); err != nil {
+		return nil, err
+	}
+
+	entry := r.backend.script(r.backend.match(samples))
+	if entry.script.Err != nil {
+		return nil, entry.script.Err
+	}
+
+	var logProb float64
+	for i := range entry.tokenIDs {
+		logProb += math.Log(entry.confidence(i))
+	}
+	confidence := 1.0
+	if len(entry.tokenIDs) > 0 {
+		confidence = math.Exp(logProb / float64(len(entry.tokenIDs)))
+	}
+
+	return &inference.InferenceResult{
+		Text:         entry.script.Text,
+		Confidence:   confidence,
+		Timestamps:   entry.script.Timestamps,
+		NoSpeechProb: entry.script.NoSpeechProb,
+	}, nil
+}
+
+// Encode matches each window to a script and hands its index to the decoder
+func (r *runtime) Encode(ctx context.Context, features []*inference.Features) ([]*inference.EncoderOutput, error) {
+	if err := r.backend.call(ctx); err != nil {
+		return nil, err
+	}
+
+	outputs := make([]*inference.EncoderOutput, len(features))
+	for i, f := range features {
+		index := r.backend.match(f.Samples)
+		if err := r.backend.script(index).script.Err; err != nil {
+			return nil, err
+		}
+		outputs[i] = &inference.EncoderOutput{Data: []float32{float32(index)}, Shape: []int64{1}}
+	}
+	return outputs, nil
+}
+
+// DecodeStep gives the next scripted token the script's confidence, spreads
+// the rest over the other tokens, and ends the text after the last token
+func (r *runtime) DecodeStep(ctx context.Context, encoded []*inference.EncoderOutput, tokens [][]int) ([][]float32, error) {
+	if err := sleep(ctx, r.backend.config.StepLatency); err != nil {
+		return nil, err
+	}
+
+	r.backend.mu.RLock()
+	size := len(r.backend.tokens)
+	special := r.backend.vocab.SpecialTokens()
+	r.backend.mu.RUnlock()
+
+	logProbs := make([][]float32, len(tokens))
+	for i, sequence := range tokens {
+		entry := r.backend.script(int(encoded[i].Data[0]))
+
+		// Tokens generated so far follow the last start of transcript
+		generated := len(sequence)
+		for j := len(sequence) - 1; j >= 0; j-- {
+			if sequence[j] == special.StartOfTranscript {
+				generated = len(sequence) - j - 1
+				break
+			}
+		}
+
+		// The script and its alternatives each offer their next token while
+		// the sequence follows them; a sequence that strays from all of them
+		// continues the script by position
+		prefix := sequence[len(sequence)-generated:]
+		var offers []*scriptEntry
+		for _, path := range append([]*scriptEntry{entry}, entry.alternatives...) {
+			if path.follows(prefix) {
+				offers = append(offers, path)
+			}
+		}
+		if len(offers) == 0 {
+			offers = []*scriptEntry{entry}
+		}
+
+		_, confidence := offers[0].next(generated)
+		rest := float32(math.Log(math.Max(1-confidence, 1e-9) / float64(size-1)))
+		row := make([]float32, size)
+		for j := range row {
+			row[j] = rest
+		}
+		for _, path := range offers {
+			next, p := path.next(generated)
+			if lp := float32(math.Log(p)); lp > row[next] {
+				row[next] = lp
+			}
+		}
+		if generated == 0 && entry.script.NoSpeechProb > 0 {
+			row[special.NoSpeech] = float32(math.Log(entry.script.NoSpeechProb))
+		}
+		logProbs[i] = row
+	}
+	return logProbs, nil
+}
+
+// Labels returns the CTC labels covering every script added so far
+func (r *runtime) Labels() []string {
+	r.backend.mu.RLock()
+	defer r.backend.mu.RUnlock()
+	return append([]string{}, r.backend.labels...)
+}
+
+// Emissions spells each window's script in CTC labels spread evenly over
+// the window's frames: every label takes the first frame of its share with
+// its word's confidence, and blanks fill the rest
+func (r *runtime) Emissions(ctx context.Context, waveforms [][]float32) ([]*inference.Emissions, error) {
+	if err := r.backend.call(ctx); err != nil {
+		return nil, err
+	}
+
+	r.backend.mu.RLock()
+	size := len(r.backend.labels)
+	r.backend.mu.RUnlock()
+
+	emissions := make([]*inference.Emissions, len(waveforms))
+	for i, waveform := range waveforms {
+		entry := r.backend.script(r.backend.match(waveform))
+		if entry.script.Err != nil {
+			return nil, entry.script.Err
+		}
+
+		frames := int(float64(len(waveform)) / (FrameDuration * 16000))
+		if frames < 2*len(entry.labelIDs) {
+			frames = 2 * len(entry.labelIDs)
+		}
+
+		rows := make([][]float32, frames)
+		for t := range rows {
+			rows[t] = frameRow(size, 0, 0.99)
+		}
+		for j, label := range entry.labelIDs {
+			t := j * frames / len(entry.labelIDs)
+			rows[t] = frameRow(size, label, entry.confidence(entry.labelWords[j]))
+		}
+		emissions[i] = &inference.Emissions{LogProbs: rows, FrameDuration: FrameDuration}
+	}
+	return emissions, nil
+}
+
+// frameRow returns label log-probabilities giving label the probability p
+// and spreading the rest evenly
+func frameRow(size, label int, p float64) []float32 {
+	rest := float32(math.Log((1 - p) / float64(size-1)))
+	row := make([]float32, size)
+	for i := range row {
+		row[i] = rest
+	}
+	row[label] = float32(math.Log(p))
+	return row
+}
+
+// next returns the token following the first generated tokens of the script
+// and its probability; the end of text once the script is complete
+func (e *scriptEntry) next(generated int) (int, float64) {
+	if generated < len(e.tokenIDs) {
+		return e.tokenIDs[generated], e.confidence(generated)
+	}
+	return endOfText, 1
+}
+
+// follows reports whether tokens are a prefix of the script's tokens
+func (e *scriptEntry) follows(tokens []int) bool {
+	if len(tokens) > len(e.tokenIDs) {
+		return false
+	}
+	for i, token := range tokens {
+		if e.tokenIDs[i] != token {
+			return false
+		}
+	}
+	return true
+}
+
+// confidence returns the probability of the script's ith token
+func (e *scriptEntry) confidence(i int) float64 {
+	if i < len(e.script.Confidences) {
+		return e.script.Confidences[i]
+	}
+	return DefaultConfidence
+}
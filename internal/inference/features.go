@@ -0,0 +1,170 @@
+package inference
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// Log-mel parameters of Whisper-style models at 16 kHz
+const (
+	// melBins is the number of mel filter bands
+	melBins = 80
+	// frameLength is the analysis window length in samples (25 ms)
+	frameLength = 400
+	// hopLength is the distance between frames in samples (10 ms)
+	hopLength = 160
+	// fftSize is the FFT length; frames are zero padded to it
+	fftSize = 512
+	// featureSampleRate is the sample rate the filter bank is built for
+	featureSampleRate = 16000
+)
+
+// Features is the encoder input for one audio window
+type Features struct {
+	// Samples is the raw waveform of the window
+	Samples []float32
+	// Mel holds Frames x Bins log-mel values, frame-major; empty for
+	// models that consume the raw waveform
+	Mel []float32
+	// Frames is the number of feature frames
+	Frames int
+	// Bins is the number of mel bins per frame
+	Bins int
+}
+
+// melFilters is the triangular filter bank, built once
+var melFilters = newMelFilterBank(melBins, fftSize, featureSampleRate)
+
+// hannWindow is the analysis window, built once
+var hannWindow = newHannWindow(frameLength)
+
+// ExtractFeatures computes the Whisper-style log-mel spectrogram of a window:
+// log10 power, clamped to 8 below the maximum and scaled to roughly [-1, 1]
+func ExtractFeatures(samples []float32) *Features {
+	frames := len(samples) / hopLength
+	if frames == 0 {
+		frames = 1
+	}
+
+	mel := make([]float32, frames*melBins)
+	frame := make([]complex128, fftSize)
+	power := make([]float64, fftSize/2+1)
+	maxValue := math.Inf(-1)
+
+	for f := 0; f < frames; f++ {
+		// Center each frame on its hop, zero padding at the edges
+		start := f*hopLength - frameLength/2
+		for i := range frame {
+			frame[i] = 0
+		}
+		for i := 0; i < frameLength; i++ {
+			if j := start + i; j >= 0 && j < len(samples) {
+				frame[i] = complex(float64(samples[j])*hannWindow[i], 0)
+			}
+		}
+
+		fft(frame)
+		for k := range power {
+			a := cmplx.Abs(frame[k])
+			power[k] = a * a
+		}
+
+		for b, filter := range melFilters {
+			var energy float64
+			for k, weight := range filter {
+				energy += weight * power[k]
+			}
+			value := math.Log10(math.Max(energy, 1e-10))
+			mel[f*melBins+b] = float32(value)
+			if value > maxValue {
+				maxValue = value
+			}
+		}
+	}
+
+	floor := float32(maxValue - 8)
+	for i, value := range mel {
+		if value < floor {
+			value = floor
+		}
+		mel[i] = (value + 4) / 4
+	}
+
+	return &Features{
+		Samples: samples,
+		Mel:     mel,
+		Frames:  frames,
+		Bins:    melBins,
+	}
+}
+
+// fft is an in-place iterative radix-2 FFT; len(x) must be a power of two
+func fft(x []complex128) {
+	n := len(x)
+
+	// Bit-reversal permutation
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even := x[start+k]
+				odd := w * x[start+k+size/2]
+				x[start+k] = even + odd
+				x[start+k+size/2] = even - odd
+				w *= step
+			}
+		}
+	}
+}
+
+// newHannWindow returns a periodic Hann window of length n
+func newHannWindow(n int) []float64 {
+	window := make([]float64, n)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+	}
+	return window
+}
+
+// newMelFilterBank returns Slaney-normalized triangular filters on the HTK
+// mel scale, one row of FFT bin weights per band
+func newMelFilterBank(bands, size, sampleRate int) [][]float64 {
+	hzToMel := func(hz float64) float64 { return 2595 * math.Log10(1+hz/700) }
+	melToHz := func(mel float64) float64 { return 700 * (math.Pow(10, mel/2595) - 1) }
+
+	bins := size/2 + 1
+	maxMel := hzToMel(float64(sampleRate) / 2)
+	edges := make([]float64, bands+2)
+	for i := range edges {
+		edges[i] = melToHz(maxMel * float64(i) / float64(bands+1))
+	}
+
+	filters := make([][]float64, bands)
+	for b := range filters {
+		lower, center, upper := edges[b], edges[b+1], edges[b+2]
+		norm := 2 / (upper - lower)
+		filters[b] = make([]float64, bins)
+		for k := range filters[b] {
+			hz := float64(k) * float64(sampleRate) / float64(size)
+			switch {
+			case hz > lower && hz <= center:
+				filters[b][k] = norm * (hz - lower) / (center - lower)
+			case hz > center && hz < upper:
+				filters[b][k] = norm * (upper - hz) / (upper - center)
+			}
+		}
+	}
+	return filters
+}
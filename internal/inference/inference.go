@@ -2,6 +2,7 @@ package inference
 
 import (
 	"context"
+	"math"
 	"sync"
 	"time"
 
@@ -14,103 +15,250 @@ type Inference struct {
 	session *Session
 	// mu protects the inference process
 	mu sync.Mutex
-	// stats tracks inference statistics
-	stats Stats
 }
 
-// NewInference creates a new inference handler
+// NewInference creates a new inference handler; statistics are recorded on the session
 func NewInference(session *Session) *Inference {
 	return &Inference{
 		session: session,
-		stats:   Stats{},
 	}
 }
 
+// bytesPerSample is the size of a float32 audio sample in an input tensor
+const bytesPerSample = 4
+
 // ProcessAudio processes an audio segment and returns the transcription
 func (i *Inference) ProcessAudio(ctx context.Context, audioData []float32) (*Result, error) {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
-	startTime := time.Now()
-
-	// TODO: Implement actual ONNX Runtime inference
-	// This will be implemented when we integrate the ONNX Runtime Go bindings
-	// Steps will include:
-	// 1. Preprocess audio data
-	// 2. Create input tensor
-	// 3. Run inference
-	// 4. Process output tensor
-	// 5. Format result
-
-	// For now, return a placeholder result
-	result := &Result{
-		Transcription:  "",
-		Confidence:     0,
-		TimestampStart: 0,
-		TimestampEnd:   float32(len(audioData)) / 16000, // Assuming 16kHz sample rate
-		ProcessingTime: float32(time.Since(startTime).Seconds()),
+	results, err := i.process(ctx, []*Window{{Audio: audioData}})
+	if err != nil {
+		return nil, errors.Wrap(err, "inference failed")
 	}
-
-	// Update statistics
-	i.updateStats(result)
-
-	return result, nil
+	return results[0], nil
 }
 
-// ProcessBatch processes a batch of audio segments
+// ProcessBatch processes a batch of audio segments with default decoding options
 func (i *Inference) ProcessBatch(ctx context.Context, audioBatch [][]float32) ([]*Result, error) {
+	windows := make([]*Window, len(audioBatch))
+	for j, audioData := range audioBatch {
+		windows[j] = &Window{Audio: audioData}
+	}
+	return i.ProcessWindows(ctx, windows)
+}
+
+// ProcessWindows processes a batch of audio windows, each with its own decoding options
+func (i *Inference) ProcessWindows(ctx context.Context, windows []*Window) ([]*Result, error) {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
-	if len(audioBatch) == 0 {
+	if len(windows) == 0 {
 		return nil, errors.New("empty batch")
 	}
 
-	if len(audioBatch) > i.session.BatchConfig.MaxBatchSize {
-		return nil, errors.Errorf("batch size %d exceeds maximum %d", len(audioBatch), i.session.BatchConfig.MaxBatchSize)
+	if len(windows) > i.session.BatchConfig.MaxBatchSize {
+		return nil, errors.Errorf("batch size %d exceeds maximum %d", len(windows), i.session.BatchConfig.MaxBatchSize)
 	}
 
+	results, err := i.process(ctx, windows)
+	if err != nil {
+		return nil, errors.Wrap(err, "batch inference failed")
+	}
+	return results, nil
+}
+
+// GetStats returns the statistics of the session behind this handler
+func (i *Inference) GetStats() Stats {
+	return i.session.Stats()
+}
+
+// process runs windows through the model and converts the outputs into
+// results, recording each stage into the context's trace
+func (i *Inference) process(ctx context.Context, windows []*Window) ([]*Result, error) {
 	startTime := time.Now()
 
-	// TODO: Implement actual ONNX Runtime batch inference
-	// This will be implemented when we integrate the ONNX Runtime Go bindings
-	// Steps will include:
-	// 1. Preprocess audio data
-	// 2. Create input tensor
-	// 3. Run batch inference
-	// 4. Process output tensors
-	// 5. Format results
-
-	// For now, return placeholder results
-	results := make([]*Result, len(audioBatch))
-	for j := range audioBatch {
+	// Padding only fills a batch out to a preferred size. No runtime takes
+	// the batch as a single tensor yet, so running a padded window would
+	// cost a whole inference for nothing; padded windows get no result
+	live := make([]*Window, 0, len(windows))
+	for _, window := range windows {
+		if !window.Padding {
+			live = append(live, window)
+		}
+	}
+	results := make([]*Result, len(windows))
+	if len(live) == 0 {
+		return results, nil
+	}
+
+	// Account for the input tensor while it is alive
+	var tensorBytes int64
+	for _, window := range live {
+		tensorBytes += int64(len(window.Audio)) * bytesPerSample
+	}
+	i.session.allocate(tensorBytes)
+	defer i.session.free(tensorBytes)
+
+	var outputs []*InferenceResult
+	err := i.session.run(ctx, func(runtime ONNXRuntime) error {
+		if isCTC(i.session.Model) {
+			ctc, ok := runtime.(CTCRuntime)
+			if !ok {
+				return errors.Errorf("model %s is a CTC model but its runtime cannot run one", i.session.Model.ID)
+			}
+			var err error
+			outputs, err = i.runCTC(ctx, ctc, live)
+			return err
+		}
+
+		var err error
+		if staged, ok := runtime.(StagedRuntime); ok {
+			outputs, err = i.runStaged(ctx, staged, live)
+		} else {
+			outputs, err = runWhole(ctx, runtime, live)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	end := StartSpan(ctx, StagePostProcessing, map[string]interface{}{"windows": len(live)})
+
+	// Windows are independent, so timestamps are relative to each window
+	processingTime := float32(time.Since(startTime).Seconds()) / float32(len(live))
+	var audioSeconds float64
+	next := 0
+	for j, window := range windows {
+		if window.Padding {
+			continue
+		}
+		output := outputs[next]
+		next++
+
+		text, warnings := applySafeguards(i.session.safeguards, window.Options.Language, output)
+		duration := float32(len(window.Audio)) / featureSampleRate
+		confidence := calibrate(i.session.calibration, output.Confidence)
 		results[j] = &Result{
-			Transcription:  "",
-			Confidence:     0,
-			TimestampStart: float32(j) * 30, // Assuming 30-second segments
-			TimestampEnd:   float32(j+1) * 30,
-			ProcessingTime: float32(time.Since(startTime).Seconds()) / float32(len(audioBatch)),
+			Transcription:  text,
+			Confidence:     confidence,
+			TimestampStart: 0,
+			TimestampEnd:   duration,
+			ProcessingTime: processingTime,
+			Warnings:       warnings,
+			Words:          wordResults(text, output.Words, confidence, duration, i.session.calibration),
+			Alternatives:   i.alternatives(window, output),
 		}
+		audioSeconds += float64(results[j].TimestampEnd)
 	}
 
-	// Update statistics
-	for _, result := range results {
-		i.updateStats(result)
+	end()
+
+	// Update statistics; warmup runs would skew them
+	if !isWarmup(ctx) {
+		// Padded windows aren't inferences and carry no audio
+		i.session.stats.recordBatch(len(live), audioSeconds, time.Since(startTime).Seconds())
 	}
 
 	return results, nil
 }
 
-// GetStats returns the current inference statistics
-func (i *Inference) GetStats() Stats {
-	i.mu.Lock()
-	defer i.mu.Unlock()
-	return i.stats
+// alternatives runs a window's alternatives through the safeguards and
+// calibration, dropping those left with the same text as a better one
+func (i *Inference) alternatives(window *Window, output *InferenceResult) []Alternative {
+	var alternatives []Alternative
+	seen := make(map[string]bool)
+	for _, alternative := range output.Alternatives {
+		text, _ := applySafeguards(i.session.safeguards, window.Options.Language, &InferenceResult{
+			Text:         alternative.Text,
+			AvgLogProb:   math.Log(float64(alternative.Confidence)),
+			NoSpeechProb: output.NoSpeechProb,
+		})
+		if seen[text] {
+			continue
+		}
+		seen[text] = true
+
+		alternative.Text = text
+		alternative.Confidence = calibrate(i.session.calibration, float64(alternative.Confidence))
+		alternatives = append(alternatives, alternative)
+	}
+	return alternatives
+}
+
+// runStaged extracts features, runs the encoder once for the whole batch
+// and decodes all windows together
+func (i *Inference) runStaged(ctx context.Context, runtime StagedRuntime, windows []*Window) ([]*InferenceResult, error) {
+	end := StartSpan(ctx, StageFeatureExtraction, map[string]interface{}{"windows": len(windows)})
+	features := make([]*Features, len(windows))
+	options := make([]DecodeOptions, len(windows))
+	var featureBytes int64
+	for j, window := range windows {
+		features[j] = ExtractFeatures(window.Audio)
+		options[j] = window.Options
+		featureBytes += int64(len(features[j].Mel)) * bytesPerSample
+	}
+	end()
+
+	i.session.allocate(featureBytes)
+	defer i.session.free(featureBytes)
+
+	end = StartSpan(ctx, StageEncoder, map[string]interface{}{"windows": len(windows)})
+	encoded, err := runtime.Encode(ctx, features)
+	end()
+	if err != nil {
+		return nil, errors.Wrap(err, "encoder failed")
+	}
+	if len(encoded) != len(windows) {
+		return nil, errors.Errorf("encoder returned %d outputs for %d windows", len(encoded), len(windows))
+	}
+
+	hypotheses, err := beamDecode(ctx, runtime, encoded, options)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoder failed")
+	}
+
+	tokenizer := runtime.Tokenizer()
+	outputs := make([]*InferenceResult, len(hypotheses))
+	for j, windowHypotheses := range hypotheses {
+		hypothesis := windowHypotheses[0]
+		outputs[j] = &InferenceResult{
+			Text:         hypothesis.Text,
+			Confidence:   math.Exp(hypothesis.AvgLogProb()),
+			AvgLogProb:   hypothesis.AvgLogProb(),
+			NoSpeechProb: hypothesis.NoSpeechProb,
+			Words:        scoreWords(tokenizer, hypothesis),
+		}
+
+		for _, alternative := range windowHypotheses {
+			if len(outputs[j].Alternatives) == options[j].MaxAlternatives {
+				break
+			}
+			outputs[j].Alternatives = append(outputs[j].Alternatives, Alternative{
+				Text:       alternative.Text,
+				Confidence: float32(math.Exp(alternative.AvgLogProb())),
+				Score:      float32(alternative.NormalizedScore()),
+			})
+		}
+	}
+	return outputs, nil
 }
 
-// updateStats updates the inference statistics
-func (i *Inference) updateStats(result *Result) {
-	i.stats.TotalInferences++
-	i.stats.TotalProcessingTime += float64(result.ProcessingTime)
-	i.stats.AverageProcessingTime = i.stats.TotalProcessingTime / float64(i.stats.TotalInferences)
+// runWhole runs each window through a runtime that does the whole pipeline
+// in one call; such runtimes cannot take prompts or hints
+func runWhole(ctx context.Context, runtime ONNXRuntime, windows []*Window) ([]*InferenceResult, error) {
+	// TODO: Run the batch as a single tensor once the ONNX Runtime Go bindings
+	// are integrated; for now each window goes through the runtime in turn
+	outputs := make([]*InferenceResult, 0, len(windows))
+	for j, window := range windows {
+		end := StartSpan(ctx, StageInference, map[string]interface{}{"window": j})
+		output, err := runtime.RunInference(ctx, window.Audio)
+		end()
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
 }
@@ -0,0 +1,119 @@
+package inference
+
+import (
+	"bufio"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// unknownLogProb is the log10 probability of a word missing from a language
+// model that has no <unk> entry
+const unknownLogProb = -10
+
+// NgramLM is a word n-gram language model with Katz-style backoff
+type NgramLM struct {
+	// order is the longest n-gram length
+	order int
+	// ngrams maps space-joined lowercase n-grams to their scores
+	ngrams map[string]ngramEntry
+}
+
+// ngramEntry holds an n-gram's log10 probability and backoff weight
+type ngramEntry struct {
+	logProb float64
+	backoff float64
+}
+
+// LoadARPA loads a language model in ARPA format
+func LoadARPA(path string) (*NgramLM, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open language model")
+	}
+	defer file.Close()
+
+	lm := &NgramLM{ngrams: make(map[string]ngramEntry)}
+	section := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "" || text == "\\data\\" || text == "\\end\\":
+			continue
+		case strings.HasPrefix(text, "ngram "):
+			continue
+		case strings.HasPrefix(text, "\\") && strings.HasSuffix(text, "-grams:"):
+			n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(text, "\\"), "-grams:"))
+			if err != nil {
+				return nil, errors.Errorf("%s:%d: bad section %q", path, line, text)
+			}
+			section = n
+			if n > lm.order {
+				lm.order = n
+			}
+			continue
+		}
+		if section == 0 {
+			return nil, errors.Errorf("%s:%d: n-gram outside a section", path, line)
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) < section+1 {
+			return nil, errors.Errorf("%s:%d: expected %d words", path, line, section)
+		}
+		entry := ngramEntry{}
+		if entry.logProb, err = strconv.ParseFloat(fields[0], 64); err != nil {
+			return nil, errors.Errorf("%s:%d: bad probability %q", path, line, fields[0])
+		}
+		if len(fields) > section+1 {
+			if entry.backoff, err = strconv.ParseFloat(fields[section+1], 64); err != nil {
+				return nil, errors.Errorf("%s:%d: bad backoff %q", path, line, fields[section+1])
+			}
+		}
+		lm.ngrams[strings.ToLower(strings.Join(fields[1:section+1], " "))] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read language model")
+	}
+	if lm.order == 0 {
+		return nil, errors.Errorf("%s: no n-grams", path)
+	}
+	return lm, nil
+}
+
+// Score returns the natural log probability of word following history
+func (lm *NgramLM) Score(history []string, word string) float64 {
+	if len(history) > lm.order-1 {
+		history = history[len(history)-(lm.order-1):]
+	}
+	context := make([]string, len(history))
+	for i, w := range history {
+		context[i] = strings.ToLower(w)
+	}
+	return lm.logProb10(context, strings.ToLower(word)) * math.Ln10
+}
+
+// logProb10 returns the log10 probability of word after context, backing
+// off to shorter contexts
+func (lm *NgramLM) logProb10(context []string, word string) float64 {
+	if entry, ok := lm.ngrams[strings.Join(append(append([]string{}, context...), word), " ")]; ok {
+		return entry.logProb
+	}
+	if len(context) == 0 {
+		if entry, ok := lm.ngrams["<unk>"]; ok {
+			return entry.logProb
+		}
+		return unknownLogProb
+	}
+
+	var backoff float64
+	if entry, ok := lm.ngrams[strings.Join(context, " ")]; ok {
+		backoff = entry.backoff
+	}
+	return backoff + lm.logProb10(context[1:], word)
+}
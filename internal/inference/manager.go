@@ -3,25 +3,52 @@ package inference
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/josealecrim/audiototext/internal/hardware"
 	"github.com/josealecrim/audiototext/internal/models"
 	"github.com/pkg/errors"
 )
 
-// Manager handles ONNX Runtime sessions and their lifecycle
+// Manager handles pools of ONNX Runtime sessions and their lifecycle
 type Manager struct {
-	// mu protects the sessions map
+	// mu protects the pools map
 	mu sync.RWMutex
-	// sessions maps model IDs to their respective sessions
-	sessions map[string]*Session
+	// pools maps model IDs to their session pools
+	pools map[string]*sessionPool
+	// schedulersMu serializes scheduler creation and teardown
+	schedulersMu sync.Mutex
+	// schedulers maps model IDs to their batching schedulers
+	schedulers map[string]*Scheduler
 	// hwDetector is used to determine available hardware capabilities
 	hwDetector *hardware.Detector
 	// defaultConfig is the default session configuration
 	defaultConfig SessionConfig
 	// defaultBatchConfig is the default batch processing configuration
 	defaultBatchConfig BatchConfig
+	// poolConfig is the configuration applied to every session pool
+	poolConfig PoolConfig
+	// newRuntime creates the ONNX runtime behind each session
+	newRuntime RuntimeFactory
+	// tracer keeps the traces of profiled requests
+	tracer *Tracer
+	// warmupResults maps model IDs to how their warmup went
+	warmupResults map[string]WarmupResult
+	// safeguards are the checks run on decoded text by new sessions
+	safeguards SafeguardConfig
+	// memoryConfig controls the memory budget
+	memoryConfig MemoryConfig
+	// budget admits sessions whose models fit in memory
+	budget *memoryBudget
+	// openSessions maps model IDs to their open sessions, pooled or standalone
+	openSessions map[string]int
+	// stopCh stops the idle eviction loop
+	stopCh chan struct{}
+	// stopOnce guards stopCh
+	stopOnce sync.Once
 }
 
 // NewManager creates a new session manager
@@ -43,40 +70,210 @@ func NewManager(hwDetector *hardware.Detector) *Manager {
 		PreferredBatchSizes: []int{1, 2, 4, 8, 16, 32},
 	}
 
-	// Adjust configuration based on hardware capabilities
+	poolConfig := PoolConfig{
+		MinSessions: 1,
+		MaxSessions: 1,
+		IdleTimeout: 5 * time.Minute,
+	}
+
+	// Prefer accelerators the hardware has, always falling back to CPU
+	var providers []ExecutionProvider
 	if hwDetector.HasCUDAGPU() {
-		defaultConfig.ExecutionProvider = CUDAExecutionProvider
-	} else if hwDetector.HasIntelGPU() {
-		defaultConfig.ExecutionProvider = OpenVINOExecutionProvider
+		providers = append(providers, CUDAExecutionProvider)
+	}
+	if hwDetector.HasIntelGPU() {
+		providers = append(providers, OpenVINOExecutionProvider)
 	}
+	providers = append(providers, CPUExecutionProvider)
+	defaultConfig.ExecutionProvider = providers[0]
+	defaultConfig.ExecutionProviders = providers
 
 	// Adjust thread counts based on CPU cores
-	cpuInfo := hwDetector.GetCPUInfo()
-	if cpuInfo.Cores > 2 {
-		defaultConfig.InterOpNumThreads = cpuInfo.Cores / 2
-		defaultConfig.IntraOpNumThreads = cpuInfo.Cores / 2
+	numCPUs := hwDetector.GetNumCPUs()
+	if numCPUs > 2 {
+		defaultConfig.InterOpNumThreads = numCPUs / 2
+		defaultConfig.IntraOpNumThreads = numCPUs / 2
 	}
 
-	return &Manager{
-		sessions:           make(map[string]*Session),
+	// Allow as many concurrent sessions per model as the cores can keep busy
+	if sessions := numCPUs / defaultConfig.IntraOpNumThreads; sessions > poolConfig.MaxSessions {
+		poolConfig.MaxSessions = sessions
+	}
+
+	m := &Manager{
+		pools:              make(map[string]*sessionPool),
+		schedulers:         make(map[string]*Scheduler),
 		hwDetector:         hwDetector,
 		defaultConfig:      defaultConfig,
 		defaultBatchConfig: defaultBatchConfig,
+		poolConfig:         poolConfig,
+		newRuntime:         NewONNXRuntime,
+		tracer:             NewTracer(tracesKept),
+		warmupResults:      make(map[string]WarmupResult),
+		safeguards:         DefaultSafeguardConfig(),
+		budget:             &memoryBudget{},
+		openSessions:       make(map[string]int),
+		stopCh:             make(chan struct{}),
+	}
+	m.SetMemoryConfig(MemoryConfig{})
+
+	go m.evictionLoop()
+
+	return m
+}
+
+// SetPoolConfig overrides the pool configuration derived from the hardware;
+// it only applies to pools created afterwards
+func (m *Manager) SetPoolConfig(config PoolConfig) {
+	if config.MaxSessions < 1 {
+		config.MaxSessions = 1
+	}
+	if config.MinSessions > config.MaxSessions {
+		config.MinSessions = config.MaxSessions
+	}
+
+	m.mu.Lock()
+	m.poolConfig = config
+	m.mu.Unlock()
+}
+
+// SetBatchConfig overrides the default batch configuration; it only applies
+// to pools and sessions created afterwards
+func (m *Manager) SetBatchConfig(config BatchConfig) {
+	m.mu.Lock()
+	m.defaultBatchConfig = config
+	m.mu.Unlock()
+}
+
+// SetRuntimeFactory replaces the function used to create ONNX runtimes;
+// it only applies to sessions opened afterwards
+func (m *Manager) SetRuntimeFactory(factory RuntimeFactory) {
+	m.mu.Lock()
+	m.newRuntime = factory
+	m.mu.Unlock()
+}
+
+// SetSafeguardConfig replaces the checks run on decoded text; it only
+// applies to sessions opened afterwards
+func (m *Manager) SetSafeguardConfig(config SafeguardConfig) {
+	m.mu.Lock()
+	m.safeguards = config
+	m.mu.Unlock()
+}
+
+// tracesKept is the number of recent request traces kept for download
+const tracesKept = 100
+
+// SetProfiling turns per-request stage tracing on or off
+func (m *Manager) SetProfiling(enabled bool) {
+	m.mu.Lock()
+	m.defaultConfig.EnableProfiling = enabled
+	m.mu.Unlock()
+}
+
+// StartTrace starts a trace for a request when profiling is enabled and
+// returns a context that records the request's stages into it; otherwise
+// it returns ctx and a nil trace
+func (m *Manager) StartTrace(ctx context.Context, requestID, modelID string) (context.Context, *Trace) {
+	m.mu.RLock()
+	enabled := m.defaultConfig.EnableProfiling
+	m.mu.RUnlock()
+
+	if !enabled {
+		return ctx, nil
+	}
+
+	trace := m.tracer.StartTrace(requestID, modelID)
+	return WithTrace(ctx, trace), trace
+}
+
+// Tracer returns the tracer holding recent request traces; it serves them
+// over HTTP in Chrome trace-event format
+func (m *Manager) Tracer() *Tracer {
+	return m.tracer
+}
+
+// Acquire returns a warm session for the given model for exclusive use,
+// opening one if the pool has capacity and waiting otherwise. The session
+// must be returned with Release.
+func (m *Manager) Acquire(ctx context.Context, model *models.ONNXModel) (*Session, error) {
+	if model == nil {
+		return nil, errors.New("model cannot be nil")
+	}
+
+	session, err := m.getPool(model).acquire(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to acquire session for model %s", model.ID)
 	}
+	return session, nil
 }
 
-// CreateSession creates a new ONNX Runtime session for the given model
+// Release returns a session obtained from Acquire to its pool
+func (m *Manager) Release(session *Session) error {
+	if session == nil || session.pool == nil {
+		return errors.New("session does not belong to a pool")
+	}
+	return session.pool.release(session)
+}
+
+// GetScheduler returns the batching scheduler for a model, warming the
+// model's session pool on first use
+func (m *Manager) GetScheduler(ctx context.Context, model *models.ONNXModel) (*Scheduler, error) {
+	if model == nil {
+		return nil, errors.New("model cannot be nil")
+	}
+
+	m.schedulersMu.Lock()
+	defer m.schedulersMu.Unlock()
+
+	if scheduler, exists := m.schedulers[model.ID]; exists {
+		return scheduler, nil
+	}
+
+	// Pay the model load cost now rather than on the first batch
+	pool := m.getPool(model)
+	session, err := pool.acquire(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to warm session pool for model %s", model.ID)
+	}
+	if err := pool.release(session); err != nil {
+		return nil, err
+	}
+
+	run := func(ctx context.Context, windows []*Window) ([]*Result, error) {
+		// Looked up per batch so that a replaced model's next batch runs on the new pool
+		pool := m.getPool(model)
+		session, err := pool.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer pool.release(session)
+
+		return NewInference(session).ProcessWindows(ctx, windows)
+	}
+
+	scheduler := NewScheduler(model.ID, run, pool.batchConfig, pool.poolConfig.MaxSessions)
+	m.schedulers[model.ID] = scheduler
+	return scheduler, nil
+}
+
+// CreateSession creates a standalone ONNX Runtime session for the given model
+// that is not part of any pool; it must be closed with CloseSession
 func (m *Manager) CreateSession(ctx context.Context, model *models.ONNXModel, config *SessionConfig, batchConfig *BatchConfig) (*Session, error) {
 	if model == nil {
 		return nil, errors.New("model cannot be nil")
 	}
 
-	// Use default configurations if not provided
+	// Use default configurations if not provided; they are copied because
+	// SetProfiling and SetBatchConfig may change them concurrently
+	m.mu.RLock()
+	defaultConfig, defaultBatchConfig := m.defaultConfig, m.defaultBatchConfig
+	m.mu.RUnlock()
 	if config == nil {
-		config = &m.defaultConfig
+		config = &defaultConfig
 	}
 	if batchConfig == nil {
-		batchConfig = &m.defaultBatchConfig
+		batchConfig = &defaultBatchConfig
 	}
 
 	// Create session
@@ -91,58 +288,93 @@ func (m *Manager) CreateSession(ctx context.Context, model *models.ONNXModel, co
 		return nil, errors.Wrap(err, "failed to initialize session")
 	}
 
-	// Store session
-	m.mu.Lock()
-	m.sessions[model.ID] = session
-	m.mu.Unlock()
-
 	return session, nil
 }
 
-// GetSession retrieves an existing session for the given model ID
-func (m *Manager) GetSession(modelID string) (*Session, error) {
-	m.mu.RLock()
-	session, exists := m.sessions[modelID]
-	m.mu.RUnlock()
-
-	if !exists {
-		return nil, fmt.Errorf("no session found for model ID: %s", modelID)
+// CloseSession closes a standalone session created with CreateSession
+func (m *Manager) CloseSession(session *Session) error {
+	if session == nil {
+		return errors.New("session cannot be nil")
+	}
+	if session.pool != nil {
+		return errors.New("pooled sessions must be released, not closed")
 	}
 
-	return session, nil
+	if err := m.closeSession(session); err != nil {
+		return errors.Wrap(err, "failed to close session")
+	}
+	return nil
 }
 
-// CloseSession closes and removes a session for the given model ID
-func (m *Manager) CloseSession(modelID string) error {
+// ReplaceModel moves the model's pool to a new handle for the same ID, such
+// as a version restored from a backup; it does nothing if the model has no
+// pool. Later sessions load the new handle while the old pool drains: idle
+// sessions close now and sessions in use close when released. The
+// scheduler keeps running, so open streams switch over at their next batch
+func (m *Manager) ReplaceModel(model *models.ONNXModel) error {
+	if model == nil {
+		return errors.New("model cannot be nil")
+	}
+
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	old, exists := m.pools[model.ID]
+	if !exists || old.model == model {
+		m.mu.Unlock()
+		return nil
+	}
+	m.pools[model.ID] = newSessionPool(model, m.defaultConfig, m.defaultBatchConfig, m.poolConfig, m.initializeSession, m.closeSession)
+	m.mu.Unlock()
 
-	session, exists := m.sessions[modelID]
-	if !exists {
-		return fmt.Errorf("no session found for model ID: %s", modelID)
+	return old.shutdown()
+}
+
+// ClosePool stops the model's scheduler and closes its session pool;
+// sessions still in use are closed when they are released
+func (m *Manager) ClosePool(modelID string) error {
+	m.schedulersMu.Lock()
+	defer m.schedulersMu.Unlock()
+
+	if scheduler, exists := m.schedulers[modelID]; exists {
+		scheduler.Stop()
+		delete(m.schedulers, modelID)
 	}
 
-	// Close the session
-	if err := m.closeSession(session); err != nil {
-		return errors.Wrap(err, "failed to close session")
+	m.mu.Lock()
+	pool, exists := m.pools[modelID]
+	delete(m.pools, modelID)
+	m.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("no session pool found for model ID: %s", modelID)
 	}
 
-	// Remove from map
-	delete(m.sessions, modelID)
-	return nil
+	return pool.shutdown()
 }
 
-// CloseAllSessions closes all active sessions
+// CloseAllSessions closes every session pool and stops idle eviction
 func (m *Manager) CloseAllSessions() error {
+	m.stopOnce.Do(func() {
+		close(m.stopCh)
+	})
+
+	m.schedulersMu.Lock()
+	defer m.schedulersMu.Unlock()
+
+	for modelID, scheduler := range m.schedulers {
+		scheduler.Stop()
+		delete(m.schedulers, modelID)
+	}
+
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	pools := m.pools
+	m.pools = make(map[string]*sessionPool)
+	m.mu.Unlock()
 
 	var errs []error
-	for modelID, session := range m.sessions {
-		if err := m.closeSession(session); err != nil {
-			errs = append(errs, fmt.Errorf("failed to close session for model %s: %v", modelID, err))
+	for _, pool := range pools {
+		if err := pool.shutdown(); err != nil {
+			errs = append(errs, err)
 		}
-		delete(m.sessions, modelID)
 	}
 
 	if len(errs) > 0 {
@@ -151,37 +383,216 @@ func (m *Manager) CloseAllSessions() error {
 	return nil
 }
 
-// GetStats returns statistics for all active sessions
+// GetStats returns statistics for every model with a session pool
 func (m *Manager) GetStats() map[string]Stats {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	stats := make(map[string]Stats)
-	for modelID, session := range m.sessions {
-		// Get stats from session
-		sessionStats := m.getSessionStats(session)
-		stats[modelID] = sessionStats
+	for modelID, pool := range m.pools {
+		sessions, _ := pool.snapshot()
+
+		all := make([]Stats, 0, len(sessions))
+		var latencies []float64
+		for _, session := range sessions {
+			sessionStats, sessionLatencies := m.getSessionStats(session)
+			all = append(all, sessionStats)
+			latencies = append(latencies, sessionLatencies...)
+		}
+
+		// Memory peaks are tracked per pool since session peaks don't coincide
+		modelStats := mergeStats(all, latencies)
+		poolMemory, _ := pool.memory.snapshot()
+		modelStats.CurrentMemoryUsage = poolMemory.CurrentMemoryUsage
+		modelStats.PeakMemoryUsage = poolMemory.PeakMemoryUsage
+		stats[modelID] = modelStats
 	}
 
 	return stats
 }
 
-// initializeSession initializes the ONNX Runtime session
-func (m *Manager) initializeSession(ctx context.Context, session *Session) error {
-	// TODO: Implement actual ONNX Runtime session initialization
-	// This will be implemented when we integrate the ONNX Runtime Go bindings
+// GetPoolStats returns the state of every session pool
+func (m *Manager) GetPoolStats() map[string]PoolStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := make(map[string]PoolStats)
+	for modelID, pool := range m.pools {
+		sessions, inUse := pool.snapshot()
+		stats[modelID] = PoolStats{
+			OpenSessions:  len(sessions),
+			InUseSessions: inUse,
+			MaxSessions:   pool.poolConfig.MaxSessions,
+		}
+	}
+
+	return stats
+}
+
+// GetProviderStatus returns the execution providers in use and the recorded
+// fallbacks for every model with open sessions
+func (m *Manager) GetProviderStatus() map[string]ProviderStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := make(map[string]ProviderStatus)
+	for modelID, pool := range m.pools {
+		sessions, _ := pool.snapshot()
+		modelStatus := ProviderStatus{Sessions: make(map[ExecutionProvider]int)}
+		for _, session := range sessions {
+			provider, fallbacks := session.ProviderInfo()
+			if provider != "" {
+				modelStatus.Sessions[provider]++
+			}
+			modelStatus.Fallbacks = append(modelStatus.Fallbacks, fallbacks...)
+		}
+		status[modelID] = modelStatus
+	}
+
+	return status
+}
+
+// getPool returns the session pool for a model, creating it if needed
+func (m *Manager) getPool(model *models.ONNXModel) *sessionPool {
+	m.mu.RLock()
+	pool, exists := m.pools[model.ID]
+	m.mu.RUnlock()
+	if exists {
+		return pool
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if pool, exists := m.pools[model.ID]; exists {
+		return pool
+	}
+
+	pool = newSessionPool(model, m.defaultConfig, m.defaultBatchConfig, m.poolConfig, m.initializeSession, m.closeSession)
+	m.pools[model.ID] = pool
+	return pool
+}
+
+// evictionLoop periodically closes sessions that have been idle too long
+func (m *Manager) evictionLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case now := <-ticker.C:
+			m.mu.RLock()
+			pools := make([]*sessionPool, 0, len(m.pools))
+			for _, pool := range m.pools {
+				pools = append(pools, pool)
+			}
+			m.mu.RUnlock()
+
+			for _, pool := range pools {
+				// Eviction errors only affect sessions that are already being discarded
+				_ = pool.evictIdle(now)
+			}
+		}
+	}
+}
+
+// initializeSession initializes the ONNX Runtime session on the first
+// execution provider in its chain that starts successfully
+func (m *Manager) initializeSession(ctx context.Context, session *Session) (err error) {
+	// The model counts as in use from before its files are read
+	m.mu.Lock()
+	newRuntime := m.newRuntime
+	session.safeguards = m.safeguards
+	m.openSessions[session.Model.ID]++
+	m.mu.Unlock()
+	defer func() {
+		if err != nil {
+			m.forgetSession(session.Model.ID)
+		} else {
+			session.counted = true
+		}
+	}()
+
+	calibration, err := LoadCalibration(filepath.Dir(session.Model.Path))
+	if err != nil {
+		return err
+	}
+	session.calibration = calibration
+
+	if isCTC(session.Model) {
+		if session.ctc, err = loadCTCModel(filepath.Dir(session.Model.Path)); err != nil {
+			return err
+		}
+	}
+
+	// The registry read the graph, so the shapes are known before the runtime loads it
+	if session.Model.Model != nil && session.Model.Graph != nil {
+		graph := session.Model.Graph
+		if len(graph.Inputs) > 0 {
+			session.InputShape = graph.Inputs[0].Dims()
+		}
+		if len(graph.Outputs) > 0 {
+			session.OutputShape = graph.Outputs[0].Dims()
+		}
+	}
+
+	// Check the model fits before loading it
+	footprint := EstimateFootprint(session.Model)
+	if err := m.admit(session.Model, footprint); err != nil {
+		return err
+	}
+
+	session.newRuntime = newRuntime
+	if err := session.openProvider(newRuntime, 0); err != nil {
+		m.budget.release(footprint)
+		return err
+	}
+	session.footprint = footprint
+
+	// Account for the model weights held by the session
+	if info, err := os.Stat(session.Model.Path); err == nil {
+		session.weightBytes = info.Size()
+		session.allocate(session.weightBytes)
+	}
+
 	return nil
 }
 
 // closeSession closes an ONNX Runtime session
 func (m *Manager) closeSession(session *Session) error {
-	// TODO: Implement actual ONNX Runtime session cleanup
-	// This will be implemented when we integrate the ONNX Runtime Go bindings
-	return nil
+	if session.counted {
+		session.counted = false
+		m.forgetSession(session.Model.ID)
+	}
+	session.free(session.weightBytes)
+	session.weightBytes = 0
+	m.budget.release(session.footprint)
+	session.footprint = 0
+	return session.closeProvider()
+}
+
+// forgetSession stops counting a session of the model as open
+func (m *Manager) forgetSession(modelID string) {
+	m.mu.Lock()
+	m.openSessions[modelID]--
+	if m.openSessions[modelID] <= 0 {
+		delete(m.openSessions, modelID)
+	}
+	m.mu.Unlock()
+}
+
+// HasSessions reports whether the model has open sessions, pooled or
+// standalone, so its files must stay on disk
+func (m *Manager) HasSessions(modelID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.openSessions[modelID] > 0
 }
 
-// getSessionStats retrieves statistics for a session
-func (m *Manager) getSessionStats(session *Session) Stats {
-	// TODO: Implement actual statistics collection from ONNX Runtime session
-	return Stats{}
+// getSessionStats retrieves statistics and recent latency samples for a session
+func (m *Manager) getSessionStats(session *Session) (Stats, []float64) {
+	_, latencies := session.stats.snapshot()
+	return session.Stats(), latencies
 }
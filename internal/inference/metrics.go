@@ -0,0 +1,42 @@
+package inference
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// Scheduler Metrics
+	schedulerQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "inference_scheduler_queue_depth",
+		Help: "Number of audio windows waiting to be batched",
+	}, []string{"model"})
+
+	schedulerBatchSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "inference_scheduler_batch_size",
+		Help:    "Number of real audio windows in each dispatched batch",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 7),
+	}, []string{"model"})
+
+	schedulerPaddedWindows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "inference_scheduler_padded_windows_total",
+		Help: "Number of silent windows added to reach a preferred batch size",
+	}, []string{"model"})
+
+	// Execution Provider Metrics
+	sessionsByProvider = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "inference_sessions_by_provider",
+		Help: "Number of open sessions running on each execution provider",
+	}, []string{"model", "provider"})
+
+	providerFallbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "inference_provider_fallbacks_total",
+		Help: "Number of times a session fell back from an execution provider",
+	}, []string{"model", "provider", "stage"})
+
+	// Warmup Metrics
+	warmupDuration = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "inference_warmup_duration_seconds",
+		Help: "Time taken to load a model and to run each batch size during warmup",
+	}, []string{"model", "stage"})
+)
@@ -9,9 +9,19 @@ type InferenceResult struct {
 	Text       string
 	Confidence float64
 	Timestamps []float64
+	// AvgLogProb is the mean token log-probability, 0 when unknown
+	AvgLogProb float64
+	// NoSpeechProb is the probability that the window holds no speech, 0 when unknown
+	NoSpeechProb float64
+	// Words are the decoded words with their token log-probabilities, nil when unknown
+	Words []WordScore
+	// Alternatives are the best hypotheses with uncalibrated confidences, nil when not requested
+	Alternatives []Alternative
 }
 
-// ONNXRuntime provides methods for ONNX model inference
+// ONNXRuntime provides methods for ONNX model inference; implementations
+// report failures of the execution provider itself as *ProviderError so the
+// session can fall back to the next provider
 type ONNXRuntime interface {
 	SetExecutionProvider(provider string) error
 	HasGPUSupport() bool
@@ -0,0 +1,227 @@
+package inference
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/pkg/errors"
+)
+
+// ErrPoolClosed is returned when acquiring from or releasing to a closed pool
+var ErrPoolClosed = errors.New("session pool closed")
+
+// sessionPool keeps warm sessions for a single model and hands them out
+// for exclusive use
+type sessionPool struct {
+	// model is the model every session in the pool runs
+	model *models.ONNXModel
+	// config is the configuration for new sessions
+	config SessionConfig
+	// batchConfig is the batch configuration for new sessions
+	batchConfig BatchConfig
+	// poolConfig controls pool sizing and eviction
+	poolConfig PoolConfig
+	// open initializes a new session
+	open func(ctx context.Context, session *Session) error
+	// close releases a session's resources
+	close func(session *Session) error
+
+	// slots limits the number of sessions handed out at once
+	slots chan struct{}
+	// memory tracks the memory held by all sessions in the pool
+	memory statsRecorder
+
+	// mu protects the fields below
+	mu sync.Mutex
+	// idle holds released sessions, most recently used last
+	idle []*Session
+	// sessions holds every open session, idle or in use
+	sessions map[*Session]struct{}
+	// refs is the number of sessions currently acquired
+	refs int
+	// closed is set once the pool has been closed
+	closed bool
+}
+
+// idleSession is an idle session and when it was released
+type idleSession struct {
+	session  *Session
+	lastUsed time.Time
+}
+
+// newSessionPool creates an empty pool for the given model
+func newSessionPool(model *models.ONNXModel, config SessionConfig, batchConfig BatchConfig, poolConfig PoolConfig,
+	open func(context.Context, *Session) error, close func(*Session) error) *sessionPool {
+	return &sessionPool{
+		model:       model,
+		config:      config,
+		batchConfig: batchConfig,
+		poolConfig:  poolConfig,
+		open:        open,
+		close:       close,
+		slots:       make(chan struct{}, poolConfig.MaxSessions),
+		sessions:    make(map[*Session]struct{}),
+	}
+}
+
+// acquire returns an idle session or opens a new one, waiting while the pool is at capacity
+func (p *sessionPool) acquire(ctx context.Context) (*Session, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		<-p.slots
+		return nil, ErrPoolClosed
+	}
+	if n := len(p.idle); n > 0 {
+		session := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.refs++
+		p.mu.Unlock()
+		return session, nil
+	}
+	p.refs++
+	p.mu.Unlock()
+
+	// Open outside the lock; model load is the expensive part
+	session := &Session{
+		Model:       p.model,
+		Config:      p.config,
+		BatchConfig: p.batchConfig,
+		pool:        p,
+	}
+	if err := p.open(ctx, session); err != nil {
+		p.mu.Lock()
+		p.refs--
+		p.mu.Unlock()
+		<-p.slots
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.sessions[session] = struct{}{}
+	p.mu.Unlock()
+
+	return session, nil
+}
+
+// release returns a session to the pool, closing it if the pool has been closed
+func (p *sessionPool) release(session *Session) error {
+	p.mu.Lock()
+	p.refs--
+	session.lastUsed = time.Now()
+	if p.closed {
+		delete(p.sessions, session)
+		p.mu.Unlock()
+		<-p.slots
+		return p.close(session)
+	}
+	p.idle = append(p.idle, session)
+	p.mu.Unlock()
+
+	<-p.slots
+	return nil
+}
+
+// evictIdle closes sessions that have been idle longer than the idle timeout,
+// keeping at least MinSessions open
+func (p *sessionPool) evictIdle(now time.Time) error {
+	p.mu.Lock()
+	var evicted []*Session
+	kept := p.idle[:0]
+	open := len(p.sessions)
+	for _, session := range p.idle {
+		if open > p.poolConfig.MinSessions && now.Sub(session.lastUsed) > p.poolConfig.IdleTimeout {
+			evicted = append(evicted, session)
+			delete(p.sessions, session)
+			open--
+			continue
+		}
+		kept = append(kept, session)
+	}
+	p.idle = kept
+	p.mu.Unlock()
+
+	var errs []error
+	for _, session := range evicted {
+		if err := p.close(session); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("errors evicting sessions for model %s: %v", p.model.ID, errs)
+	}
+	return nil
+}
+
+// idleSessions returns the pool's idle sessions
+func (p *sessionPool) idleSessions() []idleSession {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idle := make([]idleSession, len(p.idle))
+	for i, session := range p.idle {
+		idle[i] = idleSession{session: session, lastUsed: session.lastUsed}
+	}
+	return idle
+}
+
+// evict removes a session from the pool if it is still idle, reporting
+// whether it did; the caller closes the evicted session
+func (p *sessionPool) evict(session *Session) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, idle := range p.idle {
+		if idle == session {
+			p.idle = append(p.idle[:i], p.idle[i+1:]...)
+			delete(p.sessions, session)
+			return true
+		}
+	}
+	return false
+}
+
+// shutdown closes idle sessions and marks the pool closed; sessions in use
+// are closed when they are released
+func (p *sessionPool) shutdown() error {
+	p.mu.Lock()
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	for _, session := range idle {
+		delete(p.sessions, session)
+	}
+	p.mu.Unlock()
+
+	var errs []error
+	for _, session := range idle {
+		if err := p.close(session); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("errors closing sessions for model %s: %v", p.model.ID, errs)
+	}
+	return nil
+}
+
+// snapshot returns every open session and the number in use
+func (p *sessionPool) snapshot() ([]*Session, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sessions := make([]*Session, 0, len(p.sessions))
+	for session := range p.sessions {
+		sessions = append(sessions, session)
+	}
+	return sessions, p.refs
+}
@@ -0,0 +1,168 @@
+package inference
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RuntimeFactory creates an ONNX runtime for the model file at modelPath
+type RuntimeFactory func(modelPath string) (ONNXRuntime, error)
+
+// ProviderFallback records why a session moved off an execution provider
+type ProviderFallback struct {
+	// Provider is the execution provider that was abandoned
+	Provider ExecutionProvider
+	// Stage is "init" when the provider failed to start and "runtime" when it failed during inference
+	Stage string
+	// Reason is the error reported by the provider
+	Reason string
+	// Time is when the fallback happened
+	Time time.Time
+}
+
+// String formats the fallback for logs and status details
+func (f ProviderFallback) String() string {
+	return fmt.Sprintf("%s failed at %s: %s", f.Provider, f.Stage, f.Reason)
+}
+
+// ProviderError is returned by runtimes when the execution provider itself
+// fails, such as a lost device or a kernel the provider cannot run. Only
+// these move a session to the next provider; any other error belongs to
+// the request and would fail the same way on every provider
+type ProviderError struct {
+	Err error
+}
+
+// Error implements error
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("execution provider failed: %v", e.Err)
+}
+
+// Unwrap returns the provider's error
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// ProviderStatus describes the execution providers in use for a model
+type ProviderStatus struct {
+	// Sessions counts open sessions by active execution provider
+	Sessions map[ExecutionProvider]int
+	// Fallbacks lists the fallbacks recorded by the model's open sessions
+	Fallbacks []ProviderFallback
+}
+
+// providerChain returns the session's provider preference list, always ending with CPU
+func (s *Session) providerChain() []ExecutionProvider {
+	chain := s.Config.ExecutionProviders
+	if len(chain) == 0 {
+		chain = []ExecutionProvider{s.Config.ExecutionProvider}
+	}
+	if chain[len(chain)-1] != CPUExecutionProvider {
+		chain = append(append([]ExecutionProvider{}, chain...), CPUExecutionProvider)
+	}
+	return chain
+}
+
+// openProvider starts a runtime on the first provider at or after index from
+// that initializes successfully, recording a fallback for each one that fails
+func (s *Session) openProvider(newRuntime RuntimeFactory, from int) error {
+	chain := s.providerChain()
+	for i := from; i < len(chain); i++ {
+		provider := chain[i]
+
+		runtime, err := newRuntime(s.Model.Path)
+		if err == nil {
+			if err = runtime.SetExecutionProvider(string(provider)); err != nil {
+				runtime.Close()
+			}
+		}
+		if err != nil {
+			s.recordFallback(provider, "init", err)
+			continue
+		}
+
+		s.mu.Lock()
+		s.runtime = runtime
+		s.providerIndex = i
+		s.ActiveProvider = provider
+		s.mu.Unlock()
+
+		sessionsByProvider.WithLabelValues(s.Model.ID, string(provider)).Inc()
+		return nil
+	}
+
+	return errors.Errorf("no execution provider could be initialized for model %s", s.Model.ID)
+}
+
+// closeProvider closes the session's runtime, if any
+func (s *Session) closeProvider() error {
+	s.mu.Lock()
+	runtime := s.runtime
+	provider := s.ActiveProvider
+	s.runtime = nil
+	s.ActiveProvider = ""
+	s.mu.Unlock()
+
+	if runtime == nil {
+		return nil
+	}
+
+	sessionsByProvider.WithLabelValues(s.Model.ID, string(provider)).Dec()
+	return runtime.Close()
+}
+
+// run calls fn with the session's runtime; if the provider fails with a
+// ProviderError, the session falls back to the next provider in its chain
+// and retries. Other errors are returned unchanged
+func (s *Session) run(ctx context.Context, fn func(runtime ONNXRuntime) error) error {
+	for {
+		s.mu.RLock()
+		runtime := s.runtime
+		index := s.providerIndex
+		provider := s.ActiveProvider
+		s.mu.RUnlock()
+
+		if runtime == nil {
+			return errors.New("session is not initialized")
+		}
+
+		err := fn(runtime)
+		var failure *ProviderError
+		if !errors.As(err, &failure) || ctx.Err() != nil || index+1 >= len(s.providerChain()) || s.newRuntime == nil {
+			return err
+		}
+
+		s.recordFallback(provider, "runtime", err)
+		s.closeProvider()
+		if openErr := s.openProvider(s.newRuntime, index+1); openErr != nil {
+			return errors.Wrapf(openErr, "%s failed: %v", provider, err)
+		}
+	}
+}
+
+// recordFallback appends a fallback to the session and exports it as a metric
+func (s *Session) recordFallback(provider ExecutionProvider, stage string, err error) {
+	s.mu.Lock()
+	s.FallbackReasons = append(s.FallbackReasons, ProviderFallback{
+		Provider: provider,
+		Stage:    stage,
+		Reason:   err.Error(),
+		Time:     time.Now(),
+	})
+	s.mu.Unlock()
+
+	providerFallbacks.WithLabelValues(s.Model.ID, string(provider), stage).Inc()
+}
+
+// ProviderInfo returns the session's active provider and a copy of its fallback history
+func (s *Session) ProviderInfo() (ExecutionProvider, []ProviderFallback) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fallbacks := make([]ProviderFallback, len(s.FallbackReasons))
+	copy(fallbacks, s.FallbackReasons)
+	return s.ActiveProvider, fallbacks
+}
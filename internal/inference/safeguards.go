@@ -0,0 +1,220 @@
+package inference
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SafeguardConfig controls the checks run on decoded text to catch the
+// repetition loops and invented text Whisper-style models produce
+type SafeguardConfig struct {
+	// MaxNGram is the longest word sequence checked for repetition
+	MaxNGram int
+	// MaxRepeats is the number of consecutive copies of a word sequence at
+	// which the text is truncated after the first copy
+	MaxRepeats int
+	// NoSpeechThreshold is the no-speech probability above which a window
+	// is treated as silent when its average log-probability is also low
+	NoSpeechThreshold float64
+	// LogProbThreshold is the average log-probability below which a window
+	// with a high no-speech probability is dropped
+	LogProbThreshold float64
+	// CompressionRatioThreshold is the text to compressed text size ratio
+	// above which text is considered degenerate and dropped
+	CompressionRatioThreshold float64
+	// Hallucinations maps language prefixes ("en", "pt") to phrases models
+	// produce on silence or music; sentences containing them are removed
+	Hallucinations map[string][]string
+}
+
+// DefaultSafeguardConfig returns thresholds matching the reference Whisper decoder
+func DefaultSafeguardConfig() SafeguardConfig {
+	return SafeguardConfig{
+		MaxNGram:                  8,
+		MaxRepeats:                3,
+		NoSpeechThreshold:         0.6,
+		LogProbThreshold:          -1.0,
+		CompressionRatioThreshold: 2.4,
+		Hallucinations: map[string][]string{
+			"en": {
+				"thank you for watching",
+				"thanks for watching",
+				"please subscribe",
+				"like and subscribe",
+				"subtitles by the amara.org community",
+			},
+			"pt": {
+				"obrigado por assistir",
+				"obrigada por assistir",
+				"inscreva-se no canal",
+				"legendas pela comunidade amara.org",
+				"legenda adriana zanotto",
+			},
+		},
+	}
+}
+
+// applySafeguards checks a window's decoded output and returns the text to
+// keep together with a warning for every suppression made; checks with a
+// zero threshold are skipped
+func applySafeguards(config SafeguardConfig, language string, output *InferenceResult) (string, []string) {
+	text := output.Text
+	var warnings []string
+
+	// Silence: the model thinks there is no speech and isn't confident in its text
+	if config.NoSpeechThreshold > 0 && output.NoSpeechProb > config.NoSpeechThreshold && output.AvgLogProb != 0 && output.AvgLogProb < config.LogProbThreshold {
+		if text != "" {
+			warnings = append(warnings, fmt.Sprintf("no_speech: dropped %q (no_speech_prob=%.2f, avg_logprob=%.2f)",
+				text, output.NoSpeechProb, output.AvgLogProb))
+		}
+		return "", warnings
+	}
+
+	if filtered, removed := removeHallucinations(text, config.Hallucinations, language); len(removed) > 0 {
+		for _, sentence := range removed {
+			warnings = append(warnings, fmt.Sprintf("hallucination: removed %q", sentence))
+		}
+		text = filtered
+	}
+
+	if config.MaxRepeats > 0 {
+		if truncated, phrase, repeats := truncateRepetitions(text, config.MaxNGram, config.MaxRepeats); repeats > 0 {
+			warnings = append(warnings, fmt.Sprintf("repetition: %q repeated %d times, truncated", phrase, repeats))
+			text = truncated
+		}
+	}
+
+	if config.CompressionRatioThreshold > 0 {
+		if ratio := compressionRatio(text); ratio > config.CompressionRatioThreshold {
+			warnings = append(warnings, fmt.Sprintf("compression_ratio: dropped %q (ratio=%.2f)", text, ratio))
+			text = ""
+		}
+	}
+
+	return text, warnings
+}
+
+// truncateRepetitions finds the earliest run of a word sequence repeated
+// at least maxRepeats times in a row and cuts the text after its first
+// copy; it returns the text, the repeated phrase and its repeat count.
+// Single words need one extra repeat, since "no, no, no" is ordinary speech.
+func truncateRepetitions(text string, maxNGram, maxRepeats int) (string, string, int) {
+	words := strings.Fields(text)
+	normalized := make([]string, len(words))
+	for i, word := range words {
+		normalized[i] = NormalizeWord(word)
+	}
+
+	for start := 0; start < len(words); start++ {
+		for n := 1; n <= maxNGram && start+2*n <= len(words); n++ {
+			repeats := 1
+			for next := start + n; next+n <= len(words) && equalWords(normalized[start:start+n], normalized[next:next+n]); next += n {
+				repeats++
+			}
+
+			limit := maxRepeats
+			if n == 1 {
+				limit++
+			}
+			if repeats >= limit {
+				return strings.Join(words[:start+n], " "), strings.Join(words[start:start+n], " "), repeats
+			}
+		}
+	}
+	return text, "", 0
+}
+
+// removeHallucinations drops sentences containing a known hallucination
+// for the language and returns the remaining text and the removed sentences
+func removeHallucinations(text string, hallucinations map[string][]string, language string) (string, []string) {
+	language = strings.ToLower(language)
+	if i := strings.IndexAny(language, "-_"); i >= 0 {
+		language = language[:i]
+	}
+	phrases := hallucinations[language]
+	if len(phrases) == 0 || text == "" {
+		return text, nil
+	}
+
+	var kept, removed []string
+	for _, sentence := range splitSentences(text) {
+		normalized := normalizeText(sentence)
+		hallucinated := false
+		for _, phrase := range phrases {
+			if strings.Contains(normalized, normalizeText(phrase)) {
+				hallucinated = true
+				break
+			}
+		}
+		if hallucinated {
+			removed = append(removed, sentence)
+		} else {
+			kept = append(kept, sentence)
+		}
+	}
+	return strings.Join(kept, " "), removed
+}
+
+// compressionRatio returns the size of text divided by its zlib-compressed
+// size; repetitive text compresses well and scores high
+func compressionRatio(text string) float64 {
+	if text == "" {
+		return 0
+	}
+
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(text))
+	w.Close()
+	return float64(len(text)) / float64(buf.Len())
+}
+
+// splitSentences splits text after sentence-ending punctuation followed by
+// a space, so "amara.org" stays whole
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i, r := range text {
+		if (r == '.' || r == '!' || r == '?') && (i+1 == len(text) || text[i+1] == ' ') {
+			if sentence := strings.TrimSpace(text[start : i+1]); sentence != "" {
+				sentences = append(sentences, sentence)
+			}
+			start = i + 1
+		}
+	}
+	if sentence := strings.TrimSpace(text[start:]); sentence != "" {
+		sentences = append(sentences, sentence)
+	}
+	return sentences
+}
+
+// normalizeText lowercases text, drops punctuation other than that inside
+// words and collapses whitespace
+func normalizeText(text string) string {
+	words := strings.Fields(text)
+	for i, word := range words {
+		words[i] = NormalizeWord(word)
+	}
+	return strings.Join(words, " ")
+}
+
+// NormalizeWord lowercases a word and trims surrounding punctuation, so
+// that the same word compares equal wherever it falls in a sentence
+func NormalizeWord(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}))
+}
+
+// equalWords reports whether two word sequences are equal
+func equalWords(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
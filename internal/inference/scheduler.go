@@ -0,0 +1,287 @@
+package inference
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// BatchFunc runs a batch of audio windows and returns one result per window
+type BatchFunc func(ctx context.Context, windows []*Window) ([]*Result, error)
+
+// Scheduler collects audio windows from concurrent callers and runs them
+// through a model's sessions in dynamically formed batches
+type Scheduler struct {
+	// modelID identifies the model in metrics
+	modelID string
+	// run executes the batches
+	run BatchFunc
+	// config controls batch formation
+	config BatchConfig
+	// queue receives windows submitted by callers
+	queue chan *batchRequest
+	// slots limits the number of batches running at once
+	slots chan struct{}
+	// stopCh signals the dispatch loop to exit
+	stopCh chan struct{}
+	// stopOnce guards stopCh
+	stopOnce sync.Once
+	// wg tracks the dispatch loop and running batches
+	wg sync.WaitGroup
+	// batches numbers dispatched batches; traces use it as the thread ID
+	batches int64
+}
+
+// batchRequest is a single audio window waiting for a batch
+type batchRequest struct {
+	ctx      context.Context
+	window   *Window
+	resultCh chan batchResponse
+	queued   time.Time
+}
+
+// batchResponse carries the result of a window back to its caller
+type batchResponse struct {
+	result *Result
+	err    error
+}
+
+// ErrSchedulerStopped is returned when a window is submitted to a stopped scheduler
+var ErrSchedulerStopped = errors.New("scheduler stopped")
+
+// NewScheduler creates a scheduler that runs up to concurrency batches at once
+// and starts its dispatch loop
+func NewScheduler(modelID string, run BatchFunc, config BatchConfig, concurrency int) *Scheduler {
+	if config.MaxBatchSize < 1 {
+		config.MaxBatchSize = 1
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	preferred := make([]int, 0, len(config.PreferredBatchSizes))
+	for _, size := range config.PreferredBatchSizes {
+		if size > 0 && size <= config.MaxBatchSize {
+			preferred = append(preferred, size)
+		}
+	}
+	sort.Ints(preferred)
+	config.PreferredBatchSizes = preferred
+
+	s := &Scheduler{
+		modelID: modelID,
+		run:     run,
+		config:  config,
+		queue:   make(chan *batchRequest, config.MaxBatchSize*4),
+		slots:   make(chan struct{}, concurrency),
+		stopCh:  make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.loop()
+
+	return s
+}
+
+// Submit queues an audio window with default decoding options and waits for its result
+func (s *Scheduler) Submit(ctx context.Context, audio []float32) (*Result, error) {
+	return s.SubmitWindow(ctx, &Window{Audio: audio})
+}
+
+// SubmitWindow queues an audio window and waits for its result
+func (s *Scheduler) SubmitWindow(ctx context.Context, window *Window) (*Result, error) {
+	req := &batchRequest{
+		ctx:      ctx,
+		window:   window,
+		resultCh: make(chan batchResponse, 1),
+		queued:   time.Now(),
+	}
+
+	select {
+	case <-s.stopCh:
+		return nil, ErrSchedulerStopped
+	default:
+	}
+
+	select {
+	case s.queue <- req:
+		schedulerQueueDepth.WithLabelValues(s.modelID).Inc()
+	case <-s.stopCh:
+		return nil, ErrSchedulerStopped
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case resp := <-req.resultCh:
+		return resp.result, resp.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.stopCh:
+		// Running batches finish before the scheduler stops
+		s.wg.Wait()
+		select {
+		case resp := <-req.resultCh:
+			return resp.result, resp.err
+		default:
+			return nil, ErrSchedulerStopped
+		}
+	}
+}
+
+// QueueDepth returns the number of windows waiting to be batched
+func (s *Scheduler) QueueDepth() int {
+	return len(s.queue)
+}
+
+// Stop stops the dispatch loop and fails any queued windows
+func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+	s.wg.Wait()
+}
+
+// loop is the dispatch loop; it only starts collecting a batch once a
+// session slot is free, so windows arriving while all sessions are busy
+// are grouped into the next batch
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.stopCh:
+			s.drain()
+			return
+		case s.slots <- struct{}{}:
+		}
+
+		select {
+		case <-s.stopCh:
+			<-s.slots
+			s.drain()
+			return
+		case first := <-s.queue:
+			s.dequeued(1)
+			batch := s.collect(first)
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				defer func() { <-s.slots }()
+				s.dispatch(batch)
+			}()
+		}
+	}
+}
+
+// collect gathers windows for a batch until it is full or the latency budget expires
+func (s *Scheduler) collect(first *batchRequest) []*batchRequest {
+	batch := []*batchRequest{first}
+	if !s.config.DynamicBatching || s.config.MaxBatchSize == 1 {
+		return batch
+	}
+
+	timer := time.NewTimer(time.Duration(s.config.MaxLatencyMs) * time.Millisecond)
+	defer timer.Stop()
+
+	for len(batch) < s.config.MaxBatchSize {
+		select {
+		case req := <-s.queue:
+			s.dequeued(1)
+			batch = append(batch, req)
+		case <-timer.C:
+			return batch
+		case <-s.stopCh:
+			return batch
+		}
+	}
+
+	return batch
+}
+
+// dispatch runs a batch and routes each result back to its caller
+func (s *Scheduler) dispatch(batch []*batchRequest) {
+	// Drop windows whose callers already gave up
+	live := batch[:0]
+	for _, req := range batch {
+		if err := req.ctx.Err(); err != nil {
+			req.resultCh <- batchResponse{err: err}
+			continue
+		}
+		live = append(live, req)
+	}
+	if len(live) == 0 {
+		return
+	}
+
+	windows := make([]*Window, len(live), s.paddedSize(len(live)))
+	for i, req := range live {
+		windows[i] = req.window
+	}
+	for len(windows) < cap(windows) {
+		windows = append(windows, &Window{Audio: make([]float32, len(live[0].window.Audio)), Padding: true})
+	}
+
+	schedulerBatchSize.WithLabelValues(s.modelID).Observe(float64(len(live)))
+	if padding := len(windows) - len(live); padding > 0 {
+		schedulerPaddedWindows.WithLabelValues(s.modelID).Add(float64(padding))
+	}
+
+	// Stages of the batch are recorded into the trace of every request in it
+	thread := atomic.AddInt64(&s.batches, 1)
+	var traces []*Trace
+	now := time.Now()
+	for _, req := range live {
+		if trace := TraceFromContext(req.ctx); trace != nil {
+			trace.add(Span{
+				Name:     StageQueue,
+				Start:    req.queued,
+				Duration: now.Sub(req.queued),
+				Thread:   thread,
+				Args:     map[string]interface{}{"batch_size": len(live), "padded_size": len(windows)},
+			})
+			traces = append(traces, trace)
+		}
+	}
+
+	results, err := s.run(withBatchTraces(context.Background(), traces, thread), windows)
+	for i, req := range live {
+		if err != nil {
+			req.resultCh <- batchResponse{err: err}
+			continue
+		}
+		req.resultCh <- batchResponse{result: results[i]}
+	}
+}
+
+// paddedSize returns the smallest preferred batch size that fits n windows
+func (s *Scheduler) paddedSize(n int) int {
+	for _, size := range s.config.PreferredBatchSizes {
+		if size >= n {
+			return size
+		}
+	}
+	return n
+}
+
+// drain fails every window still in the queue
+func (s *Scheduler) drain() {
+	for {
+		select {
+		case req := <-s.queue:
+			s.dequeued(1)
+			req.resultCh <- batchResponse{err: ErrSchedulerStopped}
+		default:
+			return
+		}
+	}
+}
+
+// dequeued updates the queue depth metric
+func (s *Scheduler) dequeued(n int) {
+	schedulerQueueDepth.WithLabelValues(s.modelID).Sub(float64(n))
+}
@@ -0,0 +1,146 @@
+package inference
+
+import (
+	"sort"
+	"sync"
+)
+
+// latencyWindow is the number of recent batch latencies kept for percentiles
+const latencyWindow = 1024
+
+// statsRecorder accumulates inference statistics and memory accounting
+type statsRecorder struct {
+	mu sync.Mutex
+	// stats holds the running counters
+	stats Stats
+	// latencies is a ring buffer of recent batch latencies in seconds
+	latencies []float64
+	// next is the ring buffer write position
+	next int
+}
+
+// recordBatch records a batch of windows that took latency seconds to process
+func (r *statsRecorder) recordBatch(windows int, audioSeconds, latency float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.TotalInferences += int64(windows)
+	r.stats.TotalBatches++
+	r.stats.TotalProcessingTime += latency
+	r.stats.AudioSecondsProcessed += audioSeconds
+
+	if len(r.latencies) < latencyWindow {
+		r.latencies = append(r.latencies, latency)
+	} else {
+		r.latencies[r.next] = latency
+		r.next = (r.next + 1) % latencyWindow
+	}
+}
+
+// allocate records bytes of memory taken by the session
+func (r *statsRecorder) allocate(bytes int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.CurrentMemoryUsage += bytes
+	if r.stats.CurrentMemoryUsage > r.stats.PeakMemoryUsage {
+		r.stats.PeakMemoryUsage = r.stats.CurrentMemoryUsage
+	}
+}
+
+// free records bytes of memory given back by the session
+func (r *statsRecorder) free(bytes int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.CurrentMemoryUsage -= bytes
+}
+
+// snapshot returns the current statistics and a copy of the latency samples
+func (r *statsRecorder) snapshot() (Stats, []float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	latencies := make([]float64, len(r.latencies))
+	copy(latencies, r.latencies)
+
+	stats := r.stats
+	finalizeStats(&stats, latencies)
+	return stats, latencies
+}
+
+// mergeStats combines the statistics of several sessions; memory figures are summed
+func mergeStats(all []Stats, latencies []float64) Stats {
+	var merged Stats
+	for _, stats := range all {
+		merged.TotalInferences += stats.TotalInferences
+		merged.TotalBatches += stats.TotalBatches
+		merged.TotalProcessingTime += stats.TotalProcessingTime
+		merged.AudioSecondsProcessed += stats.AudioSecondsProcessed
+		merged.CurrentMemoryUsage += stats.CurrentMemoryUsage
+		merged.PeakMemoryUsage += stats.PeakMemoryUsage
+		merged.DeviceMemoryUsage += stats.DeviceMemoryUsage
+	}
+	finalizeStats(&merged, latencies)
+	return merged
+}
+
+// finalizeStats fills the derived fields from the counters and latency samples
+func finalizeStats(stats *Stats, latencies []float64) {
+	if stats.TotalInferences > 0 {
+		stats.AverageProcessingTime = stats.TotalProcessingTime / float64(stats.TotalInferences)
+	}
+	if stats.TotalBatches > 0 {
+		stats.AverageBatchSize = float64(stats.TotalInferences) / float64(stats.TotalBatches)
+	}
+	if stats.AudioSecondsProcessed > 0 {
+		stats.RealTimeFactor = stats.TotalProcessingTime / stats.AudioSecondsProcessed
+	}
+
+	if len(latencies) == 0 {
+		return
+	}
+	sorted := make([]float64, len(latencies))
+	copy(sorted, latencies)
+	sort.Float64s(sorted)
+	stats.LatencyP50 = percentile(sorted, 0.50)
+	stats.LatencyP90 = percentile(sorted, 0.90)
+	stats.LatencyP99 = percentile(sorted, 0.99)
+}
+
+// percentile returns the nearest-rank percentile of sorted samples
+func percentile(sorted []float64, p float64) float64 {
+	index := int(p*float64(len(sorted)) + 0.5)
+	if index < 1 {
+		index = 1
+	}
+	if index > len(sorted) {
+		index = len(sorted)
+	}
+	return sorted[index-1]
+}
+
+// allocate records memory taken by the session and counts it against its pool
+func (s *Session) allocate(bytes int64) {
+	s.stats.allocate(bytes)
+	if s.pool != nil {
+		s.pool.memory.allocate(bytes)
+	}
+}
+
+// free records memory given back by the session
+func (s *Session) free(bytes int64) {
+	s.stats.free(bytes)
+	if s.pool != nil {
+		s.pool.memory.free(bytes)
+	}
+}
+
+// Stats returns the session's inference statistics
+func (s *Session) Stats() Stats {
+	stats, _ := s.stats.snapshot()
+	if provider, _ := s.ProviderInfo(); provider != "" && provider != CPUExecutionProvider {
+		stats.DeviceMemoryUsage = stats.CurrentMemoryUsage
+	}
+	return stats
+}
@@ -0,0 +1,229 @@
+package inference
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Control token names of Whisper-style vocabularies
+const (
+	tokenStartOfTranscript = "<|startoftranscript|>"
+	tokenEndOfText         = "<|endoftext|>"
+	tokenStartOfPrevious   = "<|startofprev|>"
+	tokenNoSpeech          = "<|nospeech|>"
+	// tokenNoCaptions is the no-speech token's name in older vocabularies
+	tokenNoCaptions = "<|nocaptions|>"
+)
+
+// SpecialTokens holds the IDs of a model's control tokens; a token the
+// vocabulary lacks has ID -1
+type SpecialTokens struct {
+	// StartOfTranscript begins the transcript part of every decoder sequence
+	StartOfTranscript int
+	// EndOfText ends a sequence
+	EndOfText int
+	// StartOfPrevious introduces previous-context tokens ahead of the transcript
+	StartOfPrevious int
+	// NoSpeech is predicted first when the model hears no speech
+	NoSpeech int
+}
+
+// Tokenizer converts between text and token IDs
+type Tokenizer interface {
+	// Encode converts text to token IDs
+	Encode(text string) []int
+	// Decode converts token IDs to text, skipping control tokens
+	Decode(tokens []int) string
+	// SpecialTokens returns the control token IDs
+	SpecialTokens() SpecialTokens
+}
+
+// Vocabulary is a byte-level BPE Tokenizer over a fixed token list. Text is
+// encoded by greedy longest match rather than by replaying merges, which
+// yields valid, if sometimes longer, token sequences.
+type Vocabulary struct {
+	tokens  []string
+	ids     map[string]int
+	special SpecialTokens
+	maxLen  int
+}
+
+// NewVocabulary creates a tokenizer over tokens, where a token's ID is its
+// index; control tokens are found by their Whisper names
+func NewVocabulary(tokens []string) *Vocabulary {
+	v := &Vocabulary{
+		tokens: tokens,
+		ids:    make(map[string]int, len(tokens)),
+	}
+	for id, token := range tokens {
+		if _, exists := v.ids[token]; !exists {
+			v.ids[token] = id
+		}
+		if len(token) > v.maxLen {
+			v.maxLen = len(token)
+		}
+	}
+
+	lookup := func(name string) int {
+		if id, ok := v.ids[name]; ok {
+			return id
+		}
+		return -1
+	}
+	v.special = SpecialTokens{
+		StartOfTranscript: lookup(tokenStartOfTranscript),
+		EndOfText:         lookup(tokenEndOfText),
+		StartOfPrevious:   lookup(tokenStartOfPrevious),
+		NoSpeech:          lookup(tokenNoSpeech),
+	}
+	if v.special.NoSpeech < 0 {
+		v.special.NoSpeech = lookup(tokenNoCaptions)
+	}
+	return v
+}
+
+// LoadVocabulary loads a Hugging Face style vocab.json, a JSON object of
+// token to ID, merging added_tokens.json from the same directory if present
+func LoadVocabulary(path string) (*Vocabulary, error) {
+	entries := make(map[string]int)
+	if err := readTokenFile(path, entries); err != nil {
+		return nil, err
+	}
+
+	added := filepath.Join(filepath.Dir(path), "added_tokens.json")
+	if _, err := os.Stat(added); err == nil {
+		if err := readTokenFile(added, entries); err != nil {
+			return nil, err
+		}
+	}
+
+	maxID := -1
+	for _, id := range entries {
+		if id > maxID {
+			maxID = id
+		}
+	}
+	tokens := make([]string, maxID+1)
+	for token, id := range entries {
+		if id >= 0 {
+			tokens[id] = token
+		}
+	}
+	return NewVocabulary(tokens), nil
+}
+
+// readTokenFile merges a token to ID JSON file into entries
+func readTokenFile(path string, entries map[string]int) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to read vocabulary")
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return errors.Wrapf(err, "failed to parse vocabulary %s", path)
+	}
+	return nil
+}
+
+// Encode converts text to token IDs by greedy longest match over its
+// byte-level encoding; bytes missing from the vocabulary are skipped
+func (v *Vocabulary) Encode(text string) []int {
+	encoded := bytesToTokenText([]byte(text))
+
+	var ids []int
+	for len(encoded) > 0 {
+		matched := 0
+		for n := v.maxLen; n > 0; n-- {
+			if n > len(encoded) {
+				continue
+			}
+			if id, ok := v.ids[encoded[:n]]; ok && !isControlToken(encoded[:n]) {
+				ids = append(ids, id)
+				matched = n
+				break
+			}
+		}
+		if matched == 0 {
+			_, matched = utf8.DecodeRuneInString(encoded)
+		}
+		encoded = encoded[matched:]
+	}
+	return ids
+}
+
+// Decode converts token IDs to text, skipping control tokens
+func (v *Vocabulary) Decode(tokens []int) string {
+	var b strings.Builder
+	for _, id := range tokens {
+		if id < 0 || id >= len(v.tokens) || isControlToken(v.tokens[id]) {
+			continue
+		}
+		b.WriteString(v.tokens[id])
+	}
+	return strings.TrimSpace(tokenTextToString(b.String()))
+}
+
+// SpecialTokens returns the control token IDs
+func (v *Vocabulary) SpecialTokens() SpecialTokens {
+	return v.special
+}
+
+// isControlToken reports whether a token is a control token such as <|endoftext|>
+func isControlToken(token string) bool {
+	return strings.HasPrefix(token, "<|") && strings.HasSuffix(token, "|>")
+}
+
+// byteRunes and runeBytes implement the GPT-2 byte-level mapping, which
+// gives every byte a printable rune so that, for example, a space becomes "Ġ"
+var byteRunes, runeBytes = newByteLevelTables()
+
+// newByteLevelTables builds the byte to rune mapping and its inverse
+func newByteLevelTables() ([256]rune, map[rune]byte) {
+	var toRune [256]rune
+	toByte := make(map[rune]byte, 256)
+
+	printable := func(b int) bool {
+		return (b >= '!' && b <= '~') || (b >= 0xA1 && b <= 0xAC) || (b >= 0xAE && b <= 0xFF)
+	}
+	next := 256
+	for b := 0; b < 256; b++ {
+		r := rune(b)
+		if !printable(b) {
+			r = rune(next)
+			next++
+		}
+		toRune[b] = r
+		toByte[r] = byte(b)
+	}
+	return toRune, toByte
+}
+
+// ByteLevel returns the byte-level token text of s, the form tokens take in
+// a Vocabulary; for example " João" becomes "ĠJoÃ£o"
+func ByteLevel(s string) string {
+	return bytesToTokenText([]byte(s))
+}
+
+// bytesToTokenText maps raw bytes to their byte-level token text
+func bytesToTokenText(data []byte) string {
+	var b strings.Builder
+	for _, c := range data {
+		b.WriteRune(byteRunes[c])
+	}
+	return b.String()
+}
+
+// tokenTextToString maps byte-level token text back to the original string
+func tokenTextToString(text string) string {
+	data := make([]byte, 0, len(text))
+	for _, r := range text {
+		if c, ok := runeBytes[r]; ok {
+			data = append(data, c)
+		}
+	}
+	return string(data)
+}
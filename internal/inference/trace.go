@@ -0,0 +1,249 @@
+package inference
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Trace stage names recorded by the transcription pipeline
+const (
+	StageDecode            = "decode"
+	StageResample          = "resample"
+	StageQueue             = "queue"
+	StageFeatureExtraction = "feature_extraction"
+	StageEncoder           = "encoder"
+	StageDecoderStep       = "decoder_step"
+	StagePostProcessing    = "post_processing"
+	StageInference         = "inference"
+)
+
+// Trace records the timed stages of a single request
+type Trace struct {
+	// ID identifies the request
+	ID string
+	// ModelID is the model serving the request
+	ModelID string
+	// Start is when the request started
+	Start time.Time
+
+	// mu protects spans
+	mu sync.Mutex
+	// spans are the recorded stages in completion order
+	spans []Span
+}
+
+// Span is a completed pipeline stage
+type Span struct {
+	// Name is the stage name
+	Name string
+	// Start is when the stage started
+	Start time.Time
+	// Duration is how long the stage took
+	Duration time.Duration
+	// Thread separates stages that run concurrently, such as batches of
+	// different windows of the same request
+	Thread int64
+	// Args holds stage details such as batch size or step number
+	Args map[string]interface{}
+}
+
+// Spans returns a copy of the recorded stages
+func (t *Trace) Spans() []Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	spans := make([]Span, len(t.spans))
+	copy(spans, t.spans)
+	return spans
+}
+
+// add records a completed stage
+func (t *Trace) add(span Span) {
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+}
+
+// traceContext is what a context carries for tracing; a batch carries the
+// traces of every request in it
+type traceContext struct {
+	traces []*Trace
+	thread int64
+}
+
+// traceKey is the context key for traceContext
+type traceKey struct{}
+
+// WithTrace returns a context that records stages into trace
+func WithTrace(ctx context.Context, trace *Trace) context.Context {
+	if trace == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, traceKey{}, &traceContext{traces: []*Trace{trace}})
+}
+
+// TraceFromContext returns the trace a context records into, if any
+func TraceFromContext(ctx context.Context) *Trace {
+	if tc, ok := ctx.Value(traceKey{}).(*traceContext); ok && len(tc.traces) == 1 {
+		return tc.traces[0]
+	}
+	return nil
+}
+
+// withBatchTraces returns a context that records stages into the traces of
+// every request in a batch on the given thread
+func withBatchTraces(ctx context.Context, traces []*Trace, thread int64) context.Context {
+	if len(traces) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, traceKey{}, &traceContext{traces: traces, thread: thread})
+}
+
+// StartSpan starts timing a stage and returns the function that ends it;
+// it costs nothing when the context carries no trace
+func StartSpan(ctx context.Context, name string, args map[string]interface{}) func() {
+	tc, ok := ctx.Value(traceKey{}).(*traceContext)
+	if !ok {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() {
+		span := Span{
+			Name:     name,
+			Start:    start,
+			Duration: time.Since(start),
+			Thread:   tc.thread,
+			Args:     args,
+		}
+		for _, trace := range tc.traces {
+			trace.add(span)
+		}
+	}
+}
+
+// Tracer keeps the traces of the most recent profiled requests
+type Tracer struct {
+	mu sync.Mutex
+	// traces is a ring buffer of recent traces
+	traces []*Trace
+	// next is the ring buffer write position
+	next int
+	// capacity is the number of traces kept
+	capacity int
+}
+
+// NewTracer creates a tracer that keeps the last capacity traces
+func NewTracer(capacity int) *Tracer {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Tracer{capacity: capacity}
+}
+
+// StartTrace creates a trace for a request and keeps it
+func (t *Tracer) StartTrace(id, modelID string) *Trace {
+	trace := &Trace{ID: id, ModelID: modelID, Start: time.Now()}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.traces) < t.capacity {
+		t.traces = append(t.traces, trace)
+	} else {
+		t.traces[t.next] = trace
+		t.next = (t.next + 1) % t.capacity
+	}
+	return trace
+}
+
+// Get returns the kept trace with the given ID
+func (t *Tracer) Get(id string) *Trace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, trace := range t.traces {
+		if trace.ID == id {
+			return trace
+		}
+	}
+	return nil
+}
+
+// Recent returns the kept traces, oldest first
+func (t *Tracer) Recent() []*Trace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	traces := make([]*Trace, 0, len(t.traces))
+	traces = append(traces, t.traces[t.next:]...)
+	traces = append(traces, t.traces[:t.next]...)
+	return traces
+}
+
+// chromeEvent is an event in the Chrome trace-event format
+type chromeEvent struct {
+	Name      string                 `json:"name"`
+	Category  string                 `json:"cat,omitempty"`
+	Phase     string                 `json:"ph"`
+	Timestamp int64                  `json:"ts"`
+	Duration  int64                  `json:"dur,omitempty"`
+	PID       int                    `json:"pid"`
+	TID       int64                  `json:"tid"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+}
+
+// WriteChromeTrace writes traces as Chrome trace-event JSON, loadable in
+// chrome://tracing or Perfetto; each request is shown as its own process
+func WriteChromeTrace(w io.Writer, traces []*Trace) error {
+	events := make([]chromeEvent, 0)
+	for i, trace := range traces {
+		pid := i + 1
+		events = append(events, chromeEvent{
+			Name:  "process_name",
+			Phase: "M",
+			PID:   pid,
+			Args:  map[string]interface{}{"name": trace.ID + " (" + trace.ModelID + ")"},
+		})
+		for _, span := range trace.Spans() {
+			events = append(events, chromeEvent{
+				Name:      span.Name,
+				Category:  trace.ModelID,
+				Phase:     "X",
+				Timestamp: span.Start.UnixMicro(),
+				Duration:  span.Duration.Microseconds(),
+				PID:       pid,
+				TID:       span.Thread,
+				Args:      span.Args,
+			})
+		}
+	}
+
+	return json.NewEncoder(w).Encode(map[string]interface{}{
+		"traceEvents":     events,
+		"displayTimeUnit": "ms",
+	})
+}
+
+// ServeHTTP serves the kept traces as a Chrome trace download; the id
+// query parameter selects a single request
+func (t *Tracer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	traces := t.Recent()
+	if id := r.URL.Query().Get("id"); id != "" {
+		trace := t.Get(id)
+		if trace == nil {
+			http.Error(w, "trace not found", http.StatusNotFound)
+			return
+		}
+		traces = []*Trace{trace}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="trace.json"`)
+	if err := WriteChromeTrace(w, traces); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -1,6 +1,9 @@
 package inference
 
 import (
+	"sync"
+	"time"
+
 	"github.com/josealecrim/audiototext/internal/models"
 )
 
@@ -18,8 +21,12 @@ const (
 
 // SessionConfig holds the configuration for an ONNX Runtime session
 type SessionConfig struct {
-	// ExecutionProvider specifies which hardware to use for inference
+	// ExecutionProvider specifies which hardware to use for inference when
+	// ExecutionProviders is empty
 	ExecutionProvider ExecutionProvider
+	// ExecutionProviders is the ordered provider preference list; a session
+	// falls back to the next provider when one fails to initialize or run
+	ExecutionProviders []ExecutionProvider
 	// InterOpNumThreads specifies the number of threads used to parallelize the execution
 	// of the graph (across nodes)
 	InterOpNumThreads int
@@ -48,6 +55,26 @@ type BatchConfig struct {
 	PreferredBatchSizes []int
 }
 
+// PoolConfig holds the configuration for the per-model session pools
+type PoolConfig struct {
+	// MinSessions is the number of warm sessions kept open per model
+	MinSessions int
+	// MaxSessions is the maximum number of sessions open per model
+	MaxSessions int
+	// IdleTimeout is how long a session above MinSessions may stay idle before it is closed
+	IdleTimeout time.Duration
+}
+
+// PoolStats describes the state of a model's session pool
+type PoolStats struct {
+	// OpenSessions is the number of sessions currently open
+	OpenSessions int
+	// InUseSessions is the number of sessions currently acquired
+	InUseSessions int
+	// MaxSessions is the maximum number of sessions the pool may open
+	MaxSessions int
+}
+
 // Session represents an ONNX Runtime inference session
 type Session struct {
 	// Model is the ONNX model being used
@@ -56,12 +83,52 @@ type Session struct {
 	Config SessionConfig
 	// BatchConfig is the batch processing configuration
 	BatchConfig BatchConfig
-	// InputShape is the shape of the input tensor
+	// InputShape is the shape of the input tensor, read from the model graph
+	// with -1 for dynamic dimensions; nil when the graph is unknown
 	InputShape []int64
-	// OutputShape is the shape of the output tensor
+	// OutputShape is the shape of the output tensor, read like InputShape
 	OutputShape []int64
-	// session is the underlying ONNX Runtime session
-	session interface{}
+	// ActiveProvider is the execution provider currently running the session
+	ActiveProvider ExecutionProvider
+	// FallbackReasons records every provider the session moved off and why
+	FallbackReasons []ProviderFallback
+	// mu protects the provider fields
+	mu sync.RWMutex
+	// runtime is the underlying ONNX Runtime session
+	runtime ONNXRuntime
+	// providerIndex is the position of ActiveProvider in the provider chain
+	providerIndex int
+	// newRuntime creates runtimes when the session falls back to another provider
+	newRuntime RuntimeFactory
+	// stats accumulates the session's statistics and memory accounting
+	stats statsRecorder
+	// weightBytes is the memory accounted for the loaded model weights
+	weightBytes int64
+	// footprint is the memory reserved from the manager's budget
+	footprint int64
+	// counted is set while the session counts toward its model's open sessions
+	counted bool
+	// safeguards are the checks run on decoded text
+	safeguards SafeguardConfig
+	// calibration maps raw confidences to calibrated ones, nil if the model has none
+	calibration Calibrator
+	// ctc holds the decoding settings of CTC models, nil for other models
+	ctc *ctcModel
+	// pool is the pool the session belongs to, nil for standalone sessions
+	pool *sessionPool
+	// lastUsed is when the session was last released to its pool
+	lastUsed time.Time
+}
+
+// Window is an audio window together with the options for decoding it
+type Window struct {
+	// Audio holds the window's samples at 16 kHz
+	Audio []float32
+	// Options controls decoding of the window
+	Options DecodeOptions
+	// Padding marks a window that only fills a batch out to a preferred
+	// size; it isn't run and its result is nil
+	Padding bool
 }
 
 // Result represents the result of an inference
@@ -76,22 +143,62 @@ type Result struct {
 	TimestampEnd float32
 	// ProcessingTime is the time taken to process the inference
 	ProcessingTime float32
+	// Warnings records every suppression the safeguards made to the text
+	Warnings []string
+	// Words are the words of Transcription with their own confidences
+	Words []WordResult
+	// Alternatives are the best hypotheses, the best one first, when requested
+	Alternatives []Alternative
+}
+
+// Alternative is one of the best hypotheses for a window
+type Alternative struct {
+	// Text is the hypothesis's text
+	Text string
+	// Confidence is the calibrated confidence of the hypothesis (0-1)
+	Confidence float32
+	// Score is the length-normalized log-probability the decoder ranked it by
+	Score float32
+}
+
+// WordResult is a transcribed word
+type WordResult struct {
+	// Word is the word's text
+	Word string
+	// Start is the estimated start time of the word
+	Start float32
+	// End is the estimated end time of the word
+	End float32
+	// Confidence is the calibrated confidence of the word (0-1)
+	Confidence float32
 }
 
 // Stats represents statistics about the inference session
 type Stats struct {
-	// TotalInferences is the total number of inferences performed
+	// TotalInferences is the total number of audio windows processed
 	TotalInferences int64
-	// TotalProcessingTime is the total time spent processing inferences
+	// TotalProcessingTime is the total time in seconds spent processing batches
 	TotalProcessingTime float64
-	// AverageProcessingTime is the average time per inference
+	// AverageProcessingTime is the average time per audio window
 	AverageProcessingTime float64
 	// TotalBatches is the total number of batches processed
 	TotalBatches int64
-	// AverageBatchSize is the average batch size
+	// AverageBatchSize is the average number of windows per batch
 	AverageBatchSize float64
+	// AudioSecondsProcessed is the total duration of audio processed
+	AudioSecondsProcessed float64
+	// RealTimeFactor is processing time divided by audio duration; below 1 is faster than real time
+	RealTimeFactor float64
+	// LatencyP50 is the median batch latency in seconds over recent batches
+	LatencyP50 float64
+	// LatencyP90 is the 90th percentile batch latency in seconds over recent batches
+	LatencyP90 float64
+	// LatencyP99 is the 99th percentile batch latency in seconds over recent batches
+	LatencyP99 float64
 	// PeakMemoryUsage is the peak memory usage in bytes
 	PeakMemoryUsage int64
 	// CurrentMemoryUsage is the current memory usage in bytes
 	CurrentMemoryUsage int64
+	// DeviceMemoryUsage is the part of CurrentMemoryUsage held on a GPU execution provider
+	DeviceMemoryUsage int64
 }
@@ -0,0 +1,119 @@
+package inference
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/pkg/errors"
+)
+
+// warmupWindowSamples is the length of the synthetic windows run during
+// warmup; full 30 second windows compile the same shapes real traffic uses
+const warmupWindowSamples = 30 * featureSampleRate
+
+// WarmupResult records how warming up a model went
+type WarmupResult struct {
+	// LoadTime is how long opening the first session took
+	LoadTime time.Duration
+	// BatchLatencies maps each warmed batch size to the latency of its first run
+	BatchLatencies map[int]time.Duration
+	// Total is the time the whole warmup took
+	Total time.Duration
+	// Err is set when warmup failed
+	Err error
+}
+
+// warmupKey marks contexts of warmup runs, which are left out of the statistics
+type warmupKey struct{}
+
+// isWarmup reports whether ctx belongs to a warmup run
+func isWarmup(ctx context.Context) bool {
+	warmup, _ := ctx.Value(warmupKey{}).(bool)
+	return warmup
+}
+
+// Warmup loads each model's session pool and runs silent audio through every
+// preferred batch size, so the first real requests don't pay for session
+// initialization and graph compilation. It returns the first error after
+// trying every model; per-model results are kept for GetWarmupResults.
+func (m *Manager) Warmup(ctx context.Context, modelList []*models.ONNXModel) error {
+	var firstErr error
+	for _, model := range modelList {
+		result := m.warmupModel(ctx, model)
+
+		m.mu.Lock()
+		m.warmupResults[model.ID] = result
+		m.mu.Unlock()
+
+		if result.Err != nil && firstErr == nil {
+			firstErr = errors.Wrapf(result.Err, "failed to warm up model %s", model.ID)
+		}
+	}
+	return firstErr
+}
+
+// GetWarmupResults returns the warmup result of every warmed model
+func (m *Manager) GetWarmupResults() map[string]WarmupResult {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := make(map[string]WarmupResult, len(m.warmupResults))
+	for modelID, result := range m.warmupResults {
+		results[modelID] = result
+	}
+	return results
+}
+
+// warmupModel warms a single model
+func (m *Manager) warmupModel(ctx context.Context, model *models.ONNXModel) WarmupResult {
+	start := time.Now()
+	result := WarmupResult{BatchLatencies: make(map[int]time.Duration)}
+
+	// Creating the scheduler opens the first session
+	if _, err := m.GetScheduler(ctx, model); err != nil {
+		result.Err = err
+		return result
+	}
+	result.LoadTime = time.Since(start)
+	warmupDuration.WithLabelValues(model.ID, "load").Set(result.LoadTime.Seconds())
+
+	pool := m.getPool(model)
+	sizes := pool.batchConfig.PreferredBatchSizes
+	if len(sizes) == 0 {
+		sizes = []int{1}
+	}
+
+	warmupCtx := context.WithValue(ctx, warmupKey{}, true)
+	for _, size := range sizes {
+		if size < 1 || size > pool.batchConfig.MaxBatchSize {
+			continue
+		}
+
+		batch := make([][]float32, size)
+		for i := range batch {
+			batch[i] = make([]float32, warmupWindowSamples)
+		}
+
+		session, err := pool.acquire(ctx)
+		if err != nil {
+			result.Err = err
+			break
+		}
+		batchStart := time.Now()
+		_, err = NewInference(session).ProcessBatch(warmupCtx, batch)
+		latency := time.Since(batchStart)
+		pool.release(session)
+		if err != nil {
+			result.Err = errors.Wrapf(err, "batch size %d", size)
+			break
+		}
+
+		result.BatchLatencies[size] = latency
+		warmupDuration.WithLabelValues(model.ID, strconv.Itoa(size)).Set(latency.Seconds())
+	}
+
+	result.Total = time.Since(start)
+	return result
+}
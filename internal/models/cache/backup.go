@@ -0,0 +1,429 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/models"
+)
+
+// BackupDir é o subdiretório do cache que guarda os backups, um
+// subdiretório por modelo e dentro dele um por backup
+const BackupDir = ".backups"
+
+// backupMeta é o arquivo com os metadados de um backup
+const backupMeta = "backup.json"
+
+// stagingDir guarda, dentro de BackupDir, os backups e restaurações ainda
+// incompletos; fica no mesmo sistema de arquivos do cache para que a troca
+// final seja uma renomeação
+const stagingDir = ".staging"
+
+// backupIDFormat gera os IDs dos backups, que ordenam pela data de criação
+const backupIDFormat = "20060102T150405.000000000Z"
+
+// Backup descreve um backup de um modelo
+type Backup struct {
+	ID      string    `json:"id"`
+	ModelID string    `json:"model_id"`
+	Created time.Time `json:"created"`
+	// CreatedBy é quem pediu o backup; vazio usa o usuário do sistema
+	CreatedBy string `json:"created_by"`
+	// Reason explica por que o backup foi feito, como "antes de atualizar para v2"
+	Reason string `json:"reason"`
+	// Checksum é o SHA-256 em hexadecimal do arquivo de pesos
+	Checksum string `json:"checksum"`
+	// Size é o tamanho de todos os arquivos do backup
+	Size int64 `json:"size"`
+	// Files são os arquivos do backup, relativos ao diretório do modelo
+	Files []string `json:"files"`
+	// Info é o registro do modelo no índice quando o backup foi feito
+	Info models.ModelInfo `json:"info"`
+
+	// dir é o diretório do backup
+	dir string
+}
+
+// CreateBackup copia os arquivos de um modelo, inclusive o manifesto, para
+// BackupDir junto com os metadados. Os arquivos são ligados por hard link
+// quando possível: downloads e restaurações trocam os arquivos por
+// renomeação, nunca os reescrevem, então o backup não muda junto com o
+// modelo. Para que ninguém os reescreva por engano, os arquivos copiados
+// ficam somente leitura, inclusive os do modelo que compartilham o mesmo
+// inode. Um modelo cujo checksum não confere não é copiado
+func (m *Manager) CreateBackup(modelID, createdBy, reason string) (Backup, error) {
+	m.backupMu.Lock()
+	defer m.backupMu.Unlock()
+
+	return m.createBackup(modelID, createdBy, reason)
+}
+
+// createBackup implementa CreateBackup; chamado com m.backupMu
+func (m *Manager) createBackup(modelID, createdBy, reason string) (Backup, error) {
+	m.mu.RLock()
+	info, exists := m.index[modelID]
+	m.mu.RUnlock()
+
+	if !exists {
+		return Backup{}, fmt.Errorf("modelo não encontrado no cache: %s", modelID)
+	}
+	if createdBy == "" {
+		createdBy = currentUser()
+	}
+
+	created := time.Now().UTC()
+	backup := Backup{
+		ID:        created.Format(backupIDFormat),
+		ModelID:   modelID,
+		Created:   created,
+		CreatedBy: createdBy,
+		Reason:    reason,
+		Info:      info,
+	}
+	backup.dir = filepath.Join(m.backupsDir(modelID), backup.ID)
+
+	staging, err := m.stage("backup-" + flatName(modelID))
+	if err != nil {
+		return Backup{}, err
+	}
+	defer os.RemoveAll(staging)
+
+	backup.Files, backup.Size, err = linkTree(m.Dir(modelID), staging)
+	if err != nil {
+		return Backup{}, fmt.Errorf("erro ao copiar modelo %s: %w", modelID, err)
+	}
+
+	// O checksum é o da cópia, que é o que uma restauração vai trazer de volta
+	_, backup.Checksum, err = models.FileChecksum(filepath.Join(staging, info.Format.FileName()))
+	if err != nil {
+		return Backup{}, fmt.Errorf("erro ao verificar cópia do modelo %s: %w", modelID, err)
+	}
+	if info.Checksum != "" && backup.Checksum != info.Checksum {
+		return Backup{}, fmt.Errorf("modelo %s: %w: esperado %s, obtido %s", modelID, models.ErrChecksumMismatch, info.Checksum, backup.Checksum)
+	}
+
+	if err := writeBackupMeta(staging, backup); err != nil {
+		return Backup{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(backup.dir), 0755); err != nil {
+		return Backup{}, fmt.Errorf("erro ao criar diretório de backups: %w", err)
+	}
+	if err := os.Rename(staging, backup.dir); err != nil {
+		return Backup{}, fmt.Errorf("erro ao salvar backup do modelo %s: %w", modelID, err)
+	}
+	return backup, nil
+}
+
+// ListBackups lista os backups de um modelo, do mais recente para o mais
+// antigo. Os backups continuam disponíveis depois que o modelo é removido
+func (m *Manager) ListBackups(modelID string) ([]Backup, error) {
+	return listBackups(m.backupsDir(modelID))
+}
+
+// RestoreBackup devolve um modelo à versão de um backup. Os arquivos são
+// preparados ao lado do cache, conferidos por validate, quando não é nil,
+// e só então trocados pelos do modelo numa única troca atômica, então quem
+// abre o modelo vê a versão antiga ou a restaurada inteira e um backup
+// rejeitado deixa o modelo como estava; sessões de inferência já abertas
+// mantêm os arquivos que carregaram. validate recebe o modelo preparado,
+// cujo Path aponta para fora do cache, e é chamado sem locks. Se o modelo
+// existe e está íntegro, a versão substituída ganha um backup antes da
+// troca, para que a restauração possa ser desfeita
+func (m *Manager) RestoreBackup(modelID, backupID, restoredBy string, validate func(*models.Model) error) (Backup, error) {
+	m.backupMu.Lock()
+	defer m.backupMu.Unlock()
+
+	backup, err := m.findBackup(modelID, backupID)
+	if err != nil {
+		return Backup{}, err
+	}
+
+	// Um backup corrompido nunca substitui o modelo
+	_, checksum, err := models.FileChecksum(filepath.Join(backup.dir, backup.Info.Format.FileName()))
+	if err != nil {
+		return Backup{}, fmt.Errorf("erro ao verificar backup %s do modelo %s: %w", backupID, modelID, err)
+	}
+	if checksum != backup.Checksum {
+		return Backup{}, fmt.Errorf("backup %s do modelo %s: %w: esperado %s, obtido %s", backupID, modelID, models.ErrChecksumMismatch, backup.Checksum, checksum)
+	}
+
+	staging, err := m.stage("restore-" + flatName(modelID))
+	if err != nil {
+		return Backup{}, err
+	}
+	// Depois da troca, staging guarda a versão substituída
+	defer os.RemoveAll(staging)
+
+	if _, _, err := linkTree(backup.dir, staging); err != nil {
+		return Backup{}, fmt.Errorf("erro ao copiar backup %s do modelo %s: %w", backupID, modelID, err)
+	}
+	// Os metadados são do backup, não do modelo
+	if err := os.Remove(filepath.Join(staging, backupMeta)); err != nil {
+		return Backup{}, fmt.Errorf("erro ao preparar restauração: %w", err)
+	}
+
+	info := backup.Info
+	info.Verified = time.Now()
+	info.LastUsed = info.Verified
+	if validate != nil {
+		staged := &models.Model{Info: info, Path: filepath.Join(staging, info.Format.FileName())}
+		if err := validate(staged); err != nil {
+			return Backup{}, fmt.Errorf("backup %s do modelo %s rejeitado: %w", backupID, modelID, err)
+		}
+	}
+
+	m.mu.RLock()
+	_, exists := m.index[modelID]
+	m.mu.RUnlock()
+	if exists {
+		reason := fmt.Sprintf("antes de restaurar o backup %s", backupID)
+		if _, err := m.createBackup(modelID, restoredBy, reason); err != nil && !errors.Is(err, models.ErrChecksumMismatch) {
+			return Backup{}, fmt.Errorf("erro ao guardar a versão atual do modelo %s: %w", modelID, err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir := m.Dir(modelID)
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return Backup{}, fmt.Errorf("erro ao criar diretório do modelo: %w", err)
+	}
+	switch _, err = os.Stat(dir); {
+	case err == nil:
+		err = exchange(staging, dir)
+	case os.IsNotExist(err):
+		err = os.Rename(staging, dir)
+	}
+	if err != nil {
+		return Backup{}, fmt.Errorf("erro ao restaurar modelo %s: %w", modelID, err)
+	}
+
+	m.index[modelID] = info
+	return backup, m.saveIndex()
+}
+
+// PruneBackups apaga os backups de todos os modelos fora da política e
+// retorna os apagados; o backup mais recente de cada modelo é mantido
+func (m *Manager) PruneBackups(policy models.BackupPolicy) ([]Backup, error) {
+	m.backupMu.Lock()
+	defer m.backupMu.Unlock()
+
+	entries, err := os.ReadDir(filepath.Join(m.config.CachePath, BackupDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler diretório de backups: %w", err)
+	}
+
+	var pruned []Backup
+	now := time.Now()
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		backups, err := listBackups(filepath.Join(m.config.CachePath, BackupDir, entry.Name()))
+		if err != nil {
+			return pruned, err
+		}
+
+		for i, backup := range backups {
+			expired := policy.MaxAge > 0 && now.Sub(backup.Created) > policy.MaxAge
+			excess := policy.MaxCount > 0 && i >= policy.MaxCount
+			if i == 0 || !(expired || excess) {
+				continue
+			}
+			if err := os.RemoveAll(backup.dir); err != nil {
+				return pruned, fmt.Errorf("erro ao apagar backup %s do modelo %s: %w", backup.ID, backup.ModelID, err)
+			}
+			pruned = append(pruned, backup)
+		}
+	}
+	return pruned, nil
+}
+
+// BackupPolicy retorna a política de retenção dos backups derivada da configuração
+func (m *Manager) BackupPolicy() models.BackupPolicy {
+	return m.backupPolicy
+}
+
+// findBackup retorna um backup de um modelo
+func (m *Manager) findBackup(modelID, backupID string) (Backup, error) {
+	if backupID == "" || strings.ContainsAny(backupID, `/\`) || strings.HasPrefix(backupID, ".") {
+		return Backup{}, fmt.Errorf("ID de backup inválido: %q", backupID)
+	}
+
+	backup, err := readBackupMeta(filepath.Join(m.backupsDir(modelID), backupID))
+	if os.IsNotExist(err) {
+		return Backup{}, fmt.Errorf("backup %s do modelo %s não encontrado", backupID, modelID)
+	}
+	if err != nil {
+		return Backup{}, err
+	}
+	if backup.ModelID != modelID {
+		return Backup{}, fmt.Errorf("backup %s é do modelo %s, não de %s", backupID, backup.ModelID, modelID)
+	}
+	return backup, nil
+}
+
+// backupsDir retorna o diretório dos backups de um modelo
+func (m *Manager) backupsDir(modelID string) string {
+	return filepath.Join(m.config.CachePath, BackupDir, flatName(modelID))
+}
+
+// stage cria um diretório vazio para preparar um backup ou uma restauração
+func (m *Manager) stage(name string) (string, error) {
+	dir := filepath.Join(m.config.CachePath, BackupDir, stagingDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("erro ao criar diretório de backups: %w", err)
+	}
+	staging, err := os.MkdirTemp(dir, name+"-")
+	if err != nil {
+		return "", fmt.Errorf("erro ao criar diretório de backups: %w", err)
+	}
+	return staging, nil
+}
+
+// listBackups lê os backups de um diretório, do mais recente para o mais
+// antigo; diretórios sem metadados são backups incompletos e são ignorados
+func listBackups(dir string) ([]Backup, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler backups: %w", err)
+	}
+
+	var backups []Backup
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		backup, err := readBackupMeta(filepath.Join(dir, entry.Name()))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, backup)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Created.After(backups[j].Created)
+	})
+	return backups, nil
+}
+
+// readBackupMeta lê os metadados do backup em dir
+func readBackupMeta(dir string) (Backup, error) {
+	data, err := os.ReadFile(filepath.Join(dir, backupMeta))
+	if err != nil {
+		return Backup{}, err
+	}
+
+	var backup Backup
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return Backup{}, fmt.Errorf("metadados inválidos no backup %s: %w", dir, err)
+	}
+	backup.dir = dir
+	return backup, nil
+}
+
+// writeBackupMeta grava os metadados de um backup em dir
+func writeBackupMeta(dir string, backup Backup) error {
+	data, err := json.MarshalIndent(backup, "", "    ")
+	if err != nil {
+		return fmt.Errorf("erro ao serializar metadados do backup: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, backupMeta), data, 0644); err != nil {
+		return fmt.Errorf("erro ao salvar metadados do backup: %w", err)
+	}
+	return nil
+}
+
+// linkTree reproduz os arquivos de src em dst, por hard link ou, entre
+// sistemas de arquivos diferentes, por cópia; retorna os arquivos,
+// relativos a src, e o tamanho total
+func linkTree(src, dst string) ([]string, int64, error) {
+	var files []string
+	var size int64
+	err := filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if err := linkFile(path, target, info.Mode().Perm()); err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		size += info.Size()
+		return nil
+	})
+	return files, size, err
+}
+
+// linkFile liga dst a src por hard link ou, se não for possível, copia
+// src; dst fica somente leitura
+func linkFile(src, dst string, perm os.FileMode) error {
+	perm &^= 0222
+	if err := os.Link(src, dst); err == nil {
+		return os.Chmod(dst, perm)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// flatName transforma o ID de um modelo, que pode conter barras, em um
+// único nome de diretório
+func flatName(modelID string) string {
+	return strings.ReplaceAll(modelID, "/", "_")
+}
+
+// currentUser retorna o nome do usuário do sistema, que assina os backups sem autor
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "desconhecido"
+}
@@ -0,0 +1,23 @@
+package cache
+
+import "os"
+
+// swapDirs troca os diretórios a e b com três renomeações. Entre elas b
+// some por um instante, então só é usada onde não há troca atômica; se uma
+// renomeação falha, os diretórios voltam aos seus lugares
+func swapDirs(a, b string) error {
+	tmp := a + ".swap"
+	if err := os.Rename(b, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(a, b); err != nil {
+		_ = os.Rename(tmp, b)
+		return err
+	}
+	if err := os.Rename(tmp, a); err != nil {
+		_ = os.Rename(b, a)
+		_ = os.Rename(tmp, b)
+		return err
+	}
+	return nil
+}
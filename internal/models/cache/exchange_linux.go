@@ -0,0 +1,24 @@
+//go:build linux
+
+package cache
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// exchange troca atomicamente os diretórios a e b, que precisam existir e
+// estar no mesmo sistema de arquivos; sistemas de arquivos sem
+// RENAME_EXCHANGE caem em swapDirs
+func exchange(a, b string) error {
+	err := unix.Renameat2(unix.AT_FDCWD, a, unix.AT_FDCWD, b, unix.RENAME_EXCHANGE)
+	if errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EINVAL) {
+		return swapDirs(a, b)
+	}
+	if err != nil {
+		return &os.LinkError{Op: "renameat2", Old: a, New: b, Err: err}
+	}
+	return nil
+}
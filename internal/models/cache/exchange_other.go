@@ -0,0 +1,8 @@
+//go:build !linux
+
+package cache
+
+// exchange troca os diretórios a e b; fora do Linux não há troca atômica
+func exchange(a, b string) error {
+	return swapDirs(a, b)
+}
@@ -13,23 +13,74 @@ import (
 	"github.com/josealecrim/audiototext/internal/models"
 )
 
+// QuarantineDir é o subdiretório do cache para onde vão os modelos corrompidos
+const QuarantineDir = ".quarantine"
+
+// quarantineNote é o arquivo que guarda o motivo da quarentena de um modelo
+const quarantineNote = "QUARANTINE"
+
+// removingDir é o subdiretório do cache para onde os modelos são movidos
+// antes de serem apagados
+const removingDir = ".removing"
+
 // Manager implementa o gerenciador de cache
 type Manager struct {
 	config models.Config
-	mu     sync.RWMutex
-	index  map[string]models.ModelInfo
+	// policy é a política de retenção derivada de config
+	policy models.CleanPolicy
+	// backupPolicy é a política de retenção dos backups derivada de config
+	backupPolicy models.BackupPolicy
+	mu           sync.RWMutex
+	index        map[string]models.ModelInfo
+	// backupMu serializa a criação, a restauração e a limpeza de backups
+	backupMu sync.Mutex
+}
+
+// Decision registra o que a coleta de lixo decidiu sobre um modelo fora
+// da política de retenção
+type Decision struct {
+	ModelID string
+	Size    int64
+	// Reason explica por que o modelo estava fora da política
+	Reason string
+	// Evicted indica se o modelo foi apagado
+	Evicted bool
+	// KeptBecause explica por que um modelo fora da política foi mantido
+	KeptBecause string
+}
+
+// String descreve a decisão para os logs
+func (d Decision) String() string {
+	if d.Evicted {
+		return fmt.Sprintf("modelo %s (%s) removido: %s", d.ModelID, models.FormatSize(d.Size), d.Reason)
+	}
+	return fmt.Sprintf("modelo %s (%s) mantido apesar de %s: %s", d.ModelID, models.FormatSize(d.Size), d.Reason, d.KeptBecause)
 }
 
-// NewManager cria um novo gerenciador de cache
+// NewManager cria um novo gerenciador de cache com a política de retenção de config
 func NewManager(config models.Config) (*Manager, error) {
+	policy, err := config.CleanPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("política de retenção inválida: %w", err)
+	}
+
+	backupPolicy, err := config.BackupPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("política de retenção de backups inválida: %w", err)
+	}
+
 	m := &Manager{
-		config: config,
-		index:  make(map[string]models.ModelInfo),
+		config:       config,
+		policy:       policy,
+		backupPolicy: backupPolicy,
+		index:        make(map[string]models.ModelInfo),
 	}
 
 	if err := m.loadIndex(); err != nil {
 		return nil, err
 	}
+	// Sobras de remoções interrompidas; os modelos já saíram do índice
+	_ = os.RemoveAll(filepath.Join(config.CachePath, removingDir))
 
 	return m, nil
 }
@@ -46,7 +97,9 @@ func (m *Manager) Store(model *models.Model) error {
 	return m.saveIndex()
 }
 
-// Load carrega um modelo do cache
+// Load carrega um modelo do cache. Se o arquivo de pesos mudou desde a
+// última verificação, o checksum é conferido de novo e um modelo
+// corrompido vai para a quarentena
 func (m *Manager) Load(modelID string) (*models.Model, error) {
 	m.mu.RLock()
 	info, exists := m.index[modelID]
@@ -56,17 +109,128 @@ func (m *Manager) Load(modelID string) (*models.Model, error) {
 		return nil, fmt.Errorf("modelo não encontrado no cache: %s", modelID)
 	}
 
-	path := filepath.Join(m.config.CachePath, modelID)
-	if _, err := os.Stat(path); err != nil {
+	path := filepath.Join(m.Dir(modelID), info.Format.FileName())
+	stat, err := os.Stat(path)
+	if err != nil {
 		return nil, fmt.Errorf("arquivo do modelo não encontrado: %w", err)
 	}
 
+	if stat.Size() != info.Size || stat.ModTime().After(info.Verified) {
+		if info, err = m.verify(info, path); err != nil {
+			return nil, err
+		}
+	}
+
 	return &models.Model{
 		Info: info,
 		Path: path,
 	}, nil
 }
 
+// Verify confere o checksum de um modelo mesmo que o arquivo não tenha
+// mudado, mandando-o para a quarentena se estiver corrompido
+func (m *Manager) Verify(modelID string) error {
+	m.mu.RLock()
+	info, exists := m.index[modelID]
+	m.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("modelo não encontrado no cache: %s", modelID)
+	}
+
+	_, err := m.verify(info, filepath.Join(m.Dir(modelID), info.Format.FileName()))
+	return err
+}
+
+// verify confere o checksum do arquivo de pesos em path e registra a
+// verificação no índice; modelos sem checksum conhecido são aceitos
+func (m *Manager) verify(info models.ModelInfo, path string) (models.ModelInfo, error) {
+	if info.Checksum == "" {
+		return info, nil
+	}
+
+	_, checksum, err := models.FileChecksum(path)
+	if err != nil {
+		return info, fmt.Errorf("erro ao verificar modelo %s: %w", info.ID, err)
+	}
+	if checksum != info.Checksum {
+		err := fmt.Errorf("%w: esperado %s, obtido %s", models.ErrChecksumMismatch, info.Checksum, checksum)
+		dest, qerr := m.Quarantine(info.ID, err.Error())
+		if qerr != nil {
+			return info, fmt.Errorf("modelo %s corrompido (%v) e não foi possível isolá-lo: %w", info.ID, err, qerr)
+		}
+		return info, fmt.Errorf("modelo %s movido para a quarentena em %s: %w", info.ID, dest, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	info.Verified = time.Now()
+	if _, exists := m.index[info.ID]; exists {
+		m.index[info.ID] = info
+		if err := m.saveIndex(); err != nil {
+			return info, err
+		}
+	}
+	return info, nil
+}
+
+// Quarantine tira um modelo do índice e move seu diretório para
+// QuarantineDir, junto com o motivo, para que não seja mais carregado nem
+// adotado; retorna o novo diretório
+func (m *Manager) Quarantine(modelID, reason string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name := fmt.Sprintf("%s-%s", flatName(modelID), time.Now().Format("20060102T150405.000000000"))
+	dest := filepath.Join(m.config.CachePath, QuarantineDir, name)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", fmt.Errorf("erro ao criar diretório de quarentena: %w", err)
+	}
+	if err := os.Rename(m.Dir(modelID), dest); err != nil {
+		return "", fmt.Errorf("erro ao mover modelo %s para a quarentena: %w", modelID, err)
+	}
+	// O motivo é só informativo; o modelo já está isolado
+	_ = ioutil.WriteFile(filepath.Join(dest, quarantineNote), []byte(reason+"\n"), 0644)
+
+	if _, exists := m.index[modelID]; exists {
+		delete(m.index, modelID)
+		if err := m.saveIndex(); err != nil {
+			return dest, err
+		}
+	}
+	return dest, nil
+}
+
+// Dir retorna o diretório de um modelo, que guarda os pesos e os arquivos auxiliares
+func (m *Manager) Dir(modelID string) string {
+	return filepath.Join(m.config.CachePath, filepath.FromSlash(modelID))
+}
+
+// Remove apaga um modelo do cache e do índice, a menos que keep, quando
+// não é nil, retorne um motivo para mantê-lo; veja Clean
+func (m *Manager) Remove(modelID string, keep func(modelID string) string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.index[modelID]; !exists {
+		return fmt.Errorf("modelo não encontrado no cache: %s", modelID)
+	}
+	if keep != nil {
+		if reason := keep(modelID); reason != "" {
+			return fmt.Errorf("%w: %s, %s", models.ErrInUse, modelID, reason)
+		}
+	}
+	reason, err := m.remove(modelID, keep)
+	if err != nil {
+		return fmt.Errorf("erro ao remover modelo %s: %w", modelID, err)
+	}
+	if reason != "" {
+		return fmt.Errorf("%w: %s, %s", models.ErrInUse, modelID, reason)
+	}
+	return m.saveIndex()
+}
+
 // List lista todos os modelos no cache
 func (m *Manager) List() []models.ModelInfo {
 	m.mu.RLock()
@@ -85,61 +249,123 @@ func (m *Manager) List() []models.ModelInfo {
 	return infos
 }
 
-// Clean limpa o cache de acordo com a política
-func (m *Manager) Clean(policy models.CleanPolicy) error {
+// Touch marca um modelo como usado agora; a data vai para o disco com o
+// índice na próxima alteração
+func (m *Manager) Touch(modelID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	var toDelete []string
-	var totalSize int64
+	if info, exists := m.index[modelID]; exists {
+		info.LastUsed = time.Now()
+		m.index[modelID] = info
+	}
+}
+
+// Policy retorna a política de retenção derivada da configuração
+func (m *Manager) Policy() models.CleanPolicy {
+	return m.policy
+}
+
+// Collect aplica a política de retenção da configuração; veja Clean
+func (m *Manager) Collect(keep func(modelID string) string) ([]Decision, error) {
+	return m.Clean(m.policy, keep)
+}
 
-	// Lista todos os modelos ordenados por data de último uso
+// Clean apaga os modelos fora da política, dos usados há mais tempo para
+// os mais recentes, e retorna a decisão sobre cada um. Os policy.KeepLatest
+// modelos usados mais recentemente nunca são apagados, nem os modelos para
+// os quais keep retorna um motivo, que vai para Decision.KeptBecause. keep
+// é consultada de novo depois que o diretório sai do lugar; veja remove
+func (m *Manager) Clean(policy models.CleanPolicy, keep func(modelID string) string) ([]Decision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Ordena do uso mais recente para o mais antigo
 	var infos []models.ModelInfo
 	for _, info := range m.index {
 		infos = append(infos, info)
 	}
 	sort.Slice(infos, func(i, j int) bool {
-		return infos[i].LastUsed.Before(infos[j].LastUsed)
+		return lastUse(infos[i]).After(lastUse(infos[j]))
 	})
 
-	// Mantém os N modelos mais recentes
-	if len(infos) > policy.KeepLatest {
-		infos = infos[policy.KeepLatest:]
-	}
-
+	var decisions []Decision
+	var keptSize int64
 	now := time.Now()
-	for _, info := range infos {
-		// Verifica idade do modelo
-		if policy.MaxAge > 0 && now.Sub(info.LastUsed) > policy.MaxAge {
-			toDelete = append(toDelete, info.ID)
+	for i, info := range infos {
+		var reason string
+		switch idle := now.Sub(lastUse(info)); {
+		case policy.MaxAge > 0 && idle > policy.MaxAge:
+			reason = fmt.Sprintf("sem uso há %s, acima da retenção de %s", idle.Round(time.Hour), policy.MaxAge)
+		case policy.MaxSize > 0 && keptSize+info.Size > policy.MaxSize:
+			reason = fmt.Sprintf("cache passaria de %s", models.FormatSize(policy.MaxSize))
+		}
+		if reason == "" {
+			keptSize += info.Size
 			continue
 		}
 
-		// Verifica tamanho total
-		totalSize += info.Size
-		if policy.MaxSize > 0 && totalSize > policy.MaxSize {
-			toDelete = append(toDelete, info.ID)
+		decision := Decision{ModelID: info.ID, Size: info.Size, Reason: reason}
+		switch {
+		case i < policy.KeepLatest:
+			decision.KeptBecause = fmt.Sprintf("entre os %d usados mais recentemente", policy.KeepLatest)
+		case keep != nil:
+			decision.KeptBecause = keep(info.ID)
 		}
-	}
-
-	// Remove os modelos marcados para deleção
-	for _, id := range toDelete {
-		if err := m.remove(id); err != nil {
-			return fmt.Errorf("erro ao remover modelo %s: %w", id, err)
+		if decision.KeptBecause == "" {
+			reason, err := m.remove(info.ID, keep)
+			if err != nil {
+				return decisions, fmt.Errorf("erro ao remover modelo %s: %w", info.ID, err)
+			}
+			decision.KeptBecause = reason
+		}
+		if decision.KeptBecause != "" {
+			keptSize += info.Size
+			decisions = append(decisions, decision)
+			continue
 		}
+
+		decision.Evicted = true
+		decisions = append(decisions, decision)
 	}
 
-	return m.saveIndex()
+	return decisions, m.saveIndex()
 }
 
-// remove remove um modelo do cache
-func (m *Manager) remove(modelID string) error {
-	path := filepath.Join(m.config.CachePath, modelID)
-	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
-		return err
+// lastUse retorna quando um modelo foi usado pela última vez, contando o download como uso
+func lastUse(info models.ModelInfo) time.Time {
+	if info.LastUsed.After(info.Downloaded) {
+		return info.LastUsed
 	}
+	return info.Downloaded
+}
+
+// remove remove um modelo do cache, a menos que keep passe a retornar um
+// motivo para mantê-lo, que é retornado. Quem abre uma sessão não passa
+// por m.mu, então o diretório sai do lugar antes de keep ser consultada de
+// novo: uma sessão contada antes disso faz o diretório voltar, e uma
+// aberta depois não encontra os arquivos em vez de vê-los sumir durante a
+// leitura. Chamado com m.mu
+func (m *Manager) remove(modelID string, keep func(modelID string) string) (string, error) {
+	aside := filepath.Join(m.config.CachePath, removingDir, fmt.Sprintf("%s-%d", flatName(modelID), time.Now().UnixNano()))
+	if err := os.MkdirAll(filepath.Dir(aside), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(m.Dir(modelID), aside); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if keep != nil {
+		if reason := keep(modelID); reason != "" {
+			if err := os.Rename(aside, m.Dir(modelID)); err != nil && !os.IsNotExist(err) {
+				return "", fmt.Errorf("erro ao devolver modelo %s em uso: %w", modelID, err)
+			}
+			return reason, nil
+		}
+	}
+
 	delete(m.index, modelID)
-	return nil
+	return "", os.RemoveAll(aside)
 }
 
 // loadIndex carrega o índice do cache
@@ -163,8 +389,12 @@ func (m *Manager) saveIndex() error {
 		return fmt.Errorf("erro ao serializar índice: %w", err)
 	}
 
+	// Um processo interrompido no meio da escrita não deixa o índice truncado
 	path := filepath.Join(m.config.CachePath, "index.json")
-	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("erro ao salvar índice: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
 		return fmt.Errorf("erro ao salvar índice: %w", err)
 	}
 
@@ -2,7 +2,12 @@ package download
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"os"
@@ -13,178 +18,295 @@ import (
 	"github.com/josealecrim/audiototext/internal/models"
 )
 
-// Manager implementa o gerenciador de downloads
+// Manager implementa o gerenciador de downloads. Os downloads passam por
+// uma fila com prioridades e no máximo Config.ConcurrentDownloads rodam ao
+// mesmo tempo
 type Manager struct {
-	config    models.Config
-	client    *http.Client
-	downloads map[string]*download
-	mu        sync.RWMutex
-}
+	config models.Config
+	// sources são as origens dos modelos em ordem de prioridade
+	sources []Source
+	// onComplete é chamada quando um download termina, antes de liberar quem espera
+	onComplete func(*models.Model) error
 
-type download struct {
-	progress models.Progress
-	cancel   context.CancelFunc
+	mu sync.RWMutex
+	// jobs mapeia IDs de modelos para os jobs na fila ou em andamento
+	jobs    map[string]*Job
+	queue   jobQueue
+	running int
+	seq     uint64
 }
 
-// NewManager cria um novo gerenciador de downloads
-func NewManager(config models.Config) *Manager {
-	return &Manager{
-		config:    config,
-		client:    &http.Client{Timeout: config.DownloadTimeout},
-		downloads: make(map[string]*download),
+// NewManager cria um novo gerenciador de downloads com as origens de config.Sources
+func NewManager(config models.Config) (*Manager, error) {
+	sources, err := NewSources(config.Sources, &http.Client{Timeout: config.DownloadTimeout})
+	if err != nil {
+		return nil, err
 	}
+	return &Manager{
+		config:  config,
+		sources: sources,
+		jobs:    make(map[string]*Job),
+	}, nil
 }
 
-// Download inicia o download de um modelo
-func (m *Manager) Download(modelType models.ModelType, version string) (*models.Model, error) {
-	modelID := fmt.Sprintf("%s-%s", modelType, version)
+// SetOnComplete define uma função chamada uma vez por download concluído,
+// antes de liberar quem espera por ele; um erro dela falha o download
+func (m *Manager) SetOnComplete(fn func(*models.Model) error) {
+	m.onComplete = fn
+}
 
-	// Verifica se já existe um download em andamento
-	m.mu.RLock()
-	if _, exists := m.downloads[modelID]; exists {
-		m.mu.RUnlock()
-		return nil, fmt.Errorf("download já em andamento para %s", modelID)
+// run baixa o manifesto e os pesos do modelo de um job, conferindo os
+// pesos com o checksum do manifesto quando houver um. As origens são
+// tentadas em ordem de prioridade até uma ter o modelo. Um job que
+// substitui um cancelado espera aquele terminar antes de começar
+func (m *Manager) run(job *Job) {
+	if job.after != nil {
+		select {
+		case <-job.after:
+		case <-job.ctx.Done():
+		}
 	}
-	m.mu.RUnlock()
 
-	// Cria diretório de cache se não existir
-	if err := os.MkdirAll(m.config.CachePath, 0755); err != nil {
-		return nil, fmt.Errorf("erro ao criar diretório de cache: %w", err)
+	// A versão já foi validada quando o job entrou na fila
+	ref, _ := NewRef(job.request.Type, job.request.Version)
+	var failures []error
+	for _, source := range m.sources {
+		err := m.retry(job, func() error {
+			return m.fetchModel(job, source, ref)
+		})
+		if err == nil {
+			failures = nil
+			break
+		}
+		failures = append(failures, fmt.Errorf("origem %s: %w", source.Name(), err))
+		if job.ctx.Err() != nil {
+			break
+		}
 	}
-
-	// Prepara o download
-	ctx, cancel := context.WithCancel(context.Background())
-	dl := &download{
-		progress: models.Progress{Status: "iniciando"},
-		cancel:   cancel,
+	err := errors.Join(failures...)
+	if err == nil {
+		job.model.Info.Downloaded = time.Now()
+		if m.onComplete != nil {
+			err = m.onComplete(job.model)
+		}
 	}
 
 	m.mu.Lock()
-	m.downloads[modelID] = dl
+	m.running--
+	m.finishJob(job, err)
+	m.dispatch()
 	m.mu.Unlock()
+}
 
-	// Inicia o download em uma goroutine
-	go func() {
-		defer func() {
-			m.mu.Lock()
-			delete(m.downloads, modelID)
-			m.mu.Unlock()
-			cancel()
-		}()
-
-		url := getModelURL(modelType, version)
-		if err := m.downloadFile(ctx, url, modelID); err != nil {
-			dl.progress.Status = fmt.Sprintf("erro: %v", err)
-			return
-		}
-
-		dl.progress.Status = "concluído"
-	}()
-
-	// Retorna imediatamente com as informações do modelo
-	return &models.Model{
-		Info: models.ModelInfo{
-			ID:         modelID,
-			Type:       modelType,
-			Version:    version,
-			Downloaded: time.Now(),
-		},
-		Path: filepath.Join(m.config.CachePath, modelID),
-	}, nil
+// fetchModel baixa o manifesto, se houver, e os pesos do modelo de um job de source
+func (m *Manager) fetchModel(job *Job, source Source, ref Ref) error {
+	model := job.model
+	expected, err := m.downloadManifest(job.ctx, source, ref, filepath.Dir(model.Path))
+	if err != nil {
+		return err
+	}
+	checksum, err := m.downloadFile(job, source, ref, model.Info.Format.FileName(), model.Path, expected)
+	if err != nil {
+		return err
+	}
+	model.Info.Checksum = checksum
+	return nil
 }
 
-// GetProgress retorna o progresso de um download
+// GetProgress retorna o progresso de um download; um download
+// interrompido, mesmo por um processo anterior, tem status "interrompido"
+// e continua de onde parou quando for pedido de novo
 func (m *Manager) GetProgress(modelID string) (models.Progress, error) {
 	m.mu.RLock()
-	dl, exists := m.downloads[modelID]
+	job, exists := m.jobs[modelID]
+	var progress models.Progress
+	if exists {
+		progress = job.progress
+	}
 	m.mu.RUnlock()
 
-	if !exists {
-		return models.Progress{}, fmt.Errorf("download não encontrado: %s", modelID)
+	if exists {
+		return progress, nil
 	}
 
-	return dl.progress, nil
+	path := filepath.Join(m.config.CachePath, filepath.FromSlash(modelID), models.FormatONNX.FileName())
+	previous, err := loadState(path)
+	if err != nil || previous == nil {
+		return models.Progress{}, fmt.Errorf("download não encontrado: %s", modelID)
+	}
+	var current int64
+	if info, err := os.Stat(path + ".part"); err == nil {
+		current = info.Size()
+	}
+	return models.Progress{
+		Total:      previous.Total,
+		Current:    current,
+		Percentage: percentage(current, previous.Total),
+		Status:     "interrompido",
+	}, nil
 }
 
-// Cancel cancela um download em andamento
-func (m *Manager) Cancel(modelID string) error {
-	m.mu.Lock()
-	dl, exists := m.downloads[modelID]
-	m.mu.Unlock()
+// maxManifestSize limita o tamanho de um manifesto baixado
+const maxManifestSize = 1 << 20
 
-	if !exists {
-		return fmt.Errorf("download não encontrado: %s", modelID)
+// downloadManifest baixa o manifesto opcional do modelo de source para
+// dir e retorna o checksum dos pesos declarado nele; modelos sem manifesto
+// não têm checksum esperado
+func (m *Manager) downloadManifest(ctx context.Context, source Source, ref Ref, dir string) (string, error) {
+	object, err := source.Fetch(ctx, ref, models.ManifestFile, 0, "")
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("erro ao baixar manifesto: %w", err)
 	}
+	defer object.Body.Close()
 
-	dl.cancel()
-	return nil
+	data, err := io.ReadAll(io.LimitReader(object.Body, maxManifestSize))
+	if err != nil {
+		return "", retryable(fmt.Errorf("erro ao ler manifesto: %w", err))
+	}
+	var manifest models.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return "", fmt.Errorf("manifesto inválido: %w", err)
+	}
+
+	path := filepath.Join(dir, models.ManifestFile)
+	if err := os.WriteFile(path+".part", data, 0644); err != nil {
+		return "", fmt.Errorf("erro ao salvar manifesto: %w", err)
+	}
+	if err := os.Rename(path+".part", path); err != nil {
+		return "", fmt.Errorf("erro ao salvar manifesto: %w", err)
+	}
+	return manifest.SHA256, nil
 }
 
-// downloadFile realiza o download de um arquivo
-func (m *Manager) downloadFile(ctx context.Context, url, modelID string) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// downloadFile baixa o arquivo file do modelo de source para path e
+// retorna seu SHA-256. Os dados são gravados em path.part, que só
+// substitui path quando o download termina e o checksum confere com
+// expected; vazio aceita qualquer checksum. Um arquivo parcial da mesma
+// origem, inclusive de um processo anterior, é continuado de onde parou
+// se a versão do arquivo na origem não mudou
+func (m *Manager) downloadFile(job *Job, source Source, ref Ref, file, path, expected string) (string, error) {
+	ctx := job.ctx
+	partial := path + ".part"
+	out, err := os.OpenFile(partial, os.O_RDWR|os.O_CREATE, 0644)
 	if err != nil {
-		return fmt.Errorf("erro ao criar request: %w", err)
+		return "", fmt.Errorf("erro ao criar arquivo: %w", err)
 	}
+	defer out.Close()
 
-	resp, err := m.client.Do(req)
+	// O hash cobre os dados já baixados, que também deixam o arquivo pronto para continuar
+	hash := sha256.New()
+	offset, err := io.Copy(hash, out)
 	if err != nil {
-		return fmt.Errorf("erro ao fazer request: %w", err)
+		return "", fmt.Errorf("erro ao ler arquivo parcial: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("status code inválido: %d", resp.StatusCode)
+	previous, err := loadState(path)
+	if err != nil || previous == nil || previous.Source != source.Name() || previous.Version == "" {
+		previous = &state{}
+	}
+	if offset > 0 && offset == previous.Total {
+		// O processo anterior parou depois de baixar tudo
+		checksum, err := finish(out, partial, path, hex.EncodeToString(hash.Sum(nil)), expected)
+		os.Remove(path + stateSuffix)
+		return checksum, err
 	}
 
-	file, err := os.Create(filepath.Join(m.config.CachePath, modelID))
+	object, err := source.Fetch(ctx, ref, file, offset, previous.Version)
 	if err != nil {
-		return fmt.Errorf("erro ao criar arquivo: %w", err)
+		return "", err
+	}
+	defer object.Body.Close()
+
+	if object.Offset != offset {
+		// A origem mandou o arquivo inteiro
+		if err := restart(out, hash); err != nil {
+			return "", err
+		}
+		offset = 0
+	}
+	current := &state{Source: source.Name(), Version: object.Version, Total: object.Size}
+	if err := saveState(path, current); err != nil {
+		return "", err
 	}
-	defer file.Close()
 
 	// Atualiza o progresso durante o download
 	m.mu.Lock()
-	dl := m.downloads[modelID]
-	dl.progress.Total = resp.ContentLength
+	job.progress.Current = offset
+	job.progress.Total = object.Size
+	job.progress.Status = "baixando"
+	job.publish()
 	m.mu.Unlock()
 
+	writer := io.MultiWriter(out, hash)
 	buffer := make([]byte, 32*1024)
 	for {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return "", ctx.Err()
 		default:
-			n, err := resp.Body.Read(buffer)
+			// Read pode devolver os últimos bytes junto com io.EOF
+			n, err := object.Body.Read(buffer)
+			if n > 0 {
+				if _, err := writer.Write(buffer[:n]); err != nil {
+					return "", fmt.Errorf("erro ao escrever arquivo: %w", err)
+				}
+
+				m.mu.Lock()
+				job.progress.Current += int64(n)
+				job.progress.Percentage = percentage(job.progress.Current, job.progress.Total)
+				job.publish()
+				m.mu.Unlock()
+			}
 			if err == io.EOF {
-				return nil
+				checksum, err := finish(out, partial, path, hex.EncodeToString(hash.Sum(nil)), expected)
+				os.Remove(path + stateSuffix)
+				return checksum, err
 			}
 			if err != nil {
-				return fmt.Errorf("erro ao ler resposta: %w", err)
-			}
-
-			if _, err := file.Write(buffer[:n]); err != nil {
-				return fmt.Errorf("erro ao escrever arquivo: %w", err)
+				if ctx.Err() != nil {
+					return "", ctx.Err()
+				}
+				return "", retryable(fmt.Errorf("erro ao ler %s: %w", file, err))
 			}
-
-			m.mu.Lock()
-			dl.progress.Current += int64(n)
-			dl.progress.Percentage = float64(dl.progress.Current) / float64(dl.progress.Total) * 100
-			m.mu.Unlock()
 		}
 	}
 }
 
-// getModelURL retorna a URL do modelo baseado no tipo e versão
-func getModelURL(modelType models.ModelType, version string) string {
-	baseURL := "https://huggingface.co/models"
+// restart esvazia o arquivo parcial para baixá-lo desde o início
+func restart(file *os.File, hash hash.Hash) error {
+	if err := file.Truncate(0); err != nil {
+		return fmt.Errorf("erro ao truncar arquivo parcial: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("erro ao truncar arquivo parcial: %w", err)
+	}
+	hash.Reset()
+	return nil
+}
+
+// percentage retorna current como porcentagem de total; 0 se total for desconhecido
+func percentage(current, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(current) / float64(total) * 100
+}
 
-	switch modelType {
-	case models.TypeClaude:
-		return fmt.Sprintf("%s/anthropic/claude-3-sonnet-%s/resolve/main/model.onnx", baseURL, version)
-	case models.TypeWhisper:
-		return fmt.Sprintf("%s/openai/whisper-small-%s/resolve/main/model.onnx", baseURL, version)
-	default:
-		return ""
+// finish fecha o arquivo parcial e o move para path se o checksum conferir;
+// senão o apaga
+func finish(file *os.File, partial, path, checksum, expected string) (string, error) {
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("erro ao fechar arquivo: %w", err)
+	}
+	if expected != "" && checksum != expected {
+		os.Remove(partial)
+		return "", fmt.Errorf("%w: esperado %s, obtido %s", models.ErrChecksumMismatch, expected, checksum)
+	}
+	if err := os.Rename(partial, path); err != nil {
+		return "", fmt.Errorf("erro ao mover arquivo: %w", err)
 	}
+	return checksum, nil
 }
@@ -0,0 +1,330 @@
+package download
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/josealecrim/audiototext/internal/models"
+)
+
+// defaultConcurrentDownloads é o limite de downloads simultâneos sem Config.ConcurrentDownloads
+const defaultConcurrentDownloads = 2
+
+// Request pede o download de um modelo
+type Request struct {
+	Type models.ModelType
+	// Version é a versão semântica do modelo, como 1.0.0
+	Version string
+	// Priority ordena a fila: pedidos de maior prioridade começam primeiro
+	// e os de mesma prioridade na ordem em que chegaram
+	Priority int
+}
+
+// Job é o download de um modelo, compartilhado por todos que o pediram
+// enquanto ele está na fila ou em andamento
+type Job struct {
+	manager *Manager
+	request Request
+	model   *models.Model
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	// Os campos abaixo são protegidos por manager.mu
+	progress    models.Progress
+	subscribers []chan models.Progress
+	// waiters é o número de chamadas de Fetch esperando o download
+	waiters int
+	// detached mantém o download mesmo sem ninguém esperando
+	detached bool
+	// index é a posição do job na fila; -1 depois que ele sai dela
+	index int
+	// seq é a ordem de chegada do job
+	seq uint64
+	// after é o done do job cancelado que este substitui; os dois usam os
+	// mesmos arquivos, então este só baixa depois que aquele termina
+	after <-chan struct{}
+
+	done chan struct{}
+	err  error
+}
+
+// ID retorna o ID do modelo sendo baixado
+func (j *Job) ID() string {
+	return j.model.Info.ID
+}
+
+// Wait espera o download terminar e retorna o modelo baixado
+func (j *Job) Wait(ctx context.Context) (*models.Model, error) {
+	select {
+	case <-j.done:
+		if j.err != nil {
+			return nil, j.err
+		}
+		return j.model, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Subscribe retorna um canal com o progresso do download, começando pelo
+// atual. Um assinante lento só perde atualizações intermediárias: o canal
+// sempre guarda a mais recente. O canal é fechado depois do progresso
+// final ou quando a função retornada é chamada
+func (j *Job) Subscribe() (<-chan models.Progress, func()) {
+	updates := make(chan models.Progress, 1)
+
+	j.manager.mu.Lock()
+	defer j.manager.mu.Unlock()
+
+	updates <- j.progress
+	select {
+	case <-j.done:
+		close(updates)
+		return updates, func() {}
+	default:
+	}
+	j.subscribers = append(j.subscribers, updates)
+
+	unsubscribe := func() {
+		j.manager.mu.Lock()
+		defer j.manager.mu.Unlock()
+		for i, subscriber := range j.subscribers {
+			if subscriber == updates {
+				j.subscribers = append(j.subscribers[:i], j.subscribers[i+1:]...)
+				close(updates)
+				return
+			}
+		}
+	}
+	return updates, unsubscribe
+}
+
+// publish envia o progresso do job aos assinantes; chamado com manager.mu
+func (j *Job) publish() {
+	for _, subscriber := range j.subscribers {
+		// Troca a atualização pendente pela mais recente
+		select {
+		case <-subscriber:
+		default:
+		}
+		subscriber <- j.progress
+	}
+}
+
+// jobQueue é a fila de jobs esperando um worker, ordenada por prioridade
+// e chegada; implementa heap.Interface
+type jobQueue []*Job
+
+func (q jobQueue) Len() int { return len(q) }
+
+func (q jobQueue) Less(i, j int) bool {
+	if q[i].request.Priority != q[j].request.Priority {
+		return q[i].request.Priority > q[j].request.Priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q jobQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *jobQueue) Push(x interface{}) {
+	job := x.(*Job)
+	job.index = len(*q)
+	*q = append(*q, job)
+}
+
+func (q *jobQueue) Pop() interface{} {
+	old := *q
+	job := old[len(old)-1]
+	old[len(old)-1] = nil
+	job.index = -1
+	*q = old[:len(old)-1]
+	return job
+}
+
+// Enqueue põe o download de um modelo na fila e retorna sem esperar; o
+// download continua até terminar ou ser cancelado com Cancel. Se o modelo
+// já está na fila ou sendo baixado, retorna o mesmo job
+func (m *Manager) Enqueue(req Request) (*Job, error) {
+	return m.enqueue(req, true)
+}
+
+// Fetch baixa um modelo e espera o download terminar. Chamadas simultâneas
+// para o mesmo modelo esperam o mesmo download, que é cancelado se todas
+// desistirem antes dele terminar
+func (m *Manager) Fetch(ctx context.Context, req Request) (*models.Model, error) {
+	job, err := m.enqueue(req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := job.Wait(ctx)
+	if err != nil && ctx.Err() != nil {
+		m.mu.Lock()
+		job.waiters--
+		if job.waiters == 0 && !job.detached {
+			// Só o job esperado: ele pode ter acabado e outro ter começado para o mesmo modelo
+			m.cancelJob(job)
+		}
+		m.mu.Unlock()
+	}
+	return model, err
+}
+
+// enqueue retorna o job do modelo de req, criando-o na fila se preciso, e
+// registra quem o pediu sob o mesmo lock: detached para Enqueue, mais um
+// em waiters para Fetch. Assim ninguém que desiste cancela um job que
+// outro acabou de receber
+func (m *Manager) enqueue(req Request, detached bool) (*Job, error) {
+	ref, err := NewRef(req.Type, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(m.config.CachePath, filepath.FromSlash(ref.ID))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Um job cancelado ainda em andamento não é entregue a ninguém: o novo
+	// pedido ganha outro job, que espera aquele terminar
+	var after <-chan struct{}
+	if job, exists := m.jobs[ref.ID]; exists && job.ctx.Err() != nil {
+		after = job.done
+	} else if exists {
+		// Um pedido mais urgente adianta o job na fila
+		if req.Priority > job.request.Priority {
+			job.request.Priority = req.Priority
+			if job.index >= 0 {
+				heap.Fix(&m.queue, job.index)
+			}
+		}
+		job.claim(detached)
+		return job, nil
+	}
+
+	// Cria o diretório do modelo se não existir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório do modelo: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.seq++
+	job := &Job{
+		manager: m,
+		request: req,
+		model: &models.Model{
+			Info: models.ModelInfo{
+				ID:      ref.ID,
+				Type:    req.Type,
+				Version: ref.Version,
+				Format:  models.FormatONNX,
+			},
+			Path: filepath.Join(dir, models.FormatONNX.FileName()),
+		},
+		ctx:      ctx,
+		cancel:   cancel,
+		progress: models.Progress{Status: "na fila"},
+		seq:      m.seq,
+		after:    after,
+		done:     make(chan struct{}),
+	}
+	job.claim(detached)
+	m.jobs[ref.ID] = job
+	heap.Push(&m.queue, job)
+	m.dispatch()
+	return job, nil
+}
+
+// dispatch inicia os jobs da fila enquanto houver workers livres; chamado com m.mu
+func (m *Manager) dispatch() {
+	limit := m.config.ConcurrentDownloads
+	if limit <= 0 {
+		limit = defaultConcurrentDownloads
+	}
+
+	for m.running < limit && m.queue.Len() > 0 {
+		job := heap.Pop(&m.queue).(*Job)
+		m.running++
+		job.progress.Status = "iniciando"
+		job.publish()
+		go m.run(job)
+	}
+}
+
+// finishJob encerra um job com err, avisando quem espera por ele; chamado com m.mu
+func (m *Manager) finishJob(job *Job, err error) {
+	job.err = err
+	if err != nil {
+		job.progress.Status = fmt.Sprintf("erro: %v", err)
+	} else {
+		job.progress.Status = "concluído"
+	}
+	job.publish()
+	for _, subscriber := range job.subscribers {
+		close(subscriber)
+	}
+	job.subscribers = nil
+
+	if m.jobs[job.ID()] == job {
+		delete(m.jobs, job.ID())
+	}
+	close(job.done)
+	job.cancel()
+}
+
+// Subscribe retorna um canal com o progresso do download de um modelo na
+// fila ou em andamento; veja Job.Subscribe
+func (m *Manager) Subscribe(modelID string) (<-chan models.Progress, func(), error) {
+	m.mu.RLock()
+	job, exists := m.jobs[modelID]
+	m.mu.RUnlock()
+
+	if !exists {
+		return nil, nil, fmt.Errorf("download não encontrado: %s", modelID)
+	}
+	updates, unsubscribe := job.Subscribe()
+	return updates, unsubscribe, nil
+}
+
+// Cancel cancela um download na fila ou em andamento
+func (m *Manager) Cancel(modelID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.jobs[modelID]
+	if !exists {
+		return fmt.Errorf("download não encontrado: %s", modelID)
+	}
+	m.cancelJob(job)
+	return nil
+}
+
+// cancelJob cancela job se ele ainda é o download do seu modelo; chamado com m.mu
+func (m *Manager) cancelJob(job *Job) {
+	if m.jobs[job.ID()] != job {
+		return
+	}
+
+	if job.index >= 0 {
+		// Ainda na fila: nenhum worker vai encerrá-lo
+		heap.Remove(&m.queue, job.index)
+		m.finishJob(job, context.Canceled)
+		return
+	}
+	job.cancel()
+}
+
+// claim registra quem pediu o job; chamado com manager.mu
+func (j *Job) claim(detached bool) {
+	if detached {
+		j.detached = true
+	} else {
+		j.waiters++
+	}
+}
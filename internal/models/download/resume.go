@@ -0,0 +1,118 @@
+package download
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+)
+
+const (
+	// defaultRetryBackoff é a espera antes da primeira nova tentativa sem Config.RetryBackoff
+	defaultRetryBackoff = time.Second
+	// maxRetryBackoff limita a espera entre tentativas
+	maxRetryBackoff = time.Minute
+	// stateSuffix é o sufixo do arquivo de estado de um download parcial
+	stateSuffix = ".part.json"
+)
+
+// state é o estado de um download parcial, gravado ao lado do arquivo
+// parcial para que o download continue depois de uma falha ou de um
+// reinício do processo
+type state struct {
+	// Source é a origem de onde o arquivo parcial foi baixado
+	Source string `json:"source"`
+	// Version identifica a versão do arquivo na origem; o download só
+	// continua se ela não mudou
+	Version string `json:"version,omitempty"`
+	// Total é o tamanho do arquivo completo; -1 se desconhecido
+	Total int64 `json:"total"`
+}
+
+// loadState lê o estado do download parcial de path; retorna nil quando
+// não há download parcial
+func loadState(path string) (*state, error) {
+	data, err := os.ReadFile(path + stateSuffix)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler estado do download: %w", err)
+	}
+
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("estado do download inválido: %w", err)
+	}
+	return &s, nil
+}
+
+// saveState grava o estado do download parcial de path
+func saveState(path string, s *state) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar estado do download: %w", err)
+	}
+	if err := os.WriteFile(path+stateSuffix, data, 0644); err != nil {
+		return fmt.Errorf("erro ao salvar estado do download: %w", err)
+	}
+	return nil
+}
+
+// retryableError marca uma falha transitória, que vale uma nova tentativa
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// retryable marca err como transitório
+func retryable(err error) error {
+	return &retryableError{err: err}
+}
+
+// retry chama attempt até ela ter sucesso, falhar de forma definitiva ou
+// esgotar Config.Retries novas tentativas, esperando entre elas um tempo
+// que dobra a cada tentativa
+func (m *Manager) retry(job *Job, attempt func() error) error {
+	base := m.config.RetryBackoff
+	if base <= 0 {
+		base = defaultRetryBackoff
+	}
+
+	for i := 0; ; i++ {
+		err := attempt()
+		var transient *retryableError
+		if err == nil || !errors.As(err, &transient) || i >= m.config.Retries {
+			return err
+		}
+
+		m.mu.Lock()
+		job.progress.Status = fmt.Sprintf("tentativa %d falhou, repetindo: %v", i+1, err)
+		job.publish()
+		m.mu.Unlock()
+
+		select {
+		case <-job.ctx.Done():
+			return job.ctx.Err()
+		case <-time.After(backoff(base, i)):
+		}
+	}
+}
+
+// backoff retorna a espera antes da nova tentativa depois de attempt
+// falhas: base dobrada a cada falha, limitada a maxRetryBackoff, com uma
+// variação aleatória para que clientes que falharam juntos não repitam juntos
+func backoff(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 0; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,80 @@
+package download
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// unsignedPayload dispensa o hash do corpo nos requests assinados, que não têm corpo
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// s3Source baixa os arquivos de um bucket de um serviço compatível com S3,
+// endereçado por caminho (endpoint/bucket/chave) e com requests assinados
+// com AWS Signature Version 4
+type s3Source struct {
+	httpSource
+}
+
+func (s *s3Source) Fetch(ctx context.Context, ref Ref, file string, offset int64, version string) (*Object, error) {
+	key := strings.TrimPrefix(ref.expand(s.config.Path, file), "/")
+	segments := strings.Split(s.config.Bucket+"/"+key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	objectURL := strings.TrimSuffix(s.config.URL, "/") + "/" + strings.Join(segments, "/")
+
+	// S3 não honra If-Range; If-Match recusa com 412 uma versão diferente
+	return s.get(ctx, objectURL, offset, version, "If-Match", s.sign)
+}
+
+// sign assina req com AWS Signature Version 4; sem AccessKey o request vai anônimo
+func (s *s3Source) sign(req *http.Request) {
+	if s.config.AccessKey == "" {
+		return
+	}
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + unsignedPayload,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+
+	scope := date + "/" + s.config.Region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretKey), date)
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.config.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// hmacSHA256 retorna o HMAC-SHA256 de data com key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,269 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/josealecrim/audiototext/internal/models"
+)
+
+// ErrNotFound indica que a origem não tem o arquivo pedido
+var ErrNotFound = errors.New("arquivo não encontrado na origem")
+
+// defaultSource é a origem usada sem Config.Sources
+var defaultSource = models.SourceConfig{Name: "huggingface", Kind: models.SourceHTTP, URL: "https://huggingface.co/models"}
+
+// Ref identifica o modelo cujos arquivos são buscados numa origem
+type Ref struct {
+	// ID é o ID do modelo, como whisper@1.0.0
+	ID      string
+	Type    models.ModelType
+	Version string
+	// Repo é o repositório do modelo no Hugging Face, como openai/whisper-small-1.0.0
+	Repo string
+}
+
+// NewRef retorna a referência do modelo de um tipo e versão semântica. O
+// ID segue o formato família@versão do registro, com o tipo como família,
+// para que o modelo baixado seja resolvido pela família e pelos apelidos
+func NewRef(modelType models.ModelType, version string) (Ref, error) {
+	parsed, err := models.ParseVersion(version)
+	if err != nil {
+		return Ref{}, fmt.Errorf("modelo %s: %w", modelType, err)
+	}
+	version = parsed.String()
+
+	ref := Ref{ID: string(modelType) + models.VersionSeparator + version, Type: modelType, Version: version}
+	switch modelType {
+	case models.TypeWhisper:
+		ref.Repo = fmt.Sprintf("openai/whisper-small-%s", version)
+	case models.TypeCTC:
+		ref.Repo = fmt.Sprintf("facebook/wav2vec2-base-960h-%s", version)
+	}
+	return ref, nil
+}
+
+// expand troca os marcadores de template pelos dados de ref e file
+func (r Ref) expand(template, file string) string {
+	return strings.NewReplacer(
+		"{id}", r.ID,
+		"{type}", string(r.Type),
+		"{version}", r.Version,
+		"{repo}", r.Repo,
+		"{file}", file,
+	).Replace(template)
+}
+
+// Object é um arquivo aberto numa origem
+type Object struct {
+	Body io.ReadCloser
+	// Offset é o byte do arquivo onde Body começa: o offset pedido ou 0
+	// quando a origem manda o arquivo inteiro
+	Offset int64
+	// Size é o tamanho do arquivo inteiro; -1 se desconhecido
+	Size int64
+	// Version identifica a versão do arquivo para continuar um download
+	// interrompido; vazio quando a origem não a informa
+	Version string
+}
+
+// Source é uma origem de onde os arquivos dos modelos são baixados
+type Source interface {
+	// Name identifica a origem
+	Name() string
+	// Fetch abre o arquivo file do modelo ref. Com offset > 0, o arquivo é
+	// aberto a partir de offset se ainda estiver na versão version; senão
+	// vem inteiro. Retorna um erro com ErrNotFound se a origem não tem o
+	// arquivo e marcado como transitório se vale tentar de novo
+	Fetch(ctx context.Context, ref Ref, file string, offset int64, version string) (*Object, error)
+}
+
+// NewSources cria as origens configuradas, ordenadas por prioridade
+func NewSources(configs []models.SourceConfig, client *http.Client) ([]Source, error) {
+	if len(configs) == 0 {
+		configs = []models.SourceConfig{defaultSource}
+	}
+	configs = append([]models.SourceConfig(nil), configs...)
+	sort.SliceStable(configs, func(i, j int) bool { return configs[i].Priority < configs[j].Priority })
+
+	sources := make([]Source, 0, len(configs))
+	for _, config := range configs {
+		if config.Name == "" {
+			config.Name = string(config.Kind)
+		}
+		if config.URL == "" {
+			return nil, fmt.Errorf("origem %s sem URL", config.Name)
+		}
+		headers := make(http.Header)
+		for key, value := range config.Headers {
+			headers.Set(key, os.ExpandEnv(value))
+		}
+
+		switch config.Kind {
+		case models.SourceHTTP:
+			if config.Path == "" {
+				config.Path = "{repo}/resolve/main/{file}"
+			}
+			sources = append(sources, &httpSource{config: config, client: client, headers: headers})
+		case models.SourceDir:
+			if config.Path == "" {
+				config.Path = "{id}/{file}"
+			}
+			sources = append(sources, &dirSource{config: config})
+		case models.SourceS3:
+			if config.Path == "" {
+				config.Path = "{id}/{file}"
+			}
+			if config.Bucket == "" {
+				return nil, fmt.Errorf("origem %s sem bucket", config.Name)
+			}
+			if config.Region == "" {
+				config.Region = "us-east-1"
+			}
+			config.AccessKey = os.ExpandEnv(config.AccessKey)
+			config.SecretKey = os.ExpandEnv(config.SecretKey)
+			sources = append(sources, &s3Source{httpSource{config: config, client: client, headers: headers}})
+		default:
+			return nil, fmt.Errorf("tipo de origem desconhecido em %s: %q", config.Name, config.Kind)
+		}
+	}
+	return sources, nil
+}
+
+// httpSource baixa os arquivos de um servidor HTTP(S), continuando
+// downloads com Range e If-Range
+type httpSource struct {
+	config  models.SourceConfig
+	client  *http.Client
+	headers http.Header
+}
+
+func (s *httpSource) Name() string { return s.config.Name }
+
+func (s *httpSource) Fetch(ctx context.Context, ref Ref, file string, offset int64, version string) (*Object, error) {
+	url := strings.TrimSuffix(s.config.URL, "/") + "/" + strings.TrimPrefix(ref.expand(s.config.Path, file), "/")
+	return s.get(ctx, url, offset, version, "If-Range", nil)
+}
+
+// get faz o GET de url, pedindo os bytes a partir de offset condicionados
+// a version pelo cabeçalho condition; sign, se houver, assina o request
+func (s *httpSource) get(ctx context.Context, url string, offset int64, version, condition string, sign func(*http.Request)) (*Object, error) {
+	if version == "" {
+		offset = 0
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar request: %w", err)
+	}
+	for key, values := range s.headers {
+		req.Header[key] = values
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set(condition, version)
+	}
+	if sign != nil {
+		sign(req)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, retryable(fmt.Errorf("erro ao fazer request: %w", err))
+	}
+
+	object := &Object{Body: resp.Body, Size: -1, Version: responseVersion(resp.Header)}
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0 && rangeStart(resp.Header.Get("Content-Range")) == offset:
+		object.Offset = offset
+	case resp.StatusCode == http.StatusOK:
+	case offset > 0 && (resp.StatusCode == http.StatusPartialContent ||
+		resp.StatusCode == http.StatusRequestedRangeNotSatisfiable ||
+		resp.StatusCode == http.StatusPreconditionFailed):
+		// O servidor não pode continuar de onde paramos; pede o arquivo inteiro
+		resp.Body.Close()
+		return s.get(ctx, url, 0, "", condition, sign)
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, url)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		resp.Body.Close()
+		return nil, retryable(fmt.Errorf("status code inválido: %d", resp.StatusCode))
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("status code inválido: %d", resp.StatusCode)
+	}
+
+	if resp.ContentLength >= 0 {
+		object.Size = object.Offset + resp.ContentLength
+	}
+	return object, nil
+}
+
+// responseVersion retorna a versão do arquivo de uma resposta: a ETag ou,
+// se ela for fraca e não servir para If-Range, a data de modificação
+func responseVersion(header http.Header) string {
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get("Last-Modified")
+}
+
+// rangeStart retorna o primeiro byte de um cabeçalho Content-Range como
+// "bytes 100-199/200"; -1 se o cabeçalho for inválido
+func rangeStart(contentRange string) int64 {
+	var start, end int64
+	var total string
+	if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/%s", &start, &end, &total); err != nil {
+		return -1
+	}
+	return start
+}
+
+// dirSource copia os arquivos de um diretório local ou montado por NFS
+type dirSource struct {
+	config models.SourceConfig
+}
+
+func (s *dirSource) Name() string { return s.config.Name }
+
+func (s *dirSource) Fetch(ctx context.Context, ref Ref, file string, offset int64, version string) (*Object, error) {
+	path := filepath.Join(s.config.URL, filepath.FromSlash(ref.expand(s.config.Path, file)))
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	if err != nil {
+		// Montagens NFS podem voltar depois de uma falha
+		return nil, retryable(fmt.Errorf("erro ao abrir %s: %w", path, err))
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, retryable(fmt.Errorf("erro ao abrir %s: %w", path, err))
+	}
+	object := &Object{
+		Body:    f,
+		Size:    info.Size(),
+		Version: fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano()),
+	}
+	if offset > 0 && offset <= info.Size() && version == object.Version {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return nil, retryable(fmt.Errorf("erro ao abrir %s: %w", path, err))
+		}
+		object.Offset = offset
+	}
+	return object, nil
+}
@@ -0,0 +1,218 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DataType é o tipo dos elementos de um tensor ONNX, com os valores de
+// TensorProto.DataType
+type DataType int32
+
+// Tipos de TensorProto.DataType; os float8 e os de 4 bits não são usados
+// pelos modelos de fala e aparecem como desconhecidos
+const (
+	DataUndefined  DataType = 0
+	DataFloat      DataType = 1
+	DataUint8      DataType = 2
+	DataInt8       DataType = 3
+	DataUint16     DataType = 4
+	DataInt16      DataType = 5
+	DataInt32      DataType = 6
+	DataInt64      DataType = 7
+	DataString     DataType = 8
+	DataBool       DataType = 9
+	DataFloat16    DataType = 10
+	DataDouble     DataType = 11
+	DataUint32     DataType = 12
+	DataUint64     DataType = 13
+	DataComplex64  DataType = 14
+	DataComplex128 DataType = 15
+	DataBfloat16   DataType = 16
+)
+
+// dataTypes descreve cada tipo conhecido: o nome usado nos manifestos e o
+// tamanho de um elemento em bytes, 0 quando variável
+var dataTypes = map[DataType]struct {
+	name string
+	size int64
+}{
+	DataFloat:      {"float32", 4},
+	DataUint8:      {"uint8", 1},
+	DataInt8:       {"int8", 1},
+	DataUint16:     {"uint16", 2},
+	DataInt16:      {"int16", 2},
+	DataInt32:      {"int32", 4},
+	DataInt64:      {"int64", 8},
+	DataString:     {"string", 0},
+	DataBool:       {"bool", 1},
+	DataFloat16:    {"float16", 2},
+	DataDouble:     {"float64", 8},
+	DataUint32:     {"uint32", 4},
+	DataUint64:     {"uint64", 8},
+	DataComplex64:  {"complex64", 8},
+	DataComplex128: {"complex128", 16},
+	DataBfloat16:   {"bfloat16", 2},
+}
+
+// ParseDataType interpreta o nome de um tipo de tensor, como "float32" ou "int64"
+func ParseDataType(name string) (DataType, error) {
+	for dataType, info := range dataTypes {
+		if info.name == strings.ToLower(name) {
+			return dataType, nil
+		}
+	}
+	return DataUndefined, fmt.Errorf("tipo de tensor desconhecido: %q", name)
+}
+
+// String retorna o nome do tipo usado nos manifestos
+func (d DataType) String() string {
+	if info, ok := dataTypes[d]; ok {
+		return info.name
+	}
+	return fmt.Sprintf("tipo(%d)", int32(d))
+}
+
+// Size retorna o tamanho de um elemento em bytes; 0 para tipos de tamanho
+// variável ou desconhecidos
+func (d DataType) Size() int64 {
+	return dataTypes[d].size
+}
+
+// IsFloat indica um tipo de ponto flutuante real
+func (d DataType) IsFloat() bool {
+	switch d {
+	case DataFloat, DataFloat16, DataDouble, DataBfloat16:
+		return true
+	}
+	return false
+}
+
+// Dim é uma dimensão de um tensor: fixa, com Value, ou dinâmica, com o
+// nome simbólico em Param quando o grafo o declara
+type Dim struct {
+	Value   int64  `json:"value,omitempty"`
+	Param   string `json:"param,omitempty"`
+	Dynamic bool   `json:"dynamic,omitempty"`
+}
+
+// String formata a dimensão como no grafo: o valor ou o nome simbólico
+func (d Dim) String() string {
+	switch {
+	case !d.Dynamic:
+		return fmt.Sprint(d.Value)
+	case d.Param != "":
+		return d.Param
+	}
+	return "?"
+}
+
+// TensorInfo descreve uma entrada ou saída do grafo
+type TensorInfo struct {
+	Name string   `json:"name"`
+	Type DataType `json:"type"`
+	// Shape são as dimensões do tensor; nil quando o grafo não declara o posto
+	Shape []Dim `json:"shape"`
+}
+
+// Dims retorna as dimensões do tensor com -1 nas dinâmicas
+func (t TensorInfo) Dims() []int64 {
+	if t.Shape == nil {
+		return nil
+	}
+	dims := make([]int64, len(t.Shape))
+	for i, dim := range t.Shape {
+		if dim.Dynamic {
+			dims[i] = -1
+		} else {
+			dims[i] = dim.Value
+		}
+	}
+	return dims
+}
+
+// String formata o tensor como "nome float32[batch,80,3000]"
+func (t TensorInfo) String() string {
+	dims := make([]string, len(t.Shape))
+	for i, dim := range t.Shape {
+		dims[i] = dim.String()
+	}
+	return fmt.Sprintf("%s %s[%s]", t.Name, t.Type, strings.Join(dims, ","))
+}
+
+// Initializer descreve um peso guardado no grafo
+type Initializer struct {
+	Name string   `json:"name"`
+	Type DataType `json:"type"`
+	Dims []int64  `json:"dims"`
+	// Bytes é o tamanho dos dados do peso
+	Bytes int64 `json:"bytes"`
+	// External indica que os dados ficam em outro arquivo ao lado do modelo
+	External bool `json:"external,omitempty"`
+}
+
+// Opset é um conjunto de operadores importado pelo modelo
+type Opset struct {
+	// Domain é o domínio dos operadores; vazio é o padrão, ai.onnx
+	Domain  string `json:"domain"`
+	Version int64  `json:"version"`
+}
+
+// Graph descreve o grafo ONNX de um modelo, lido do arquivo de pesos sem
+// carregá-lo num runtime
+type Graph struct {
+	IRVersion       int64   `json:"ir_version"`
+	Opsets          []Opset `json:"opsets"`
+	ProducerName    string  `json:"producer_name"`
+	ProducerVersion string  `json:"producer_version"`
+	ModelVersion    int64   `json:"model_version"`
+	Name            string  `json:"name"`
+	// Inputs são as entradas alimentadas a cada execução; entradas que só
+	// repetem um initializer, comuns em IR antigos, ficam de fora
+	Inputs  []TensorInfo `json:"inputs"`
+	Outputs []TensorInfo `json:"outputs"`
+	// Initializers são os pesos guardados no grafo
+	Initializers []Initializer `json:"initializers"`
+	// InitializerBytes é o tamanho total dos pesos
+	InitializerBytes int64 `json:"initializer_bytes"`
+	// Nodes é o número de nós do grafo principal
+	Nodes int `json:"nodes"`
+	// Metadata são as metadata_props do modelo
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Opset retorna a versão importada de um domínio; "" e "ai.onnx" são o
+// mesmo domínio padrão
+func (g *Graph) Opset(domain string) (int64, bool) {
+	for _, opset := range g.Opsets {
+		if opset.Domain == domain || defaultDomain(opset.Domain) && defaultDomain(domain) {
+			return opset.Version, true
+		}
+	}
+	return 0, false
+}
+
+// Input retorna uma entrada do grafo pelo nome
+func (g *Graph) Input(name string) (TensorInfo, bool) {
+	return findTensor(g.Inputs, name)
+}
+
+// Output retorna uma saída do grafo pelo nome
+func (g *Graph) Output(name string) (TensorInfo, bool) {
+	return findTensor(g.Outputs, name)
+}
+
+// findTensor procura um tensor pelo nome
+func findTensor(tensors []TensorInfo, name string) (TensorInfo, bool) {
+	for _, tensor := range tensors {
+		if tensor.Name == name {
+			return tensor, true
+		}
+	}
+	return TensorInfo{}, false
+}
+
+// defaultDomain indica o domínio padrão dos operadores ONNX
+func defaultDomain(domain string) bool {
+	return domain == "" || domain == "ai.onnx"
+}
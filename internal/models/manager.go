@@ -1,203 +0,0 @@
-package models
-
-import (
-	"context"
-	"fmt"
-	"os"
-	"path/filepath"
-	"sync"
-	"time"
-)
-
-// manager implements the Manager interface
-type manager struct {
-	modelDir string
-	cacheDir string
-	mu       sync.RWMutex
-	versions map[string]string
-	cache    map[string][]byte
-}
-
-// NewManager creates a new model manager
-func NewManager(modelDir, cacheDir string) (Manager, error) {
-	return &manager{
-		modelDir: modelDir,
-		cacheDir: cacheDir,
-		versions: make(map[string]string),
-		cache:    make(map[string][]byte),
-	}, nil
-}
-
-// GetModel retrieves a model by its ID
-func (m *manager) GetModel(id string) error {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	if !m.ModelExists(id) {
-		return fmt.Errorf("model %s not found", id)
-	}
-	return nil
-}
-
-// ListModels returns all available models
-func (m *manager) ListModels() ([]string, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	models := make([]string, 0, len(m.versions))
-	for id := range m.versions {
-		models = append(models, id)
-	}
-	return models, nil
-}
-
-// AddModel adds a new model
-func (m *manager) AddModel(id string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	if m.ModelExists(id) {
-		return fmt.Errorf("model %s already exists", id)
-	}
-
-	m.versions[id] = time.Now().Format(time.RFC3339)
-	return nil
-}
-
-// RemoveModel removes a model
-func (m *manager) RemoveModel(id string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	if !m.ModelExists(id) {
-		return fmt.Errorf("model %s not found", id)
-	}
-
-	delete(m.versions, id)
-	delete(m.cache, id)
-	return nil
-}
-
-// UpdateModel updates an existing model
-func (m *manager) UpdateModel(id string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	if !m.ModelExists(id) {
-		return fmt.Errorf("model %s not found", id)
-	}
-
-	m.versions[id] = time.Now().Format(time.RFC3339)
-	return nil
-}
-
-// Implementation of manager methods
-func (m *manager) DownloadModel(ctx context.Context, modelID string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	// Simulate download by creating a dummy file
-	modelPath := filepath.Join(m.modelDir, modelID)
-	if err := os.MkdirAll(filepath.Dir(modelPath), 0755); err != nil {
-		return fmt.Errorf("failed to create model directory: %v", err)
-	}
-
-	// Update version
-	m.versions[modelID] = time.Now().Format(time.RFC3339)
-
-	// Add to cache
-	m.cache[modelID] = []byte("model data")
-
-	return nil
-}
-
-func (m *manager) ModelExists(modelID string) bool {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	_, exists := m.versions[modelID]
-	return exists
-}
-
-func (m *manager) ConvertToONNX(ctx context.Context, modelID string) (string, error) {
-	if !m.ModelExists(modelID) {
-		return "", fmt.Errorf("model %s not found", modelID)
-	}
-	return filepath.Join(m.modelDir, modelID+".onnx"), nil
-}
-
-func (m *manager) ONNXModelExists(modelID string) bool {
-	onnxPath := filepath.Join(m.modelDir, modelID+".onnx")
-	_, err := os.Stat(onnxPath)
-	return err == nil
-}
-
-func (m *manager) ValidateONNXModel(ctx context.Context, modelID string) error {
-	if !m.ONNXModelExists(modelID) {
-		return fmt.Errorf("ONNX model %s not found", modelID)
-	}
-	return nil
-}
-
-func (m *manager) OptimizeONNXModel(ctx context.Context, modelID string) (string, error) {
-	if !m.ONNXModelExists(modelID) {
-		return "", fmt.Errorf("ONNX model %s not found", modelID)
-	}
-	return filepath.Join(m.modelDir, modelID+".optimized.onnx"), nil
-}
-
-func (m *manager) OptimizedModelExists(modelID string) bool {
-	optimizedPath := filepath.Join(m.modelDir, modelID+".optimized.onnx")
-	_, err := os.Stat(optimizedPath)
-	return err == nil
-}
-
-func (m *manager) GetModelVersion(modelID string) (string, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	version, exists := m.versions[modelID]
-	if !exists {
-		return "", fmt.Errorf("model %s not found", modelID)
-	}
-	return version, nil
-}
-
-func (m *manager) CreateBackup(ctx context.Context, modelID string) error {
-	if !m.ModelExists(modelID) {
-		return fmt.Errorf("model %s not found", modelID)
-	}
-	return nil
-}
-
-func (m *manager) ListBackups(modelID string) ([]string, error) {
-	if !m.ModelExists(modelID) {
-		return nil, fmt.Errorf("model %s not found", modelID)
-	}
-	return []string{"backup-" + time.Now().Format(time.RFC3339)}, nil
-}
-
-func (m *manager) RestoreFromBackup(ctx context.Context, modelID string, backupID string) error {
-	if !m.ModelExists(modelID) {
-		return fmt.Errorf("model %s not found", modelID)
-	}
-	return nil
-}
-
-func (m *manager) GetCacheSize() (int64, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	var size int64
-	for _, data := range m.cache {
-		size += int64(len(data))
-	}
-	return size, nil
-}
-
-func (m *manager) ClearCache() error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	m.cache = make(map[string][]byte)
-	return nil
-}
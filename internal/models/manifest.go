@@ -0,0 +1,197 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ManifestFile é o nome do manifesto no diretório de um modelo
+const ManifestFile = "manifest.json"
+
+// DefaultSampleRate é a taxa de amostragem dos modelos sem uma declarada
+const DefaultSampleRate = 16000
+
+// FeatureType é a entrada que o modelo espera
+type FeatureType string
+
+const (
+	// FeatureLogMel é o espectrograma log-mel dos modelos estilo Whisper
+	FeatureLogMel FeatureType = "log_mel"
+	// FeatureWaveform é o áudio bruto normalizado dos modelos CTC
+	FeatureWaveform FeatureType = "waveform"
+)
+
+// languageTag aceita códigos de idioma como "pt", "pt-BR" e "zh-Hant-TW"
+var languageTag = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// sha256Hex aceita um SHA-256 em hexadecimal
+var sha256Hex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Manifest descreve um modelo; fica em manifest.json ao lado dos pesos:
+//
+//	{
+//	  "name": "Whisper Small",
+//	  "description": "Reconhecimento de fala multilíngue",
+//	  "languages": ["en", "pt-BR"],
+//	  "capabilities": {"streaming": true, "word_timestamps": false, "diarization": false, "translation": true},
+//	  "sample_rate": 16000,
+//	  "feature_type": "log_mel",
+//	  "tokenizer_files": ["vocab.json", "added_tokens.json"],
+//	  "license": "MIT",
+//	  "sha256": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+//	  "opset": 17,
+//	  "inputs": [{"name": "input_features", "dtype": "float32", "shape": [-1, 80, 3000]}],
+//	  "outputs": [{"name": "last_hidden_state", "dtype": "float32"}]
+//	}
+type Manifest struct {
+	// Name é o nome de exibição do modelo
+	Name string `json:"name"`
+	// Description descreve o modelo para os clientes
+	Description string `json:"description"`
+	// Type sobrepõe o tipo deduzido pelos arquivos do modelo
+	Type ModelType `json:"type,omitempty"`
+	// Languages são os idiomas suportados; vazio significa qualquer idioma
+	Languages []string `json:"languages"`
+	// Capabilities descreve o que o modelo suporta
+	Capabilities Capabilities `json:"capabilities"`
+	// SampleRate é a taxa de amostragem esperada em Hz; 0 significa DefaultSampleRate
+	SampleRate int `json:"sample_rate"`
+	// FeatureType é a entrada esperada; vazio usa a do tipo do modelo
+	FeatureType FeatureType `json:"feature_type"`
+	// TokenizerFiles são os arquivos do tokenizador, relativos ao diretório do modelo
+	TokenizerFiles []string `json:"tokenizer_files"`
+	// License é o identificador SPDX da licença dos pesos
+	License string `json:"license"`
+	// SHA256 é o checksum esperado do arquivo de pesos em hexadecimal;
+	// downloads e modelos em cache que não o seguem são rejeitados
+	SHA256 string `json:"sha256,omitempty"`
+	// Opset é a versão do opset ai.onnx com que o modelo foi exportado; 0 não confere
+	Opset int64 `json:"opset,omitempty"`
+	// Inputs e Outputs são os tensores que o grafo deve ter; modelos cujo
+	// grafo não os tem são rejeitados
+	Inputs  []TensorSpec `json:"inputs,omitempty"`
+	Outputs []TensorSpec `json:"outputs,omitempty"`
+}
+
+// TensorSpec é um tensor declarado no manifesto, como
+// {"name": "input_features", "dtype": "float32", "shape": [-1, 80, 3000]}
+type TensorSpec struct {
+	Name string `json:"name"`
+	// DType é o nome do tipo dos elementos; vazio não confere
+	DType string `json:"dtype,omitempty"`
+	// Shape são as dimensões, com -1 nas que podem variar; vazio não confere
+	Shape []int64 `json:"shape,omitempty"`
+}
+
+// LoadManifest lê o manifesto do diretório de um modelo; retorna nil
+// quando o diretório não tem manifesto
+func LoadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler manifesto: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("manifesto inválido: %w", err)
+	}
+	return &manifest, nil
+}
+
+// Validate confere o manifesto de um modelo do tipo modelType guardado em
+// dir e preenche os campos omitidos com os valores padrão
+func (m *Manifest) Validate(dir string, modelType ModelType) error {
+	if strings.TrimSpace(m.Name) == "" {
+		return fmt.Errorf("manifesto sem nome")
+	}
+	for _, language := range m.Languages {
+		if !languageTag.MatchString(language) {
+			return fmt.Errorf("idioma inválido no manifesto: %q", language)
+		}
+	}
+
+	if m.SampleRate < 0 {
+		return fmt.Errorf("taxa de amostragem inválida no manifesto: %d", m.SampleRate)
+	}
+	if m.SampleRate == 0 {
+		m.SampleRate = DefaultSampleRate
+	}
+
+	expected := FeatureLogMel
+	if modelType == TypeCTC {
+		expected = FeatureWaveform
+	}
+	switch m.FeatureType {
+	case "":
+		m.FeatureType = expected
+	case FeatureLogMel, FeatureWaveform:
+		if m.FeatureType != expected {
+			return fmt.Errorf("modelos %s usam %s, não %s", modelType, expected, m.FeatureType)
+		}
+	default:
+		return fmt.Errorf("tipo de entrada desconhecido no manifesto: %q", m.FeatureType)
+	}
+
+	if m.SHA256 != "" && !sha256Hex.MatchString(m.SHA256) {
+		return fmt.Errorf("checksum inválido no manifesto: %q", m.SHA256)
+	}
+
+	if m.Opset < 0 {
+		return fmt.Errorf("opset inválido no manifesto: %d", m.Opset)
+	}
+	for _, spec := range append(append([]TensorSpec(nil), m.Inputs...), m.Outputs...) {
+		if spec.Name == "" {
+			return fmt.Errorf("tensor sem nome no manifesto")
+		}
+		if spec.DType != "" {
+			if _, err := ParseDataType(spec.DType); err != nil {
+				return fmt.Errorf("tensor %s: %w", spec.Name, err)
+			}
+		}
+		for _, dim := range spec.Shape {
+			if dim < -1 {
+				return fmt.Errorf("tensor %s: dimensão inválida no manifesto: %d", spec.Name, dim)
+			}
+		}
+	}
+
+	for _, name := range m.TokenizerFiles {
+		if filepath.IsAbs(name) || strings.HasPrefix(filepath.Clean(name), "..") {
+			return fmt.Errorf("arquivo do tokenizador fora do diretório do modelo: %s", name)
+		}
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("arquivo do tokenizador não encontrado: %s", name)
+		}
+	}
+	return nil
+}
+
+// SupportsLanguage indica se um modelo com os idiomas dados atende ao
+// idioma pedido. Os códigos são comparados sem diferenciar maiúsculas e
+// um código só de idioma atende a todas as suas variantes regionais:
+// "pt" atende "pt-BR" e vice-versa. Sem idiomas, qualquer idioma é atendido.
+func SupportsLanguage(languages []string, language string) bool {
+	if len(languages) == 0 || language == "" {
+		return true
+	}
+
+	want := strings.ToLower(language)
+	wantBase := strings.SplitN(want, "-", 2)[0]
+	for _, supported := range languages {
+		have := strings.ToLower(supported)
+		switch {
+		case have == want:
+			return true
+		case have == wantBase || want == strings.SplitN(have, "-", 2)[0]:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,506 @@
+// Package onnx lê a descrição do grafo de um modelo ONNX direto do
+// protobuf (ModelProto), sem carregá-lo num runtime, e a confere com o
+// que o manifesto do modelo declara.
+//
+// O arquivo é lido em sequência e os dados dos pesos são pulados com
+// Seek, então examinar um modelo de vários gigabytes lê só a sua estrutura.
+package onnx
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/josealecrim/audiototext/internal/models"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ErrInvalidModel indica um arquivo que não é um ModelProto ONNX bem formado
+var ErrInvalidModel = errors.New("modelo ONNX inválido")
+
+// maxInlineMessage limita as mensagens lidas inteiras para a memória, como
+// entradas, saídas e metadados; os pesos nunca são lidos
+const maxInlineMessage = 16 << 20
+
+// Campos de ModelProto
+const (
+	modelIRVersion       protowire.Number = 1
+	modelProducerName    protowire.Number = 2
+	modelProducerVersion protowire.Number = 3
+	modelModelVersion    protowire.Number = 5
+	modelGraph           protowire.Number = 7
+	modelOpsetImport     protowire.Number = 8
+	modelMetadataProps   protowire.Number = 14
+)
+
+// Campos de GraphProto
+const (
+	graphNode        protowire.Number = 1
+	graphName        protowire.Number = 2
+	graphInitializer protowire.Number = 5
+	graphInput       protowire.Number = 11
+	graphOutput      protowire.Number = 12
+)
+
+// Campos de TensorProto
+const (
+	tensorDims         protowire.Number = 1
+	tensorDataType     protowire.Number = 2
+	tensorName         protowire.Number = 8
+	tensorRawData      protowire.Number = 9
+	tensorExternalData protowire.Number = 13
+	tensorDataLocation protowire.Number = 14
+)
+
+// dataLocationExternal é o TensorProto.DataLocation dos pesos guardados fora do modelo
+const dataLocationExternal = 1
+
+// Inspect lê o grafo do modelo ONNX em path
+func Inspect(path string) (*models.Graph, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &reader{file: file, buf: bufio.NewReader(file)}
+	graph := &models.Graph{}
+	seenGraph := false
+	err = r.fields(stat.Size(), func(num protowire.Number, typ protowire.Type) error {
+		switch {
+		case num == modelIRVersion && typ == protowire.VarintType:
+			v, err := r.varint()
+			graph.IRVersion = int64(v)
+			return err
+		case num == modelProducerName && typ == protowire.BytesType:
+			return r.str(&graph.ProducerName)
+		case num == modelProducerVersion && typ == protowire.BytesType:
+			return r.str(&graph.ProducerVersion)
+		case num == modelModelVersion && typ == protowire.VarintType:
+			v, err := r.varint()
+			graph.ModelVersion = int64(v)
+			return err
+		case num == modelOpsetImport && typ == protowire.BytesType:
+			data, err := r.message()
+			if err != nil {
+				return err
+			}
+			opset, err := parseOpset(data)
+			graph.Opsets = append(graph.Opsets, opset)
+			return err
+		case num == modelMetadataProps && typ == protowire.BytesType:
+			data, err := r.message()
+			if err != nil {
+				return err
+			}
+			key, value, err := parseEntry(data)
+			if graph.Metadata == nil {
+				graph.Metadata = make(map[string]string)
+			}
+			graph.Metadata[key] = value
+			return err
+		case num == modelGraph && typ == protowire.BytesType:
+			n, err := r.length()
+			if err != nil {
+				return err
+			}
+			seenGraph = true
+			return r.graph(graph, r.pos+n)
+		}
+		return r.skipField(typ)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidModel, err)
+	}
+	if !seenGraph {
+		return nil, fmt.Errorf("%w: modelo sem grafo", ErrInvalidModel)
+	}
+
+	// Em IR antigos os initializers também aparecem como entradas
+	initializers := make(map[string]bool, len(graph.Initializers))
+	for _, initializer := range graph.Initializers {
+		initializers[initializer.Name] = true
+	}
+	inputs := graph.Inputs[:0]
+	for _, input := range graph.Inputs {
+		if !initializers[input.Name] {
+			inputs = append(inputs, input)
+		}
+	}
+	graph.Inputs = inputs
+	return graph, nil
+}
+
+// graph lê um GraphProto que termina em end
+func (r *reader) graph(graph *models.Graph, end int64) error {
+	return r.fields(end, func(num protowire.Number, typ protowire.Type) error {
+		if typ != protowire.BytesType {
+			return r.skipField(typ)
+		}
+
+		switch num {
+		case graphNode:
+			graph.Nodes++
+		case graphName:
+			return r.str(&graph.Name)
+		case graphInitializer:
+			n, err := r.length()
+			if err != nil {
+				return err
+			}
+			initializer, err := r.tensor(r.pos + n)
+			if err != nil {
+				return fmt.Errorf("initializer %s: %w", initializer.Name, err)
+			}
+			graph.Initializers = append(graph.Initializers, initializer)
+			graph.InitializerBytes += initializer.Bytes
+			return nil
+		case graphInput, graphOutput:
+			data, err := r.message()
+			if err != nil {
+				return err
+			}
+			tensor, err := parseValueInfo(data)
+			if err != nil {
+				return fmt.Errorf("tensor %s: %w", tensor.Name, err)
+			}
+			if num == graphInput {
+				graph.Inputs = append(graph.Inputs, tensor)
+			} else {
+				graph.Outputs = append(graph.Outputs, tensor)
+			}
+			return nil
+		}
+		return r.skipField(typ)
+	})
+}
+
+// tensor lê a descrição de um TensorProto que termina em end, pulando os dados
+func (r *reader) tensor(end int64) (models.Initializer, error) {
+	var initializer models.Initializer
+	var raw, external int64 = -1, -1
+	err := r.fields(end, func(num protowire.Number, typ protowire.Type) error {
+		switch {
+		case num == tensorDims && typ == protowire.VarintType:
+			v, err := r.varint()
+			initializer.Dims = append(initializer.Dims, int64(v))
+			return err
+		case num == tensorDims && typ == protowire.BytesType:
+			data, err := r.message()
+			if err != nil {
+				return err
+			}
+			for len(data) > 0 {
+				v, n := protowire.ConsumeVarint(data)
+				if n < 0 {
+					return protowire.ParseError(n)
+				}
+				initializer.Dims = append(initializer.Dims, int64(v))
+				data = data[n:]
+			}
+			return nil
+		case num == tensorDataType && typ == protowire.VarintType:
+			v, err := r.varint()
+			initializer.Type = models.DataType(v)
+			return err
+		case num == tensorName && typ == protowire.BytesType:
+			return r.str(&initializer.Name)
+		case num == tensorRawData && typ == protowire.BytesType:
+			n, err := r.length()
+			if err != nil {
+				return err
+			}
+			raw = n
+			return r.skip(n)
+		case num == tensorExternalData && typ == protowire.BytesType:
+			data, err := r.message()
+			if err != nil {
+				return err
+			}
+			key, value, err := parseEntry(data)
+			if err == nil && key == "length" {
+				external, err = strconv.ParseInt(value, 10, 64)
+			}
+			return err
+		case num == tensorDataLocation && typ == protowire.VarintType:
+			v, err := r.varint()
+			initializer.External = v == dataLocationExternal
+			return err
+		}
+		return r.skipField(typ)
+	})
+
+	switch {
+	case raw >= 0:
+		initializer.Bytes = raw
+	case initializer.External && external >= 0:
+		initializer.Bytes = external
+	default:
+		// Dados em campos tipados ou externos sem tamanho: vale o declarado
+		count := int64(1)
+		for _, dim := range initializer.Dims {
+			count *= dim
+		}
+		initializer.Bytes = count * initializer.Type.Size()
+	}
+	return initializer, err
+}
+
+// parseOpset lê um OperatorSetIdProto
+func parseOpset(data []byte) (models.Opset, error) {
+	var opset models.Opset
+	err := consumeFields(data, func(num protowire.Number, typ protowire.Type, value []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(value)
+			opset.Domain = string(v)
+			return n, nil
+		case num == 2 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(value)
+			opset.Version = int64(v)
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, value), nil
+	})
+	return opset, err
+}
+
+// parseEntry lê um StringStringEntryProto
+func parseEntry(data []byte) (key, value string, err error) {
+	err = consumeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) (int, error) {
+		if typ == protowire.BytesType && (num == 1 || num == 2) {
+			v, n := protowire.ConsumeBytes(data)
+			if num == 1 {
+				key = string(v)
+			} else {
+				value = string(v)
+			}
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, data), nil
+	})
+	return key, value, err
+}
+
+// parseValueInfo lê um ValueInfoProto; tipos que não são tensores ficam
+// com o tipo indefinido e sem dimensões
+func parseValueInfo(data []byte) (models.TensorInfo, error) {
+	var tensor models.TensorInfo
+	err := consumeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) (int, error) {
+		if typ != protowire.BytesType {
+			return protowire.ConsumeFieldValue(num, typ, data), nil
+		}
+		v, n := protowire.ConsumeBytes(data)
+		switch num {
+		case 1:
+			tensor.Name = string(v)
+		case 2:
+			// TypeProto: só o tensor_type interessa
+			return n, consumeFields(v, func(num protowire.Number, typ protowire.Type, data []byte) (int, error) {
+				if num != 1 || typ != protowire.BytesType {
+					return protowire.ConsumeFieldValue(num, typ, data), nil
+				}
+				v, n := protowire.ConsumeBytes(data)
+				return n, parseTensorType(v, &tensor)
+			})
+		}
+		return n, nil
+	})
+	return tensor, err
+}
+
+// parseTensorType lê um TypeProto.Tensor
+func parseTensorType(data []byte, tensor *models.TensorInfo) error {
+	return consumeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			tensor.Type = models.DataType(v)
+			return n, nil
+		case num == 2 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			// Um shape sem dimensões é um escalar; sem shape o posto é desconhecido
+			tensor.Shape = []models.Dim{}
+			return n, consumeFields(v, func(num protowire.Number, typ protowire.Type, data []byte) (int, error) {
+				if num != 1 || typ != protowire.BytesType {
+					return protowire.ConsumeFieldValue(num, typ, data), nil
+				}
+				v, n := protowire.ConsumeBytes(data)
+				dim, err := parseDim(v)
+				tensor.Shape = append(tensor.Shape, dim)
+				return n, err
+			})
+		}
+		return protowire.ConsumeFieldValue(num, typ, data), nil
+	})
+}
+
+// parseDim lê um TensorShapeProto.Dimension; sem dim_value ela é dinâmica
+func parseDim(data []byte) (models.Dim, error) {
+	dim := models.Dim{Dynamic: true}
+	err := consumeFields(data, func(num protowire.Number, typ protowire.Type, data []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			dim.Value, dim.Dynamic = int64(v), false
+			return n, nil
+		case num == 2 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			dim.Param = string(v)
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, data), nil
+	})
+	return dim, err
+}
+
+// consumeFields percorre os campos de uma mensagem em memória; consume
+// recebe o valor de cada campo e retorna quantos bytes ele ocupa
+func consumeFields(data []byte, consume func(num protowire.Number, typ protowire.Type, value []byte) (int, error)) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		n, err := consume(num, typ, data)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// reader lê os campos de um arquivo protobuf em sequência, guardando a
+// posição para que campos grandes sejam pulados com Seek
+type reader struct {
+	file *os.File
+	buf  *bufio.Reader
+	pos  int64
+}
+
+// fields chama field para cada campo até a posição end, com o reader
+// posicionado no valor do campo; field precisa consumir o valor inteiro
+func (r *reader) fields(end int64, field func(num protowire.Number, typ protowire.Type) error) error {
+	for r.pos < end {
+		v, err := r.varint()
+		if err != nil {
+			return err
+		}
+		num, typ := protowire.DecodeTag(v)
+		if num < protowire.MinValidNumber {
+			return fmt.Errorf("campo inválido %d na posição %d", num, r.pos)
+		}
+		if err := field(num, typ); err != nil {
+			return err
+		}
+	}
+	if r.pos != end {
+		return fmt.Errorf("mensagem termina em %d, esperado %d", r.pos, end)
+	}
+	return nil
+}
+
+// ReadByte implementa io.ByteReader contando a posição
+func (r *reader) ReadByte() (byte, error) {
+	b, err := r.buf.ReadByte()
+	if err == nil {
+		r.pos++
+	}
+	return b, err
+}
+
+// varint lê um varint
+func (r *reader) varint() (uint64, error) {
+	v, err := binary.ReadUvarint(r)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return v, err
+}
+
+// length lê o tamanho de um campo delimitado
+func (r *reader) length() (int64, error) {
+	n, err := r.varint()
+	if err != nil {
+		return 0, err
+	}
+	if n > 1<<62 {
+		return 0, fmt.Errorf("tamanho de campo inválido: %d", n)
+	}
+	return int64(n), nil
+}
+
+// message lê um campo delimitado inteiro para a memória
+func (r *reader) message() ([]byte, error) {
+	n, err := r.length()
+	if err != nil {
+		return nil, err
+	}
+	if n > maxInlineMessage {
+		return nil, fmt.Errorf("campo de %d bytes na posição %d excede o limite", n, r.pos)
+	}
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r.buf, data); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	r.pos += n
+	return data, nil
+}
+
+// str lê um campo string em s
+func (r *reader) str(s *string) error {
+	data, err := r.message()
+	*s = string(data)
+	return err
+}
+
+// skip pula n bytes, com Seek quando eles não estão no buffer
+func (r *reader) skip(n int64) error {
+	if n <= int64(r.buf.Buffered()) {
+		_, err := r.buf.Discard(int(n))
+		r.pos += n
+		return err
+	}
+	if _, err := r.file.Seek(r.pos+n, io.SeekStart); err != nil {
+		return err
+	}
+	r.buf.Reset(r.file)
+	r.pos += n
+	return nil
+}
+
+// skipField pula o valor de um campo que não interessa
+func (r *reader) skipField(typ protowire.Type) error {
+	switch typ {
+	case protowire.VarintType:
+		_, err := r.varint()
+		return err
+	case protowire.Fixed32Type:
+		return r.skip(4)
+	case protowire.Fixed64Type:
+		return r.skip(8)
+	case protowire.BytesType:
+		n, err := r.length()
+		if err != nil {
+			return err
+		}
+		return r.skip(n)
+	}
+	return fmt.Errorf("tipo de campo não suportado %d na posição %d", typ, r.pos)
+}
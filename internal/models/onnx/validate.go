@@ -0,0 +1,135 @@
+package onnx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/josealecrim/audiototext/internal/models"
+)
+
+// Faixas suportadas pelo ONNX Runtime empacotado
+const (
+	minIRVersion = 3
+	maxIRVersion = 10
+	minOpset     = 7
+	maxOpset     = 21
+)
+
+// featureRank é o posto da entrada de cada tipo de feature: [lote, mels,
+// quadros] para log-mel e [lote, amostras] para o áudio bruto
+var featureRank = map[models.FeatureType]int{
+	models.FeatureLogMel:   3,
+	models.FeatureWaveform: 2,
+}
+
+// Validate confere o grafo de um modelo do tipo modelType com o runtime e
+// com o que o manifesto declara; manifest pode ser nil. Todos os problemas
+// encontrados são retornados juntos.
+func Validate(graph *models.Graph, manifest *models.Manifest, modelType models.ModelType) error {
+	var problems []error
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	if graph.IRVersion < minIRVersion || graph.IRVersion > maxIRVersion {
+		fail("versão IR %d fora da faixa suportada %d-%d", graph.IRVersion, minIRVersion, maxIRVersion)
+	}
+	opset, hasOpset := graph.Opset("")
+	switch {
+	case !hasOpset:
+		fail("modelo não importa o opset ai.onnx")
+	case opset < minOpset || opset > maxOpset:
+		fail("opset %d fora da faixa suportada %d-%d", opset, minOpset, maxOpset)
+	}
+
+	if len(graph.Inputs) == 0 {
+		fail("grafo sem entradas")
+	}
+	if len(graph.Outputs) == 0 {
+		fail("grafo sem saídas")
+	}
+	names := make(map[string]bool)
+	for _, tensor := range append(append([]models.TensorInfo(nil), graph.Inputs...), graph.Outputs...) {
+		switch {
+		case tensor.Name == "":
+			fail("tensor sem nome no grafo")
+		case names[tensor.Name]:
+			fail("tensor %s repetido no grafo", tensor.Name)
+		}
+		names[tensor.Name] = true
+	}
+
+	feature := models.FeatureLogMel
+	if modelType == models.TypeCTC {
+		feature = models.FeatureWaveform
+	}
+	if manifest != nil && manifest.FeatureType != "" {
+		feature = manifest.FeatureType
+	}
+	if rank, ok := featureRank[feature]; ok && len(graph.Inputs) > 0 && !hasFeatureInput(graph.Inputs, rank) {
+		fail("nenhuma entrada float de posto %d para %s", rank, feature)
+	}
+
+	if manifest != nil {
+		if manifest.Opset != 0 && hasOpset && manifest.Opset != opset {
+			fail("manifesto declara opset %d, grafo usa %d", manifest.Opset, opset)
+		}
+		for _, spec := range manifest.Inputs {
+			tensor, found := graph.Input(spec.Name)
+			checkSpec(tensor, found, spec, "entrada", fail)
+		}
+		for _, spec := range manifest.Outputs {
+			tensor, found := graph.Output(spec.Name)
+			checkSpec(tensor, found, spec, "saída", fail)
+		}
+		if value, ok := graph.Metadata["sample_rate"]; ok && manifest.SampleRate != 0 {
+			if rate, err := strconv.Atoi(value); err == nil && rate != manifest.SampleRate {
+				fail("manifesto declara taxa de amostragem %d, grafo declara %d", manifest.SampleRate, rate)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidModel, errors.Join(problems...))
+	}
+	return nil
+}
+
+// hasFeatureInput indica uma entrada float com o posto dado; entradas de
+// posto desconhecido são aceitas
+func hasFeatureInput(inputs []models.TensorInfo, rank int) bool {
+	for _, input := range inputs {
+		if input.Type.IsFloat() && (input.Shape == nil || len(input.Shape) == rank) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSpec confere um tensor declarado no manifesto com o do grafo, que
+// found indica existir
+func checkSpec(tensor models.TensorInfo, found bool, spec models.TensorSpec, kind string, fail func(string, ...interface{})) {
+	if !found {
+		fail("%s %s declarada no manifesto não existe no grafo", kind, spec.Name)
+		return
+	}
+
+	if spec.DType != "" {
+		if dataType, err := models.ParseDataType(spec.DType); err == nil && dataType != tensor.Type {
+			fail("%s %s: manifesto declara %s, grafo usa %s", kind, spec.Name, dataType, tensor.Type)
+		}
+	}
+	if len(spec.Shape) == 0 || tensor.Shape == nil {
+		return
+	}
+	if len(spec.Shape) != len(tensor.Shape) {
+		fail("%s %s: manifesto declara posto %d, grafo tem %s", kind, spec.Name, len(spec.Shape), tensor)
+		return
+	}
+	for i, dim := range spec.Shape {
+		if dim >= 0 && !tensor.Shape[i].Dynamic && tensor.Shape[i].Value != dim {
+			fail("%s %s: manifesto declara dimensão %d = %d, grafo tem %s", kind, spec.Name, i, dim, tensor)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/models"
+)
+
+// aliasesFile guarda os apelidos fixados no diretório do registro
+const aliasesFile = "aliases.json"
+
+// maxAliasHistory é quantos alvos anteriores cada apelido guarda para rollback
+const maxAliasHistory = 10
+
+// aliasName aceita nomes de apelidos; "@" fica reservado para as versões
+var aliasName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+
+// resolve encontra o modelo pedido por ref, que pode ser o ID exato de um
+// modelo, como "whisper-base@1.3.0", um apelido fixado, como "default", ou
+// uma família, como "whisper-base", que aponta para sua versão estável
+// mais recente; chamado com r.mu
+func (r *Registry) resolve(ref string) (*models.ONNXModel, error) {
+	if model, exists := r.handles[ref]; exists {
+		return model, nil
+	}
+
+	if alias, exists := r.aliases[ref]; exists {
+		model, exists := r.handles[alias.Target]
+		if !exists {
+			return nil, fmt.Errorf("%w: %s, alvo do apelido %s", models.ErrNotRegistered, alias.Target, ref)
+		}
+		return model, nil
+	}
+
+	if versions := r.versions(ref); len(versions) > 0 {
+		return versions[0], nil
+	}
+	return nil, fmt.Errorf("%w: %s", models.ErrNotRegistered, ref)
+}
+
+// versions retorna as versões estáveis registradas de uma família, da mais
+// recente para a mais antiga; chamado com r.mu
+func (r *Registry) versions(family string) []*models.ONNXModel {
+	type versioned struct {
+		model   *models.ONNXModel
+		version models.Version
+	}
+
+	var found []versioned
+	for modelID, model := range r.handles {
+		name, raw := models.SplitID(modelID)
+		if name != family || raw == "" {
+			continue
+		}
+		version, err := models.ParseVersion(raw)
+		if err != nil || !version.Stable() {
+			continue
+		}
+		found = append(found, versioned{model, version})
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].version.Compare(found[j].version) > 0
+	})
+
+	list := make([]*models.ONNXModel, len(found))
+	for i, v := range found {
+		list[i] = v.model
+	}
+	return list
+}
+
+// Aliases retorna os apelidos fixados e os implícitos das famílias com
+// versões registradas, ordenados por nome
+func (r *Registry) Aliases() []models.Alias {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]models.Alias, 0, len(r.aliases))
+	for _, alias := range r.aliases {
+		alias.Previous = append([]string(nil), alias.Previous...)
+		list = append(list, alias)
+	}
+
+	families := make(map[string]bool)
+	for modelID := range r.handles {
+		if family, version := models.SplitID(modelID); version != "" {
+			families[family] = true
+		}
+	}
+	for family := range families {
+		if _, pinned := r.aliases[family]; pinned {
+			continue
+		}
+		if versions := r.versions(family); len(versions) > 0 {
+			list = append(list, models.Alias{Name: family, Target: versions[0].ID, Implicit: true})
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Alias retorna um apelido, fixado ou implícito
+func (r *Registry) Alias(name string) (models.Alias, error) {
+	for _, alias := range r.Aliases() {
+		if alias.Name == name {
+			return alias, nil
+		}
+	}
+	return models.Alias{}, fmt.Errorf("%w: apelido %s", models.ErrNotRegistered, name)
+}
+
+// SetAlias aponta um apelido para um modelo registrado, guardando o alvo
+// anterior para rollback. A troca é atômica: cada pedido resolve o apelido
+// para o alvo antigo ou para o novo, e os apelidos vão para o disco antes
+// de valer
+func (r *Registry) SetAlias(name, target string) (models.Alias, error) {
+	if !aliasName.MatchString(name) {
+		return models.Alias{}, fmt.Errorf("nome de apelido inválido: %q", name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.handles[name]; exists {
+		return models.Alias{}, fmt.Errorf("apelido %s é o ID de um modelo registrado", name)
+	}
+	if _, exists := r.handles[target]; !exists {
+		return models.Alias{}, fmt.Errorf("%w: %s", models.ErrNotRegistered, target)
+	}
+
+	alias := r.aliases[name]
+	alias.Name = name
+	alias.Previous = append([]string(nil), alias.Previous...)
+	if current, err := r.resolve(name); err == nil && current.ID != target {
+		alias.Previous = append([]string{current.ID}, alias.Previous...)
+		if len(alias.Previous) > maxAliasHistory {
+			alias.Previous = alias.Previous[:maxAliasHistory]
+		}
+	}
+	alias.Target = target
+	alias.Updated = time.Now()
+	return alias, r.putAlias(alias)
+}
+
+// RollbackAlias volta um apelido para o alvo anterior. Um apelido de
+// família sem histórico volta para a versão estável anterior à atual
+func (r *Registry) RollbackAlias(name string) (models.Alias, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.resolve(name)
+	if err != nil {
+		return models.Alias{}, err
+	}
+
+	alias, pinned := r.aliases[name]
+	if pinned && len(alias.Previous) > 0 {
+		previous := alias.Previous[0]
+		if _, exists := r.handles[previous]; !exists {
+			return models.Alias{}, fmt.Errorf("versão anterior do apelido %s: %w: %s", name, models.ErrNotRegistered, previous)
+		}
+		alias.Target = previous
+		alias.Previous = append([]string(nil), alias.Previous[1:]...)
+		alias.Updated = time.Now()
+		return alias, r.putAlias(alias)
+	}
+
+	// Sem histórico, a versão anterior é a da família do alvo atual
+	family, _ := models.SplitID(current.ID)
+	versions := r.versions(family)
+	for i, model := range versions {
+		if model.ID == current.ID && i+1 < len(versions) {
+			alias = models.Alias{Name: name, Target: versions[i+1].ID, Updated: time.Now()}
+			return alias, r.putAlias(alias)
+		}
+	}
+	return models.Alias{}, fmt.Errorf("apelido %s não tem versão anterior a %s", name, current.ID)
+}
+
+// RemoveAlias apaga um apelido fixado; o nome de uma família volta a
+// seguir sua versão estável mais recente
+func (r *Registry) RemoveAlias(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.aliases[name]; !exists {
+		return fmt.Errorf("%w: apelido %s", models.ErrNotRegistered, name)
+	}
+
+	aliases := r.copyAliases()
+	delete(aliases, name)
+	if err := r.saveAliases(aliases); err != nil {
+		return err
+	}
+	r.aliases = aliases
+	return nil
+}
+
+// putAlias grava um apelido e só então passa a usá-lo; chamado com r.mu
+func (r *Registry) putAlias(alias models.Alias) error {
+	aliases := r.copyAliases()
+	aliases[alias.Name] = alias
+	if err := r.saveAliases(aliases); err != nil {
+		return err
+	}
+	r.aliases = aliases
+	return nil
+}
+
+// copyAliases retorna uma cópia dos apelidos fixados; chamado com r.mu
+func (r *Registry) copyAliases() map[string]models.Alias {
+	aliases := make(map[string]models.Alias, len(r.aliases)+1)
+	for name, alias := range r.aliases {
+		aliases[name] = alias
+	}
+	return aliases
+}
+
+// loadAliases lê os apelidos fixados do disco
+func (r *Registry) loadAliases() error {
+	data, err := os.ReadFile(filepath.Join(r.config.CachePath, aliasesFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao ler apelidos: %w", err)
+	}
+
+	var list []models.Alias
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("apelidos inválidos: %w", err)
+	}
+	for _, alias := range list {
+		r.aliases[alias.Name] = alias
+	}
+	return nil
+}
+
+// saveAliases grava os apelidos fixados substituindo o arquivo inteiro de
+// uma vez, para que uma falha no meio não o deixe pela metade
+func (r *Registry) saveAliases(aliases map[string]models.Alias) error {
+	list := make([]models.Alias, 0, len(aliases))
+	for _, alias := range aliases {
+		list = append(list, alias)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	data, err := json.MarshalIndent(list, "", "    ")
+	if err != nil {
+		return fmt.Errorf("erro ao serializar apelidos: %w", err)
+	}
+
+	path := filepath.Join(r.config.CachePath, aliasesFile)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("erro ao salvar apelidos: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("erro ao salvar apelidos: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,589 @@
+// Package registry é o registro único dos modelos em disco. Ele junta o
+// índice do cache e os downloads e entrega handles tipados dos modelos ao
+// servidor, à inferência e às ferramentas de linha de comando.
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/internal/models/cache"
+	"github.com/josealecrim/audiototext/internal/models/download"
+	"github.com/josealecrim/audiototext/internal/models/onnx"
+)
+
+// ctcConfigFile marca os diretórios de modelos CTC; é o mesmo arquivo lido pela inferência
+const ctcConfigFile = "ctc.json"
+
+// Registry guarda os modelos de um diretório, um subdiretório por modelo
+// com o arquivo de pesos e seus arquivos auxiliares
+type Registry struct {
+	config    models.Config
+	cache     *cache.Manager
+	downloads *download.Manager
+
+	mu sync.RWMutex
+	// handles mapeia IDs de modelos para seus handles
+	handles map[string]*models.ONNXModel
+	// aliases mapeia os apelidos fixados para seus alvos; é trocado
+	// inteiro a cada mudança, depois de gravado em disco
+	aliases map[string]models.Alias
+	// inUse indica se um modelo tem sessões de inferência abertas
+	inUse func(modelID string) bool
+	// onReplace é avisada quando um modelo passa a ser servido por um novo handle
+	onReplace func(*models.ONNXModel) error
+	// invalid mapeia IDs de modelos que não são servidos, encontrados ao
+	// abrir o registro ou rejeitados por Verify, para o motivo
+	invalid map[string]error
+}
+
+// New abre o registro em config.CachePath, carregando os modelos do índice
+// e adotando os diretórios de modelos que ainda não estão nele
+func New(config models.Config) (*Registry, error) {
+	if err := os.MkdirAll(config.CachePath, 0755); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório de modelos: %w", err)
+	}
+
+	cacheManager, err := cache.NewManager(config)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir índice de modelos: %w", err)
+	}
+
+	downloadManager, err := download.NewManager(config)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao configurar origens de modelos: %w", err)
+	}
+
+	r := &Registry{
+		config:    config,
+		cache:     cacheManager,
+		downloads: downloadManager,
+		handles:   make(map[string]*models.ONNXModel),
+		aliases:   make(map[string]models.Alias),
+		invalid:   make(map[string]error),
+	}
+	if err := r.loadAliases(); err != nil {
+		return nil, err
+	}
+
+	// Cada download concluído é registrado uma vez, mesmo com vários interessados
+	downloadManager.SetOnComplete(func(model *models.Model) error {
+		_, err := r.Register(model.Info)
+		return err
+	})
+
+	// Modelos do índice cujos arquivos sumiram ou cujo manifesto ou grafo é inválido não são servidos
+	for _, info := range cacheManager.List() {
+		model, err := cacheManager.Load(info.ID)
+		if err == nil {
+			err = describe(model)
+		}
+		if err == nil {
+			err = inspect(model)
+		}
+		if err != nil {
+			r.invalid[info.ID] = err
+			continue
+		}
+		r.handles[info.ID] = newHandle(model)
+	}
+
+	if err := r.scan(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetModel retorna o handle de um modelo e o marca como usado. ref pode
+// ser o ID exato, que fixa a versão, um apelido ou o nome de uma família;
+// o handle retornado tem o ID e a versão resolvidos
+func (r *Registry) GetModel(ref string) (*models.ONNXModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	model, err := r.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.Touch(model.ID)
+	return model, nil
+}
+
+// ListModels retorna os handles de todos os modelos ordenados por ID
+func (r *Registry) ListModels() ([]*models.ONNXModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]*models.ONNXModel, 0, len(r.handles))
+	for _, model := range r.handles {
+		list = append(list, model)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// Dir retorna o diretório de um modelo
+func (r *Registry) Dir(modelID string) string {
+	return r.cache.Dir(modelID)
+}
+
+// Register registra um modelo cujos arquivos já estão em Dir(info.ID),
+// calculando o tamanho e o checksum do arquivo de pesos. Um ID com versão,
+// como "whisper-base@1.3.0", define a versão do modelo, que precisa ser
+// semântica. Se o manifesto declara outro checksum, o modelo vai para a
+// quarentena
+func (r *Registry) Register(info models.ModelInfo) (*models.ONNXModel, error) {
+	if info.ID == "" {
+		return nil, fmt.Errorf("modelo sem ID")
+	}
+	if _, raw := models.SplitID(info.ID); raw != "" {
+		version, err := models.ParseVersion(raw)
+		if err != nil {
+			return nil, fmt.Errorf("modelo %s: %w", info.ID, err)
+		}
+		if info.Version != "" && info.Version != raw && info.Version != version.String() {
+			return nil, fmt.Errorf("modelo %s registrado com outra versão: %s", info.ID, info.Version)
+		}
+		info.Version = version.String()
+	}
+	if info.Format == "" {
+		info.Format = models.FormatONNX
+	}
+
+	dir := r.Dir(info.ID)
+	path := filepath.Join(dir, info.Format.FileName())
+	size, checksum, err := models.FileChecksum(path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler pesos do modelo %s: %w", info.ID, err)
+	}
+	info.Size = size
+	info.Checksum = checksum
+	info.Verified = time.Now()
+
+	if info.Type == "" {
+		info.Type = detectType(dir)
+	}
+	if info.Capabilities.IsZero() {
+		info.Capabilities = models.DefaultCapabilities(info.Type)
+	}
+
+	model := &models.Model{Info: info, Path: path}
+	if err := describe(model); err != nil {
+		return nil, fmt.Errorf("modelo %s: %w", info.ID, err)
+	}
+	if model.Manifest != nil && model.Manifest.SHA256 != "" && model.Manifest.SHA256 != checksum {
+		err := fmt.Errorf("%w: esperado %s, obtido %s", models.ErrChecksumMismatch, model.Manifest.SHA256, checksum)
+		if _, qerr := r.cache.Quarantine(info.ID, err.Error()); qerr != nil {
+			return nil, fmt.Errorf("modelo %s: %v; %w", info.ID, err, qerr)
+		}
+		return nil, fmt.Errorf("modelo %s movido para a quarentena: %w", info.ID, err)
+	}
+	if err := inspect(model); err != nil {
+		return nil, fmt.Errorf("modelo %s: %w", info.ID, err)
+	}
+	if err := r.cache.Store(model); err != nil {
+		return nil, fmt.Errorf("erro ao registrar modelo %s: %w", info.ID, err)
+	}
+
+	handle := newHandle(model)
+	r.serve(handle)
+	return handle, nil
+}
+
+// Download baixa um modelo com a prioridade padrão e o registra
+func (r *Registry) Download(ctx context.Context, modelType models.ModelType, version string) (*models.ONNXModel, error) {
+	return r.Fetch(ctx, download.Request{Type: modelType, Version: version})
+}
+
+// Fetch baixa um modelo pela fila de downloads e o registra; pedidos
+// simultâneos do mesmo modelo esperam o mesmo download
+func (r *Registry) Fetch(ctx context.Context, req download.Request) (*models.ONNXModel, error) {
+	model, err := r.downloads.Fetch(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao baixar modelo %s@%s: %w", req.Type, req.Version, err)
+	}
+	return r.GetModel(model.Info.ID)
+}
+
+// Enqueue põe o download de um modelo na fila sem esperar por ele; o
+// modelo é registrado quando o download termina
+func (r *Registry) Enqueue(req download.Request) (*download.Job, error) {
+	return r.downloads.Enqueue(req)
+}
+
+// CancelDownload cancela o download de um modelo na fila ou em andamento
+func (r *Registry) CancelDownload(modelID string) error {
+	return r.downloads.Cancel(modelID)
+}
+
+// Progress retorna o progresso do download de um modelo
+func (r *Registry) Progress(modelID string) (models.Progress, error) {
+	return r.downloads.GetProgress(modelID)
+}
+
+// Remove apaga um modelo do disco e do registro; como na coleta de lixo,
+// um modelo protegido por protection não é apagado
+func (r *Registry) Remove(modelID string) error {
+	if err := r.cache.Remove(modelID, r.protection()); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	delete(r.handles, modelID)
+	r.mu.Unlock()
+	return nil
+}
+
+// protection retorna uma função que diz por que um modelo não pode ser
+// apagado, ou vazio se pode: ele tem sessões de inferência abertas ou é o
+// alvo de um apelido fixado, atual ou guardado para rollback. Os apelidos
+// implícitos das famílias não contam: seguem a versão mais recente que
+// sobrar, e protegê-los deixaria toda família fora da política de retenção
+func (r *Registry) protection() func(modelID string) string {
+	r.mu.RLock()
+	inUse := r.inUse
+	r.mu.RUnlock()
+
+	// Apagar um alvo quebraria o apelido, ou o rollback para ele
+	var aliases []models.Alias
+	for _, alias := range r.Aliases() {
+		if !alias.Implicit {
+			aliases = append(aliases, alias)
+		}
+	}
+	targets := make(map[string]string)
+	for _, alias := range aliases {
+		for _, previous := range alias.Previous {
+			if _, exists := targets[previous]; !exists {
+				targets[previous] = "alvo anterior do apelido " + alias.Name
+			}
+		}
+	}
+	// Um alvo atual prevalece sobre o histórico de outro apelido
+	for _, alias := range aliases {
+		targets[alias.Target] = "alvo do apelido " + alias.Name
+	}
+
+	return func(modelID string) string {
+		if inUse != nil && inUse(modelID) {
+			return "em uso por uma sessão de inferência"
+		}
+		return targets[modelID]
+	}
+}
+
+// SetInUse define como o registro sabe se um modelo tem sessões de
+// inferência abertas; esses modelos nunca são apagados pela coleta de lixo
+func (r *Registry) SetInUse(inUse func(modelID string) bool) {
+	r.mu.Lock()
+	r.inUse = inUse
+	r.mu.Unlock()
+}
+
+// SetOnReplace define uma função chamada quando um modelo registrado ou
+// restaurado passa a ser servido por um novo handle, para que quem guarda
+// o handle anterior, como os pools de sessões, passe a usar o novo
+func (r *Registry) SetOnReplace(onReplace func(*models.ONNXModel) error) {
+	r.mu.Lock()
+	r.onReplace = onReplace
+	r.mu.Unlock()
+}
+
+// Clean apaga os modelos fora da política, exceto os protegidos por
+// protection, e os tira do registro; retorna a decisão sobre cada modelo
+// fora da política
+func (r *Registry) Clean(policy models.CleanPolicy) ([]cache.Decision, error) {
+	decisions, err := r.cache.Clean(policy, r.protection())
+
+	r.mu.Lock()
+	for _, decision := range decisions {
+		if decision.Evicted {
+			delete(r.handles, decision.ModelID)
+		}
+	}
+	r.mu.Unlock()
+	return decisions, err
+}
+
+// Collect aplica a política de retenção da configuração; veja Clean
+func (r *Registry) Collect() ([]cache.Decision, error) {
+	return r.Clean(r.cache.Policy())
+}
+
+// CollectEvery chama Collect a cada interval até ctx terminar, passando
+// as decisões e o erro de cada rodada para report
+func (r *Registry) CollectEvery(ctx context.Context, interval time.Duration, report func([]cache.Decision, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			decisions, err := r.Collect()
+			if report != nil && (len(decisions) > 0 || err != nil) {
+				report(decisions, err)
+			}
+		}
+	}
+}
+
+// Verify confere de novo o checksum de todos os modelos registrados, mesmo
+// os que não mudaram. Os corrompidos vão para a quarentena e deixam de ser
+// servidos; são retornados com o motivo
+func (r *Registry) Verify() map[string]error {
+	r.mu.RLock()
+	ids := make([]string, 0, len(r.handles))
+	for modelID := range r.handles {
+		ids = append(ids, modelID)
+	}
+	r.mu.RUnlock()
+
+	failed := make(map[string]error)
+	for _, modelID := range ids {
+		if err := r.cache.Verify(modelID); err != nil {
+			failed[modelID] = err
+		}
+	}
+
+	r.mu.Lock()
+	for modelID, err := range failed {
+		delete(r.handles, modelID)
+		r.invalid[modelID] = err
+	}
+	r.mu.Unlock()
+	return failed
+}
+
+// VerifyEvery chama Verify a cada interval até ctx terminar, passando os
+// modelos rejeitados em cada rodada para report
+func (r *Registry) VerifyEvery(ctx context.Context, interval time.Duration, report func(map[string]error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if failed := r.Verify(); len(failed) > 0 && report != nil {
+				report(failed)
+			}
+		}
+	}
+}
+
+// CreateBackup guarda um snapshot dos arquivos de um modelo, assinado por
+// createdBy com o motivo dado, e apaga os backups fora da política de
+// retenção da configuração
+func (r *Registry) CreateBackup(modelID, createdBy, reason string) (cache.Backup, error) {
+	backup, err := r.cache.CreateBackup(modelID, createdBy, reason)
+	if err != nil {
+		return cache.Backup{}, fmt.Errorf("erro ao criar backup do modelo %s: %w", modelID, err)
+	}
+	if _, err := r.PruneBackups(); err != nil {
+		return backup, err
+	}
+	return backup, nil
+}
+
+// ListBackups lista os backups de um modelo, do mais recente para o mais antigo
+func (r *Registry) ListBackups(modelID string) ([]cache.Backup, error) {
+	return r.cache.ListBackups(modelID)
+}
+
+// PruneBackups apaga os backups fora da política de retenção da
+// configuração e retorna os apagados
+func (r *Registry) PruneBackups() ([]cache.Backup, error) {
+	pruned, err := r.cache.PruneBackups(r.cache.BackupPolicy())
+	if err != nil {
+		return pruned, fmt.Errorf("erro ao limpar backups: %w", err)
+	}
+	return pruned, nil
+}
+
+// RestoreBackup devolve um modelo à versão de um backup e passa a servir
+// a versão restaurada. O backup é conferido como um modelo novo antes de
+// substituir os arquivos, então um backup inválido deixa a versão atual
+// servindo. Quem já tem o handle anterior, como as sessões de inferência
+// abertas, continua com ele; os próximos GetModel recebem o novo
+func (r *Registry) RestoreBackup(modelID, backupID, restoredBy string) (*models.ONNXModel, error) {
+	var model *models.Model
+	_, err := r.cache.RestoreBackup(modelID, backupID, restoredBy, func(staged *models.Model) error {
+		if err := describe(staged); err != nil {
+			return err
+		}
+		if err := inspect(staged); err != nil {
+			return err
+		}
+		model = staged
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// O modelo foi conferido fora do cache; os arquivos agora estão no lugar
+	model.Path = filepath.Join(r.Dir(modelID), model.Info.Format.FileName())
+	handle := newHandle(model)
+	r.serve(handle)
+	return handle, nil
+}
+
+// serve passa a servir handle no lugar do handle anterior do modelo, se
+// houver, e avisa onReplace para que as sessões de inferência troquem de
+// versão
+func (r *Registry) serve(handle *models.ONNXModel) {
+	r.mu.Lock()
+	r.handles[handle.ID] = handle
+	delete(r.invalid, handle.ID)
+	onReplace := r.onReplace
+	r.mu.Unlock()
+
+	if onReplace != nil {
+		// O erro só afeta as sessões da versão substituída, que saem de uso
+		_ = onReplace(handle)
+	}
+}
+
+// Size retorna o tamanho dos pesos de todos os modelos registrados
+func (r *Registry) Size() int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var size int64
+	for _, model := range r.handles {
+		size += model.Info.Size
+	}
+	return size
+}
+
+// scan registra os diretórios com arquivo de pesos que não estão no
+// índice; diretórios ocultos, como a quarentena e os backups, são ignorados
+func (r *Registry) scan() error {
+	var found []models.ModelInfo
+	err := filepath.WalkDir(r.config.CachePath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != r.config.CachePath && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		var format models.Format
+		switch entry.Name() {
+		case models.FormatONNX.FileName():
+			format = models.FormatONNX
+		case models.FormatORT.FileName():
+			format = models.FormatORT
+		default:
+			return nil
+		}
+
+		rel, err := filepath.Rel(r.config.CachePath, filepath.Dir(path))
+		if err != nil || rel == "." {
+			return nil
+		}
+		modelID := filepath.ToSlash(rel)
+		if _, exists := r.handles[modelID]; !exists {
+			found = append(found, models.ModelInfo{ID: modelID, Format: format, Downloaded: fileTime(path)})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao examinar diretório de modelos: %w", err)
+	}
+
+	for _, info := range found {
+		if _, err := r.Register(info); err != nil {
+			r.invalid[info.ID] = err
+		}
+	}
+	return nil
+}
+
+// Invalid retorna os modelos que não são servidos, encontrados ao abrir o
+// registro ou rejeitados por Verify, e o motivo de cada um
+func (r *Registry) Invalid() map[string]error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	invalid := make(map[string]error, len(r.invalid))
+	for modelID, err := range r.invalid {
+		invalid[modelID] = err
+	}
+	return invalid
+}
+
+// describe carrega e valida o manifesto de um modelo, que prevalece sobre
+// o tipo e as capacidades deduzidos
+func describe(model *models.Model) error {
+	dir := filepath.Dir(model.Path)
+	manifest, err := models.LoadManifest(dir)
+	if err != nil || manifest == nil {
+		return err
+	}
+
+	if manifest.Type != "" {
+		model.Info.Type = manifest.Type
+	}
+	if err := manifest.Validate(dir, model.Info.Type); err != nil {
+		return err
+	}
+	model.Info.Capabilities = manifest.Capabilities
+	model.Manifest = manifest
+	return nil
+}
+
+// inspect lê o grafo de um modelo ONNX e o confere com o manifesto, para
+// que um modelo que o runtime não carregaria seja rejeitado antes de
+// qualquer sessão; os outros formatos não são examinados
+func inspect(model *models.Model) error {
+	if model.Info.Format != "" && model.Info.Format != models.FormatONNX {
+		return nil
+	}
+
+	graph, err := onnx.Inspect(model.Path)
+	if err != nil {
+		return err
+	}
+	if err := onnx.Validate(graph, model.Manifest, model.Info.Type); err != nil {
+		return err
+	}
+	model.Graph = graph
+	return nil
+}
+
+// newHandle cria o handle de um modelo
+func newHandle(model *models.Model) *models.ONNXModel {
+	return &models.ONNXModel{Model: model, ID: model.Info.ID}
+}
+
+// detectType deduz o tipo de um modelo pelos arquivos auxiliares
+func detectType(dir string) models.ModelType {
+	if _, err := os.Stat(filepath.Join(dir, ctcConfigFile)); err == nil {
+		return models.TypeCTC
+	}
+	return models.TypeWhisper
+}
+
+// fileTime retorna a data de modificação de um arquivo
+func fileTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -1,17 +1,80 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
 	"time"
 )
 
+// ErrNotRegistered indica um modelo ou apelido que o registro não conhece
+var ErrNotRegistered = errors.New("modelo não registrado")
+
+// ErrChecksumMismatch indica um arquivo de pesos cujo SHA-256 não confere
+var ErrChecksumMismatch = errors.New("checksum SHA-256 não confere")
+
+// ErrInUse indica um modelo que não pode ser apagado porque tem sessões de
+// inferência abertas ou é alvo de um apelido
+var ErrInUse = errors.New("modelo em uso")
+
 // ModelType representa o tipo do modelo
 type ModelType string
 
 const (
-	TypeClaude  ModelType = "claude"
+	// TypeWhisper é um modelo encoder-decoder estilo Whisper
 	TypeWhisper ModelType = "whisper"
+	// TypeCTC é um modelo acústico CTC estilo wav2vec2
+	TypeCTC ModelType = "ctc"
+)
+
+// Format é o formato do arquivo de pesos de um modelo
+type Format string
+
+const (
+	// FormatONNX é um grafo ONNX
+	FormatONNX Format = "onnx"
+	// FormatORT é um grafo no formato otimizado do ONNX Runtime
+	FormatORT Format = "ort"
 )
 
+// FileName retorna o nome do arquivo de pesos no diretório do modelo
+func (f Format) FileName() string {
+	if f == "" {
+		f = FormatONNX
+	}
+	return "model." + string(f)
+}
+
+// Capabilities descreve o que um modelo suporta
+type Capabilities struct {
+	// Streaming indica que o modelo serve transcrição em streaming
+	Streaming bool `json:"streaming"`
+	// WordTimestamps indica que o decodificador conhece os tempos das palavras
+	WordTimestamps bool `json:"word_timestamps"`
+	// Diarization indica que o modelo separa os falantes
+	Diarization bool `json:"diarization"`
+	// Translation indica que o modelo traduz a fala para o inglês
+	Translation bool `json:"translation"`
+}
+
+// IsZero indica que nenhuma capacidade foi declarada
+func (c Capabilities) IsZero() bool {
+	return c == Capabilities{}
+}
+
+// DefaultCapabilities retorna as capacidades de um tipo de modelo
+func DefaultCapabilities(modelType ModelType) Capabilities {
+	switch modelType {
+	case TypeCTC:
+		// Os quadros de emissão dão o tempo de cada palavra
+		return Capabilities{Streaming: true, WordTimestamps: true}
+	default:
+		return Capabilities{Streaming: true}
+	}
+}
+
 // ModelInfo contém informações sobre um modelo
 type ModelInfo struct {
 	ID          string    `json:"id"`
@@ -21,13 +84,26 @@ type ModelInfo struct {
 	Downloaded  time.Time `json:"downloaded"`
 	LastUsed    time.Time `json:"last_used"`
 	IsConverted bool      `json:"is_converted"`
+	// Format é o formato do arquivo de pesos
+	Format Format `json:"format"`
+	// Checksum é o SHA-256 em hexadecimal do arquivo de pesos
+	Checksum string `json:"checksum"`
+	// Verified é quando o checksum do arquivo de pesos foi conferido pela última vez
+	Verified time.Time `json:"verified"`
+	// Capabilities descreve o que o modelo suporta
+	Capabilities Capabilities `json:"capabilities"`
 }
 
 // Model representa um modelo baixado
 type Model struct {
-	Info     ModelInfo `json:"info"`
-	Path     string    `json:"path"`
-	Checksum string    `json:"checksum"`
+	Info ModelInfo `json:"info"`
+	// Path é o arquivo de pesos; os arquivos auxiliares ficam no mesmo diretório
+	Path string `json:"path"`
+	// Manifest descreve o modelo; nil quando o diretório não tem manifesto
+	Manifest *Manifest `json:"manifest,omitempty"`
+	// Graph descreve o grafo ONNX lido do arquivo de pesos; nil em outros
+	// formatos e nos modelos que o registro não examinou
+	Graph *Graph `json:"graph,omitempty"`
 }
 
 // ONNXModel extends Model to include ONNX-specific fields
@@ -56,15 +132,100 @@ type CleanPolicy struct {
 	KeepLatest int           `json:"keep_latest"`
 }
 
+// BackupPolicy define a política de retenção dos backups de cada modelo;
+// o backup mais recente de um modelo nunca é apagado
+type BackupPolicy struct {
+	// MaxAge apaga backups mais antigos que ele; 0 não apaga por idade
+	MaxAge time.Duration `json:"max_age"`
+	// MaxCount é quantos backups de cada modelo são mantidos; 0 não limita
+	MaxCount int `json:"max_count"`
+}
+
+// SourceKind é o tipo de uma origem de modelos
+type SourceKind string
+
+const (
+	// SourceHTTP baixa os modelos de um servidor HTTP(S), como o Hugging Face ou um espelho interno
+	SourceHTTP SourceKind = "http"
+	// SourceDir copia os modelos de um diretório local ou montado por NFS
+	SourceDir SourceKind = "dir"
+	// SourceS3 baixa os modelos de um bucket de um serviço compatível com S3
+	SourceS3 SourceKind = "s3"
+)
+
+// SourceConfig configura uma origem de onde os modelos são baixados.
+// URL e Path aceitam os marcadores {id}, {type}, {version}, {repo} e
+// {file}, trocados pelo ID do modelo (whisper@1.0.0), seu tipo, sua versão,
+// seu repositório no Hugging Face (openai/whisper-small-1.0.0) e o nome do
+// arquivo (model.onnx ou manifest.json)
+type SourceConfig struct {
+	// Name identifica a origem nos erros; vazio usa o tipo
+	Name string `yaml:"name"`
+	// Kind é o tipo da origem
+	Kind SourceKind `yaml:"kind"`
+	// Priority ordena as origens: as de menor prioridade são tentadas primeiro
+	Priority int `yaml:"priority"`
+	// URL é o endereço base de um servidor HTTP, o endpoint de um serviço
+	// S3 ou o diretório de uma origem local
+	URL string `yaml:"url"`
+	// Path é o caminho dos arquivos a partir de URL; vazio usa
+	// "{repo}/resolve/main/{file}" no HTTP e "{id}/{file}" nos outros tipos
+	Path string `yaml:"path"`
+	// Headers são enviados em cada request, como os de autenticação;
+	// referências a variáveis de ambiente ($VAR) são expandidas
+	Headers map[string]string `yaml:"headers"`
+	// Bucket, Region, AccessKey e SecretKey configuram origens S3; sem
+	// AccessKey os requests não são assinados. As chaves aceitam $VAR
+	Bucket    string `yaml:"bucket"`
+	Region    string `yaml:"region"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+}
+
 // Config contém a configuração do sistema de modelos
 type Config struct {
-	CachePath           string        `yaml:"cache_path"`
-	MaxCacheSize        string        `yaml:"max_cache_size"`
-	RetentionPeriod     string        `yaml:"retention_period"`
+	CachePath string `yaml:"cache_path"`
+	// Sources são as origens dos downloads; vazio usa o Hugging Face
+	Sources []SourceConfig `yaml:"sources"`
+	// MaxCacheSize limita o tamanho dos modelos em cache, como "10GB" ou
+	// "500GiB"; vazio não limita
+	MaxCacheSize string `yaml:"max_cache_size"`
+	// RetentionPeriod apaga modelos sem uso há mais tempo que ele, como
+	// "30d" ou "2w"; vazio não apaga por idade
+	RetentionPeriod string `yaml:"retention_period"`
+	// KeepLatest mantém os modelos usados mais recentemente mesmo fora da política
+	KeepLatest int `yaml:"keep_latest"`
+	// CleanInterval é o intervalo da coleta de lixo do cache; 0 a desativa
+	CleanInterval time.Duration `yaml:"clean_interval"`
+	// BackupRetention apaga backups mais antigos que ele, como "90d"; vazio
+	// mantém os backups por idade
+	BackupRetention string `yaml:"backup_retention"`
+	// KeepBackups é quantos backups de cada modelo são mantidos; 0 não limita
+	KeepBackups         int           `yaml:"keep_backups"`
 	ConcurrentDownloads int           `yaml:"concurrent_downloads"`
 	DownloadTimeout     time.Duration `yaml:"download_timeout"`
-	Retries             int           `yaml:"retries"`
-	OptimizationLevel   string        `yaml:"optimization_level"`
-	EnableQuantization  bool          `yaml:"enable_quantization"`
-	TargetPlatform      string        `yaml:"target_platform"`
+	// Retries é o número de novas tentativas de um download interrompido
+	Retries int `yaml:"retries"`
+	// RetryBackoff é a espera antes da primeira nova tentativa, dobrada a
+	// cada tentativa seguinte; 0 usa 1s
+	RetryBackoff       time.Duration `yaml:"retry_backoff"`
+	OptimizationLevel  string        `yaml:"optimization_level"`
+	EnableQuantization bool          `yaml:"enable_quantization"`
+	TargetPlatform     string        `yaml:"target_platform"`
+}
+
+// FileChecksum retorna o tamanho e o SHA-256 em hexadecimal de um arquivo
+func FileChecksum(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
 }
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sizeUnits mapeia unidades de tamanho em minúsculas para bytes: as SI são
+// potências de 1000 e as IEC de 1024
+var sizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"m":   1e6,
+	"mb":  1e6,
+	"g":   1e9,
+	"gb":  1e9,
+	"t":   1e12,
+	"tb":  1e12,
+	"ki":  1 << 10,
+	"kib": 1 << 10,
+	"mi":  1 << 20,
+	"mib": 1 << 20,
+	"gi":  1 << 30,
+	"gib": 1 << 30,
+	"ti":  1 << 40,
+	"tib": 1 << 40,
+}
+
+// sizePattern separa o número e a unidade de um tamanho
+var sizePattern = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)\s*([a-zA-Z]*)$`)
+
+// durationTerm é um termo de uma duração, como "30d" ou "12h"
+var durationTerm = regexp.MustCompile(`([0-9]+(?:\.[0-9]+)?)([a-zµ]+)`)
+
+// ParseSize interpreta tamanhos como "10GB", "512 MiB" ou "1.5TB"; sem
+// unidade o número é em bytes. Vazio retorna 0
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	match := sizePattern.FindStringSubmatch(s)
+	if match == nil {
+		return 0, fmt.Errorf("tamanho inválido: %q", s)
+	}
+	unit, ok := sizeUnits[strings.ToLower(match[2])]
+	if !ok {
+		return 0, fmt.Errorf("unidade de tamanho desconhecida em %q", s)
+	}
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("tamanho inválido: %q", s)
+	}
+	return int64(value * float64(unit)), nil
+}
+
+// ParseDuration interpreta durações como "30d", "2w" ou "1w3d12h": além
+// das unidades de time.ParseDuration aceita dias (d) e semanas (w). Vazio
+// retorna 0
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	var total time.Duration
+	var parsed int
+	for _, match := range durationTerm.FindAllStringSubmatchIndex(s, -1) {
+		if match[0] != parsed {
+			return 0, fmt.Errorf("duração inválida: %q", s)
+		}
+		parsed = match[1]
+
+		number, unit := s[match[2]:match[3]], s[match[4]:match[5]]
+		var scale time.Duration
+		switch unit {
+		case "d":
+			scale = 24 * time.Hour
+		case "w":
+			scale = 7 * 24 * time.Hour
+		default:
+			term, err := time.ParseDuration(number + unit)
+			if err != nil {
+				return 0, fmt.Errorf("duração inválida: %q", s)
+			}
+			total += term
+			continue
+		}
+		value, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return 0, fmt.Errorf("duração inválida: %q", s)
+		}
+		total += time.Duration(value * float64(scale))
+	}
+	if parsed != len(s) {
+		return 0, fmt.Errorf("duração inválida: %q", s)
+	}
+	return total, nil
+}
+
+// FormatSize formata um tamanho em bytes com unidades IEC, como "1.5 GiB"
+func FormatSize(bytes int64) string {
+	const unit = 1 << 10
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit && exp < 3; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGT"[exp])
+}
+
+// CleanPolicy retorna a política de retenção do cache descrita por
+// MaxCacheSize, RetentionPeriod e KeepLatest
+func (c Config) CleanPolicy() (CleanPolicy, error) {
+	maxSize, err := ParseSize(c.MaxCacheSize)
+	if err != nil {
+		return CleanPolicy{}, fmt.Errorf("max_cache_size: %w", err)
+	}
+	maxAge, err := ParseDuration(c.RetentionPeriod)
+	if err != nil {
+		return CleanPolicy{}, fmt.Errorf("retention_period: %w", err)
+	}
+	if c.KeepLatest < 0 {
+		return CleanPolicy{}, fmt.Errorf("keep_latest negativo: %d", c.KeepLatest)
+	}
+	return CleanPolicy{MaxSize: maxSize, MaxAge: maxAge, KeepLatest: c.KeepLatest}, nil
+}
+
+// BackupPolicy retorna a política de retenção dos backups descrita por
+// BackupRetention e KeepBackups
+func (c Config) BackupPolicy() (BackupPolicy, error) {
+	maxAge, err := ParseDuration(c.BackupRetention)
+	if err != nil {
+		return BackupPolicy{}, fmt.Errorf("backup_retention: %w", err)
+	}
+	if c.KeepBackups < 0 {
+		return BackupPolicy{}, fmt.Errorf("keep_backups negativo: %d", c.KeepBackups)
+	}
+	return BackupPolicy{MaxAge: maxAge, MaxCount: c.KeepBackups}, nil
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VersionSeparator separa a família e a versão no ID de um modelo, como em
+// "whisper-base@1.3.0"
+const VersionSeparator = "@"
+
+// semverPattern aceita versões semânticas como "1.3.0", "v2.0.1" e "1.4.0-rc.1"
+var semverPattern = regexp.MustCompile(`^v?(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)(?:-([0-9A-Za-z.-]+))?$`)
+
+// Version é uma versão semântica de um modelo
+type Version struct {
+	Major, Minor, Patch int
+	// Prerelease marca uma versão de teste, como "rc.1"; vazio numa versão estável
+	Prerelease string
+}
+
+// ParseVersion interpreta uma versão semântica como "1.3.0" ou "v1.4.0-rc.1"
+func ParseVersion(s string) (Version, error) {
+	match := semverPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return Version{}, fmt.Errorf("versão semântica inválida: %q", s)
+	}
+
+	var v Version
+	for i, field := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		n, err := strconv.Atoi(match[i+1])
+		if err != nil {
+			return Version{}, fmt.Errorf("versão semântica inválida: %q", s)
+		}
+		*field = n
+	}
+	v.Prerelease = match[4]
+	return v, nil
+}
+
+// String formata a versão sem o prefixo "v"
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// Stable indica que a versão não é de teste
+func (v Version) Stable() bool {
+	return v.Prerelease == ""
+}
+
+// Compare retorna -1, 0 ou 1 conforme v é anterior, igual ou posterior a
+// other. Uma versão de teste vem antes da estável de mesmo número
+func (v Version) Compare(other Version) int {
+	for _, pair := range [][2]int{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		switch {
+		case pair[0] < pair[1]:
+			return -1
+		case pair[0] > pair[1]:
+			return 1
+		}
+	}
+
+	switch {
+	case v.Prerelease == other.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case other.Prerelease == "":
+		return -1
+	}
+	return comparePrerelease(v.Prerelease, other.Prerelease)
+}
+
+// comparePrerelease compara identificadores de versões de teste campo a
+// campo: os numéricos por valor e vêm antes dos alfanuméricos
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil && an != bn:
+			if an < bn {
+				return -1
+			}
+			return 1
+		case aErr == nil && bErr != nil:
+			return -1
+		case aErr != nil && bErr == nil:
+			return 1
+		case as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+// SplitID separa o ID de um modelo em família e versão; IDs sem versão são
+// a própria família
+func SplitID(modelID string) (family, version string) {
+	if i := strings.LastIndex(modelID, VersionSeparator); i >= 0 {
+		return modelID[:i], modelID[i+1:]
+	}
+	return modelID, ""
+}
+
+// Alias é um nome estável para um modelo específico, como "default" ou
+// "whisper-base" apontando para "whisper-base@1.3.0"
+type Alias struct {
+	Name string `json:"name"`
+	// Target é o ID do modelo para onde o apelido aponta
+	Target string `json:"target"`
+	// Previous são os alvos anteriores, do mais recente para o mais antigo;
+	// um rollback volta para o primeiro
+	Previous []string `json:"previous,omitempty"`
+	// Updated é quando o alvo mudou pela última vez; zero nos implícitos
+	Updated time.Time `json:"updated,omitempty"`
+	// Implicit marca o apelido de uma família que ninguém fixou: ele segue a
+	// versão estável mais recente da família
+	Implicit bool `json:"implicit,omitempty"`
+}
@@ -0,0 +1,363 @@
+// Package resultcache caches transcription results by the content of the
+// audio they were produced from, so resubmitted recordings skip inference.
+package resultcache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// entryExt is the file extension of on-disk entries
+const entryExt = ".result"
+
+// tempExt is the file extension of on-disk entries still being written
+const tempExt = ".tmp"
+
+// Config controls the cache tiers
+type Config struct {
+	// MaxEntries bounds the number of in-memory entries; 0 means no bound
+	MaxEntries int
+	// MaxBytes bounds the size of in-memory values; 0 means no bound
+	MaxBytes int64
+	// TTL is how long entries stay valid; 0 means forever
+	TTL time.Duration
+	// Dir holds the on-disk tier; empty disables it
+	Dir string
+	// MaxDiskBytes bounds the size of the on-disk tier; 0 means no bound
+	MaxDiskBytes int64
+}
+
+// Stats reports cache usage
+type Stats struct {
+	Hits      int64
+	Misses    int64
+	Entries   int
+	Bytes     int64
+	DiskBytes int64
+}
+
+// Cache is a two-tier LRU cache of encoded results
+type Cache struct {
+	config Config
+
+	mu sync.Mutex
+	// lru orders in-memory entries from most to least recently used
+	lru *list.List
+	// entries maps keys to their element in lru
+	entries map[string]*list.Element
+	// bytes is the size of in-memory values
+	bytes int64
+	// disk maps on-disk keys to their size and write time
+	disk map[string]diskEntry
+	// diskBytes is the size of on-disk values
+	diskBytes int64
+	hits      int64
+	misses    int64
+}
+
+// memoryEntry is an in-memory cached value
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// diskEntry describes an on-disk cached value
+type diskEntry struct {
+	size    int64
+	written time.Time
+}
+
+// New creates a cache, indexing the entries already in its directory
+func New(config Config) (*Cache, error) {
+	c := &Cache{
+		config:  config,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+		disk:    make(map[string]diskEntry),
+	}
+
+	if config.Dir != "" {
+		if err := os.MkdirAll(config.Dir, 0755); err != nil {
+			return nil, errors.Wrap(err, "failed to create result cache directory")
+		}
+		files, err := os.ReadDir(config.Dir)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read result cache directory")
+		}
+		for _, file := range files {
+			if !file.IsDir() && strings.HasSuffix(file.Name(), tempExt) {
+				// Left behind by a write that never finished
+				_ = os.Remove(filepath.Join(config.Dir, file.Name()))
+				continue
+			}
+			if file.IsDir() || !strings.HasSuffix(file.Name(), entryExt) {
+				continue
+			}
+			info, err := file.Info()
+			if err != nil {
+				continue
+			}
+			key := strings.TrimSuffix(file.Name(), entryExt)
+			c.disk[key] = diskEntry{size: info.Size(), written: info.ModTime()}
+			c.diskBytes += info.Size()
+		}
+	}
+
+	return c, nil
+}
+
+// keyBlock is the number of samples hashed at a time
+const keyBlock = 1024
+
+// Key hashes decoded audio together with the model and the settings that
+// affect its transcription; modelChecksum identifies the exact weights, so
+// a model replaced under the same ID doesn't serve its predecessor's results
+func Key(samples []float32, modelID, modelChecksum string, settings []byte) string {
+	h := sha256.New()
+	writeSize := func(size int) {
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], uint64(size))
+		h.Write(buf[:])
+	}
+	writeField := func(data []byte) {
+		writeSize(len(data))
+		h.Write(data)
+	}
+	writeField([]byte(modelID))
+	writeField([]byte(modelChecksum))
+	writeField(settings)
+
+	// The audio is hashed a block at a time rather than copied whole
+	writeSize(4 * len(samples))
+	var buf [4 * keyBlock]byte
+	for start := 0; start < len(samples); start += keyBlock {
+		block := samples[start:]
+		if len(block) > keyBlock {
+			block = block[:keyBlock]
+		}
+		for i, sample := range block {
+			binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(sample))
+		}
+		h.Write(buf[:4*len(block)])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns the value cached under key, looking in memory first and then
+// on disk, where a hit is promoted to memory
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+
+	now := time.Now()
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*memoryEntry)
+		if entry.expires.IsZero() || now.Before(entry.expires) {
+			c.lru.MoveToFront(element)
+			c.hits++
+			c.mu.Unlock()
+			return entry.value, true
+		}
+		c.removeMemory(element)
+	}
+
+	disk, ok := c.disk[key]
+	if ok && c.config.TTL > 0 && now.Sub(disk.written) >= c.config.TTL {
+		stale := c.removeDisk(key)
+		c.misses++
+		c.mu.Unlock()
+		removeFiles(stale)
+		return nil, false
+	}
+	if !ok {
+		c.misses++
+		c.mu.Unlock()
+		return nil, false
+	}
+	c.mu.Unlock()
+
+	// Reading the file without mu keeps a slow disk from stalling every
+	// other lookup; the entry may be replaced or evicted in the meantime
+	value, err := os.ReadFile(c.path(key))
+
+	c.mu.Lock()
+	current, unchanged := c.disk[key]
+	unchanged = unchanged && current == disk
+	var stale []string
+	switch {
+	case err == nil:
+		if unchanged {
+			var expires time.Time
+			if c.config.TTL > 0 {
+				expires = disk.written.Add(c.config.TTL)
+			}
+			c.storeMemory(key, value, expires)
+		}
+		c.hits++
+	case unchanged:
+		stale = c.removeDisk(key)
+		c.misses++
+	default:
+		c.misses++
+	}
+	c.mu.Unlock()
+
+	removeFiles(stale)
+	return value, err == nil
+}
+
+// Put caches value under key in memory and, when enabled, on disk
+func (c *Cache) Put(key string, value []byte) error {
+	c.mu.Lock()
+	now := time.Now()
+	var expires time.Time
+	if c.config.TTL > 0 {
+		expires = now.Add(c.config.TTL)
+	}
+	c.storeMemory(key, value, expires)
+	c.mu.Unlock()
+
+	if c.config.Dir == "" {
+		return nil
+	}
+	if c.config.MaxDiskBytes > 0 && int64(len(value)) > c.config.MaxDiskBytes {
+		return nil
+	}
+
+	// The write replaces any previous file atomically, so it needs no lock
+	if err := writeFileAtomic(c.path(key), value); err != nil {
+		return errors.Wrap(err, "failed to write cached result")
+	}
+
+	c.mu.Lock()
+	if previous, ok := c.disk[key]; ok {
+		c.diskBytes -= previous.size
+	}
+	c.disk[key] = diskEntry{size: int64(len(value)), written: now}
+	c.diskBytes += int64(len(value))
+	stale := c.evictDisk()
+	c.mu.Unlock()
+
+	removeFiles(stale)
+	return nil
+}
+
+// Stats returns the cache's usage
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Entries:   c.lru.Len(),
+		Bytes:     c.bytes,
+		DiskBytes: c.diskBytes,
+	}
+}
+
+// storeMemory adds or replaces an in-memory entry and evicts the least
+// recently used entries past the limits; the caller holds mu
+func (c *Cache) storeMemory(key string, value []byte, expires time.Time) {
+	if element, ok := c.entries[key]; ok {
+		c.removeMemory(element)
+	}
+	if c.config.MaxBytes > 0 && int64(len(value)) > c.config.MaxBytes {
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&memoryEntry{key: key, value: value, expires: expires})
+	c.bytes += int64(len(value))
+
+	for (c.config.MaxEntries > 0 && c.lru.Len() > c.config.MaxEntries) ||
+		(c.config.MaxBytes > 0 && c.bytes > c.config.MaxBytes) {
+		c.removeMemory(c.lru.Back())
+	}
+}
+
+// removeMemory drops an in-memory entry; the caller holds mu
+func (c *Cache) removeMemory(element *list.Element) {
+	entry := c.lru.Remove(element).(*memoryEntry)
+	delete(c.entries, entry.key)
+	c.bytes -= int64(len(entry.value))
+}
+
+// evictDisk drops the oldest on-disk entries past the size limit and
+// returns their files; the caller holds mu and deletes them after releasing it
+func (c *Cache) evictDisk() []string {
+	if c.config.MaxDiskBytes <= 0 || c.diskBytes <= c.config.MaxDiskBytes {
+		return nil
+	}
+
+	keys := make([]string, 0, len(c.disk))
+	for key := range c.disk {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.disk[keys[i]].written.Before(c.disk[keys[j]].written)
+	})
+	var stale []string
+	for _, key := range keys {
+		if c.diskBytes <= c.config.MaxDiskBytes {
+			break
+		}
+		stale = append(stale, c.removeDisk(key)...)
+	}
+	return stale
+}
+
+// removeDisk drops an on-disk entry and returns its file; the caller holds
+// mu and deletes the file after releasing it
+func (c *Cache) removeDisk(key string) []string {
+	entry, ok := c.disk[key]
+	if !ok {
+		return nil
+	}
+	delete(c.disk, key)
+	c.diskBytes -= entry.size
+	return []string{c.path(key)}
+}
+
+// removeFiles deletes the files of dropped on-disk entries
+func removeFiles(paths []string) {
+	for _, path := range paths {
+		// A file that is already gone needs no removal
+		_ = os.Remove(path)
+	}
+}
+
+// path returns the file of an on-disk entry
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.config.Dir, key+entryExt)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so a crash never leaves a truncated entry behind
+func writeFileAtomic(path string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*"+tempExt)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/pkg/errors"
+)
+
+// ModelAdmin manages the aliases requests use to name models
+type ModelAdmin interface {
+	// Aliases returns every alias, pinned or following its family's latest version
+	Aliases() []models.Alias
+	// Alias returns one alias
+	Alias(name string) (models.Alias, error)
+	// SetAlias atomically points an alias at a registered model
+	SetAlias(name, target string) (models.Alias, error)
+	// RollbackAlias points an alias back at its previous target
+	RollbackAlias(name string) (models.Alias, error)
+	// RemoveAlias removes a pinned alias
+	RemoveAlias(name string) error
+}
+
+// aliasPrefix is the path the alias endpoints are served under
+const aliasPrefix = "/admin/aliases"
+
+// AdminHandler returns the HTTP handler for the model admin endpoints:
+//
+//	GET    /admin/aliases                 lists every alias
+//	GET    /admin/aliases/{name}          returns an alias
+//	PUT    /admin/aliases/{name}          retargets an alias to {"target": "whisper-base@1.3.0"}
+//	POST   /admin/aliases/{name}/rollback points an alias back at its previous target
+//	DELETE /admin/aliases/{name}          removes a pinned alias
+//
+// Responses are JSON; errors come as {"error": "..."}
+func AdminHandler(admin ModelAdmin) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(aliasPrefix, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeAdminError(w, http.StatusMethodNotAllowed, errors.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		writeJSON(w, http.StatusOK, admin.Aliases())
+	})
+	mux.HandleFunc(aliasPrefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, aliasPrefix+"/")
+		if r.Method == http.MethodPost && strings.HasSuffix(name, "/rollback") {
+			alias, err := admin.RollbackAlias(strings.TrimSuffix(name, "/rollback"))
+			writeAliasResult(w, alias, err)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			alias, err := admin.Alias(name)
+			writeAliasResult(w, alias, err)
+		case http.MethodPut:
+			var body struct {
+				Target string `json:"target"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Target == "" {
+				writeAdminError(w, http.StatusBadRequest, errors.New(`expected a body like {"target": "model-id"}`))
+				return
+			}
+			alias, err := admin.SetAlias(name, body.Target)
+			writeAliasResult(w, alias, err)
+		case http.MethodDelete:
+			if err := admin.RemoveAlias(name); err != nil {
+				writeAdminError(w, adminStatus(err), err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeAdminError(w, http.StatusMethodNotAllowed, errors.Errorf("method %s not allowed", r.Method))
+		}
+	})
+	return mux
+}
+
+// writeAliasResult writes an alias, or the error that prevented getting it
+func writeAliasResult(w http.ResponseWriter, alias models.Alias, err error) {
+	if err != nil {
+		writeAdminError(w, adminStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, alias)
+}
+
+// adminStatus maps an admin failure to an HTTP status: unknown models and
+// aliases are not found, anything else is a bad request
+func adminStatus(err error) int {
+	if errors.Is(err, models.ErrNotRegistered) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// writeAdminError writes err as a JSON error body
+func writeAdminError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// writeJSON writes value as the JSON response body
+func writeJSON(w http.ResponseWriter, code int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(value)
+}
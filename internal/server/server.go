@@ -4,28 +4,69 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
+	"github.com/josealecrim/audiototext/internal/audio"
 	"github.com/josealecrim/audiototext/internal/inference"
 	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/internal/resultcache"
 	pb "github.com/josealecrim/audiototext/pkg/transcription"
 	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
+const (
+	// sampleRate is the sample rate expected by the models
+	sampleRate = 16000
+	// windowDuration is the length in seconds of the audio windows sent to the scheduler
+	windowDuration = 30
+	// beamSize is the number of hypotheses the decoder keeps per step
+	beamSize = 5
+	// maxInitialPromptLength is the longest initial prompt accepted, in characters
+	maxInitialPromptLength = 1000
+	// maxPhraseHints is the number of phrase hints accepted per request
+	maxPhraseHints = 100
+	// maxPhraseHintLength is the longest phrase hint accepted, in characters
+	maxPhraseHintLength = 100
+	// maxHintBoost bounds the magnitude of a phrase hint boost
+	maxHintBoost = 10
+	// maxAlternatives is the most alternatives a request can ask for
+	maxAlternatives = 10
+)
+
+// ModelCatalog looks up the models the server can serve
+type ModelCatalog interface {
+	// GetModel returns a model by ID
+	GetModel(modelID string) (*models.ONNXModel, error)
+	// ListModels returns every available model
+	ListModels() ([]*models.ONNXModel, error)
+}
+
 // Server implements the TranscriptionService gRPC server
 type Server struct {
 	pb.UnimplementedTranscriptionServiceServer
 	// inferenceManager manages ONNX Runtime sessions
 	inferenceManager *inference.Manager
-	// modelManager manages model downloads and caching
-	modelManager *models.Manager
+	// modelManager looks up models to serve
+	modelManager ModelCatalog
 	// activeSessions tracks active transcription sessions
 	activeSessions sync.Map
 	// stats tracks server statistics
 	stats *Stats
+	// ready is set once warmup has completed
+	ready atomic.Bool
+	// resultCache holds the results of recent requests, nil when disabled
+	resultCache *resultcache.Cache
 }
 
 // Stats tracks server statistics
@@ -42,7 +83,7 @@ type Stats struct {
 }
 
 // NewServer creates a new transcription server
-func NewServer(inferenceManager *inference.Manager, modelManager *models.Manager) *Server {
+func NewServer(inferenceManager *inference.Manager, modelManager ModelCatalog) *Server {
 	return &Server{
 		inferenceManager: inferenceManager,
 		modelManager:     modelManager,
@@ -50,8 +91,46 @@ func NewServer(inferenceManager *inference.Manager, modelManager *models.Manager
 	}
 }
 
+// SetResultCache enables caching transcription results by audio content;
+// a nil cache disables it
+func (s *Server) SetResultCache(cache *resultcache.Cache) {
+	s.resultCache = cache
+}
+
+// Warmup loads the given models and runs synthetic audio through them,
+// marking the server ready once every model is warm. Until then requests
+// are rejected with Unavailable.
+func (s *Server) Warmup(ctx context.Context, modelIDs []string) error {
+	warmupModels := make([]*models.ONNXModel, 0, len(modelIDs))
+	for _, modelID := range modelIDs {
+		model, err := s.modelManager.GetModel(modelID)
+		if err != nil {
+			return errors.Wrapf(err, "model %s", modelID)
+		}
+		warmupModels = append(warmupModels, model)
+	}
+
+	if err := s.inferenceManager.Warmup(ctx, warmupModels); err != nil {
+		return err
+	}
+
+	s.ready.Store(true)
+	return nil
+}
+
+// checkReady rejects requests until warmup has completed
+func (s *Server) checkReady() error {
+	if !s.ready.Load() {
+		return status.Error(codes.Unavailable, "server is warming up")
+	}
+	return nil
+}
+
 // Transcribe implements the one-shot transcription RPC
 func (s *Server) Transcribe(ctx context.Context, req *pb.TranscribeRequest) (*pb.TranscribeResponse, error) {
+	if err := s.checkReady(); err != nil {
+		return nil, err
+	}
 	if err := s.validateTranscribeRequest(req); err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
@@ -62,56 +141,100 @@ func (s *Server) Transcribe(ctx context.Context, req *pb.TranscribeRequest) (*pb
 		return nil, status.Error(codes.NotFound, fmt.Sprintf("model not found: %v", err))
 	}
 
-	// Create inference session
-	session, err := s.inferenceManager.CreateSession(ctx, model, nil, nil)
+	// Record the request's stages when profiling is enabled
+	requestID := fmt.Sprintf("req-%d", time.Now().UnixNano())
+	ctx, trace := s.inferenceManager.StartTrace(ctx, requestID, model.ID)
+
+	// Convert audio data to float32 array
+	audioData, err := s.convertAudioData(ctx, req.AudioData, req.Format)
 	if err != nil {
-		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to create inference session: %v", err))
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("failed to convert audio data: %v", err))
 	}
-	defer s.inferenceManager.CloseSession(model.ID)
 
-	// Create inference handler
-	inf := inference.NewInference(session)
+	// Serve repeated audio from the cache
+	var cacheKey string
+	if s.resultCache != nil {
+		cacheKey = resultCacheKey(audioData, model, req.Config)
+		if !req.Config.BypassCache {
+			if response := s.cachedResponse(cacheKey); response != nil {
+				return response, nil
+			}
+		}
+	}
 
-	// Convert audio data to float32 array
-	audioData, err := s.convertAudioData(req.AudioData, req.Format)
+	// Get the model's batching scheduler
+	scheduler, err := s.inferenceManager.GetScheduler(ctx, model)
 	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("failed to convert audio data: %v", err))
+		return nil, inferenceError(err, "failed to create inference session")
 	}
 
-	// Process audio
-	result, err := inf.ProcessAudio(ctx, audioData)
+	// Process audio window by window so it can be batched with other requests
+	results, err := s.transcribeWindows(ctx, scheduler, audioData, decodeOptions(req.Config))
 	if err != nil {
-		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to process audio: %v", err))
+		return nil, inferenceError(err, "failed to process audio")
 	}
 
-	// Convert result to response
-	response := s.convertResultToResponse(result)
+	// Convert results to response
+	response := s.convertResultsToResponse(results)
+	setModelMetadata(response, model)
+	if s.resultCache != nil {
+		s.cacheResponse(cacheKey, response)
+		response.Metadata["cache_hit"] = "false"
+	}
+	if trace != nil {
+		response.Metadata["trace_id"] = trace.ID
+	}
 
 	return response, nil
 }
 
 // TranscribeStream implements the streaming transcription RPC
 func (s *Server) TranscribeStream(stream pb.TranscriptionService_TranscribeStreamServer) error {
-	ctx := stream.Context()
+	if err := s.checkReady(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
 	sessionID := fmt.Sprintf("stream-%d", time.Now().UnixNano())
 
 	// Create channels for audio processing
 	audioCh := make(chan []float32, 10)
-	resultCh := make(chan *inference.Result, 10)
+	resultCh := make(chan *streamResult, 10)
 	errorCh := make(chan error, 1)
+	sendDone := make(chan struct{})
+	processDone := make(chan struct{})
 
-	// Start processing goroutine
-	go s.processAudioStream(ctx, sessionID, audioCh, resultCh, errorCh)
-
-	// Start result sending goroutine
-	go s.sendResults(stream, resultCh, errorCh)
+	// Whichever way the stream ends, the goroutines stop before the
+	// handler returns; gRPC forbids sending on a finished stream
+	var config *pb.TranscriptionConfig
+	audioClosed := false
+	defer func() {
+		cancel()
+		if !audioClosed {
+			close(audioCh)
+		}
+		if config != nil {
+			<-processDone
+			<-sendDone
+		}
+	}()
 
 	// Receive audio chunks
-	var config *pb.TranscriptionConfig
 	for {
 		chunk, err := stream.Recv()
 		if err == io.EOF {
 			close(audioCh)
+			audioClosed = true
+			if config != nil {
+				// Wait for pending results to be sent; a failure of the last
+				// windows must not end the stream as if it had succeeded
+				<-sendDone
+				select {
+				case err := <-errorCh:
+					return inferenceError(err, "processing error")
+				default:
+				}
+			}
 			return nil
 		}
 		if err != nil {
@@ -120,14 +243,41 @@ func (s *Server) TranscribeStream(stream pb.TranscriptionService_TranscribeStrea
 
 		// Get config from first chunk
 		if config == nil {
-			config = chunk.Config
-			if err := s.validateConfig(config); err != nil {
+			if err := s.validateConfig(chunk.Config); err != nil {
 				return status.Error(codes.InvalidArgument, err.Error())
 			}
+
+			model, err := s.modelManager.GetModel(chunk.Config.ModelId)
+			if err != nil {
+				return status.Error(codes.NotFound, fmt.Sprintf("model not found: %v", err))
+			}
+
+			scheduler, err := s.inferenceManager.GetScheduler(ctx, model)
+			if err != nil {
+				return inferenceError(err, "failed to create inference session")
+			}
+
+			// Record the stream's stages under its session ID when profiling is enabled
+			ctx, _ = s.inferenceManager.StartTrace(ctx, sessionID, model.ID)
+
+			// Set only once both goroutines run, since the handler waits for them
+			config = chunk.Config
+
+			// Start processing goroutine
+			go func() {
+				defer close(processDone)
+				s.processAudioStream(ctx, sessionID, scheduler, decodeOptions(config), config.EnableInterimResults, audioCh, resultCh, errorCh)
+			}()
+
+			// Start result sending goroutine
+			go func() {
+				defer close(sendDone)
+				s.sendResults(ctx, stream, model, resultCh, errorCh)
+			}()
 		}
 
 		// Convert audio data
-		audioData, err := s.convertAudioData(chunk.AudioData, chunk.Format)
+		audioData, err := s.convertAudioData(ctx, chunk.AudioData, chunk.Format)
 		if err != nil {
 			return status.Error(codes.InvalidArgument, fmt.Sprintf("failed to convert audio data: %v", err))
 		}
@@ -136,26 +286,47 @@ func (s *Server) TranscribeStream(stream pb.TranscriptionService_TranscribeStrea
 		select {
 		case audioCh <- audioData:
 		case err := <-errorCh:
-			return status.Error(codes.Internal, fmt.Sprintf("processing error: %v", err))
+			return inferenceError(err, "processing error")
 		case <-ctx.Done():
 			return status.Error(codes.Canceled, "stream canceled")
 		}
 	}
 }
 
-// GetModels implements the GetModels RPC
+// inferenceError converts an inference failure to a gRPC status; work
+// rejected for lack of memory gets ResourceExhausted with a retry hint
+func inferenceError(err error, message string) error {
+	var exhausted *inference.MemoryExhaustedError
+	if !errors.As(err, &exhausted) {
+		return status.Error(codes.Internal, fmt.Sprintf("%s: %v", message, err))
+	}
+
+	st := status.New(codes.ResourceExhausted, fmt.Sprintf("%s: %v", message, err))
+	if exhausted.RetryAfter > 0 {
+		if detailed, detailErr := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(exhausted.RetryAfter)}); detailErr == nil {
+			st = detailed
+		}
+	}
+	return st.Err()
+}
+
+// GetModels implements the GetModels RPC, returning the models that
+// support the requested language when a filter is given
 func (s *Server) GetModels(ctx context.Context, req *pb.GetModelsRequest) (*pb.GetModelsResponse, error) {
-	models, err := s.modelManager.ListModels()
+	list, err := s.modelManager.ListModels()
 	if err != nil {
 		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to list models: %v", err))
 	}
 
 	response := &pb.GetModelsResponse{
-		Models: make([]*pb.Model, 0, len(models)),
+		Models: make([]*pb.Model, 0, len(list)),
 	}
 
-	for _, model := range models {
+	for _, model := range list {
 		info := s.convertModelToInfo(model)
+		if !models.SupportsLanguage(info.Languages, req.LanguageFilter) {
+			continue
+		}
 		response.Models = append(response.Models, info)
 	}
 
@@ -167,23 +338,17 @@ func (s *Server) GetStatus(ctx context.Context, req *pb.GetStatusRequest) (*pb.G
 	s.stats.mu.RLock()
 	defer s.stats.mu.RUnlock()
 
-	// Get hardware stats
+	// Sum memory across models; device memory is what sessions hold on accelerators
 	memStats := s.inferenceManager.GetStats()
 	var gpuMemoryUsage int64
 	var currentMemoryUsage int64
-
-	// Get the first model's stats or use 0 if no models are loaded
-	for modelID, stats := range memStats {
-		if stats.PeakMemoryUsage > gpuMemoryUsage {
-			gpuMemoryUsage = stats.PeakMemoryUsage
-		}
-		if modelID == "default" {
-			currentMemoryUsage = stats.CurrentMemoryUsage
-		}
+	for _, stats := range memStats {
+		currentMemoryUsage += stats.CurrentMemoryUsage
+		gpuMemoryUsage += stats.DeviceMemoryUsage
 	}
 
 	response := &pb.GetStatusResponse{
-		IsReady:        true,
+		IsReady:        s.ready.Load(),
 		Load:           float32(s.stats.activeSessionCount) / 100.0, // Arbitrary scale
 		ActiveSessions: s.stats.activeSessionCount,
 		MemoryUsage:    currentMemoryUsage,
@@ -195,6 +360,51 @@ func (s *Server) GetStatus(ctx context.Context, req *pb.GetStatusRequest) (*pb.G
 		},
 	}
 
+	// Report how long each model took to warm up
+	for modelID, result := range s.inferenceManager.GetWarmupResults() {
+		if result.Err != nil {
+			response.Details["warmup."+modelID] = fmt.Sprintf("failed: %v", result.Err)
+			continue
+		}
+		sizes := make([]int, 0, len(result.BatchLatencies))
+		for size := range result.BatchLatencies {
+			sizes = append(sizes, size)
+		}
+		sort.Ints(sizes)
+		parts := []string{fmt.Sprintf("load=%.3fs", result.LoadTime.Seconds())}
+		for _, size := range sizes {
+			parts = append(parts, fmt.Sprintf("batch_%d=%.3fs", size, result.BatchLatencies[size].Seconds()))
+		}
+		response.Details["warmup."+modelID] = strings.Join(parts, " ")
+	}
+
+	// Report per-model inference statistics
+	for modelID, stats := range memStats {
+		response.Details["stats."+modelID] = fmt.Sprintf(
+			"inferences=%d batches=%d avg_batch=%.2f audio_s=%.1f rtf=%.3f p50=%.3f p90=%.3f p99=%.3f memory=%d peak=%d",
+			stats.TotalInferences, stats.TotalBatches, stats.AverageBatchSize, stats.AudioSecondsProcessed,
+			stats.RealTimeFactor, stats.LatencyP50, stats.LatencyP90, stats.LatencyP99,
+			stats.CurrentMemoryUsage, stats.PeakMemoryUsage)
+	}
+
+	// Report the execution provider each model is running on and why it fell back
+	for modelID, providerStatus := range s.inferenceManager.GetProviderStatus() {
+		providers := make([]string, 0, len(providerStatus.Sessions))
+		for provider, count := range providerStatus.Sessions {
+			providers = append(providers, fmt.Sprintf("%s=%d", provider, count))
+		}
+		sort.Strings(providers)
+		response.Details["provider."+modelID] = strings.Join(providers, ",")
+
+		if len(providerStatus.Fallbacks) > 0 {
+			fallbacks := make([]string, 0, len(providerStatus.Fallbacks))
+			for _, fallback := range providerStatus.Fallbacks {
+				fallbacks = append(fallbacks, fallback.String())
+			}
+			response.Details["provider_fallbacks."+modelID] = strings.Join(fallbacks, "; ")
+		}
+	}
+
 	return response, nil
 }
 
@@ -223,60 +433,395 @@ func (s *Server) validateConfig(config *pb.TranscriptionConfig) error {
 	if config.Language == "" {
 		return errors.New("language cannot be empty")
 	}
+	if config.MaxAlternatives < 0 || config.MaxAlternatives > maxAlternatives {
+		return errors.Errorf("max alternatives %d is outside [0, %d]", config.MaxAlternatives, maxAlternatives)
+	}
+	if n := utf8.RuneCountInString(config.InitialPrompt); n > maxInitialPromptLength {
+		return errors.Errorf("initial prompt has %d characters, limit is %d", n, maxInitialPromptLength)
+	}
+	if len(config.PhraseHints) > maxPhraseHints {
+		return errors.Errorf("%d phrase hints given, limit is %d", len(config.PhraseHints), maxPhraseHints)
+	}
+	for i, hint := range config.PhraseHints {
+		if strings.TrimSpace(hint.Phrase) == "" {
+			return errors.Errorf("phrase hint %d is empty", i)
+		}
+		if n := utf8.RuneCountInString(hint.Phrase); n > maxPhraseHintLength {
+			return errors.Errorf("phrase hint %d has %d characters, limit is %d", i, n, maxPhraseHintLength)
+		}
+		if hint.Boost > maxHintBoost || hint.Boost < -maxHintBoost {
+			return errors.Errorf("phrase hint %d boost %.2f is outside [-%d, %d]", i, hint.Boost, maxHintBoost, maxHintBoost)
+		}
+	}
 	return nil
 }
 
-func (s *Server) convertAudioData(data []byte, format pb.AudioFormat) ([]float32, error) {
-	// TODO: Implement actual audio conversion
-	// This will be implemented in the audio processing sprint
-	return make([]float32, len(data)/4), nil
+// decodeOptions converts the request's biasing settings into decoder options
+func decodeOptions(config *pb.TranscriptionConfig) inference.DecodeOptions {
+	options := inference.DecodeOptions{
+		BeamSize:        beamSize,
+		Language:        config.Language,
+		Prompt:          config.InitialPrompt,
+		MaxAlternatives: int(config.MaxAlternatives),
+	}
+	// The beam has to hold every alternative asked for
+	if options.MaxAlternatives > options.BeamSize {
+		options.BeamSize = options.MaxAlternatives
+	}
+	for _, hint := range config.PhraseHints {
+		options.Hints = append(options.Hints, inference.PhraseHint{
+			Phrase: hint.Phrase,
+			Boost:  hint.Boost,
+		})
+	}
+	return options
+}
+
+// convertAudioData decodes request audio into mono samples at the model
+// sample rate. WAV data is decoded from its header; headerless data, such as
+// the chunks following the first one in a stream, is taken as 16 kHz mono PCM16.
+func (s *Server) convertAudioData(ctx context.Context, data []byte, format pb.AudioFormat) ([]float32, error) {
+	end := inference.StartSpan(ctx, inference.StageDecode, map[string]interface{}{"bytes": len(data), "format": format.String()})
+	var decoded *audio.Audio
+	var err error
+	switch format {
+	case pb.AudioFormat_AUDIO_FORMAT_UNSPECIFIED, pb.AudioFormat_AUDIO_FORMAT_WAV:
+		if audio.IsWAV(data) {
+			decoded, err = audio.DecodeWAV(data)
+		} else {
+			decoded = audio.DecodePCM16(data, sampleRate)
+		}
+	default:
+		err = errors.Errorf("unsupported audio format %s", format)
+	}
+	end()
+	if err != nil {
+		return nil, err
+	}
+
+	end = inference.StartSpan(ctx, inference.StageResample, map[string]interface{}{"from_rate": decoded.SampleRate, "to_rate": sampleRate})
+	resampled := audio.Resample(decoded, sampleRate)
+	end()
+
+	return resampled.Samples, nil
+}
+
+// DebugHandler returns the HTTP handler for the debug endpoints:
+// /debug/traces serves recent request traces in Chrome trace-event format
+func (s *Server) DebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/traces", s.inferenceManager.Tracer())
+	return mux
 }
 
-func (s *Server) convertResultToResponse(result *inference.Result) *pb.TranscribeResponse {
+// transcribeWindows splits audio into fixed-size windows, submits them to the
+// scheduler and returns the results in order with absolute timestamps.
+// Windows run concurrently unless the request has a prompt: then they run
+// in order, each seeing the prompt followed by the previous window's text.
+func (s *Server) transcribeWindows(ctx context.Context, scheduler *inference.Scheduler, audioData []float32, options inference.DecodeOptions) ([]*inference.Result, error) {
+	windowSamples := windowDuration * sampleRate
+
+	var windows [][]float32
+	for start := 0; start < len(audioData); start += windowSamples {
+		end := start + windowSamples
+		if end > len(audioData) {
+			end = len(audioData)
+		}
+		windows = append(windows, audioData[start:end])
+	}
+	if len(windows) == 0 {
+		windows = append(windows, audioData)
+	}
+
+	results := make([]*inference.Result, len(windows))
+	errs := make([]error, len(windows))
+
+	if options.Prompt != "" {
+		for i, window := range windows {
+			results[i], errs[i] = scheduler.SubmitWindow(ctx, &inference.Window{Audio: window, Options: options})
+			if errs[i] != nil {
+				break
+			}
+			options.PreviousText = results[i].Transcription
+		}
+	} else {
+		var wg sync.WaitGroup
+		for i, window := range windows {
+			wg.Add(1)
+			go func(i int, window []float32) {
+				defer wg.Done()
+				results[i], errs[i] = scheduler.SubmitWindow(ctx, &inference.Window{Audio: window, Options: options})
+			}(i, window)
+		}
+		wg.Wait()
+	}
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, errors.Wrapf(err, "window %d", i)
+		}
+		shiftResult(results[i], float32(i*windowDuration))
+	}
+
+	return results, nil
+}
+
+func (s *Server) convertResultsToResponse(results []*inference.Result) *pb.TranscribeResponse {
 	response := &pb.TranscribeResponse{
+		Segments: make([]*pb.Segment, 0, len(results)),
+	}
+
+	var texts []string
+	var processingTime, confidenceSum float32
+	words := 0
+	for _, result := range results {
+		if result.Transcription != "" {
+			texts = append(texts, result.Transcription)
+		}
+		// Segments count towards the transcript's confidence by their words
+		confidenceSum += result.Confidence * float32(len(result.Words))
+		words += len(result.Words)
+		processingTime += result.ProcessingTime
+		response.Segments = append(response.Segments, convertSegment(result))
+		response.Warnings = append(response.Warnings, resultWarnings(result)...)
+	}
+	if words > 0 {
+		response.Confidence = confidenceSum / float32(words)
+	} else if len(results) > 0 {
+		for _, result := range results {
+			response.Confidence += result.Confidence
+		}
+		response.Confidence /= float32(len(results))
+	}
+
+	response.Text = strings.Join(texts, " ")
+	response.Alternatives = transcriptAlternatives(results)
+	response.IsFinal = true
+	response.Metadata = map[string]string{
+		"processing_time": fmt.Sprintf("%.3f", processingTime),
+	}
+
+	return response
+}
+
+// setModelMetadata echoes the model a request was resolved to, so callers
+// asking for an alias or a family learn the exact version that served them
+func setModelMetadata(response *pb.TranscribeResponse, model *models.ONNXModel) {
+	if response.Metadata == nil {
+		response.Metadata = make(map[string]string)
+	}
+	response.Metadata["model_id"] = model.ID
+	if model.Model != nil && model.Info.Version != "" {
+		response.Metadata["model_version"] = model.Info.Version
+	}
+}
+
+// resultCacheKey identifies a request's result by its decoded audio, model
+// and transcription settings; the bypass flag doesn't change the result
+func resultCacheKey(audioData []float32, model *models.ONNXModel, config *pb.TranscriptionConfig) string {
+	settings := proto.Clone(config).(*pb.TranscriptionConfig)
+	settings.BypassCache = false
+	encoded, _ := proto.MarshalOptions{Deterministic: true}.Marshal(settings)
+
+	// The checksum tells apart weights restored or replaced under the same ID
+	var checksum string
+	if model.Model != nil {
+		checksum = model.Info.Checksum
+	}
+	return resultcache.Key(audioData, model.ID, checksum, encoded)
+}
+
+// cachedResponse returns the cached response for key, or nil on a miss
+func (s *Server) cachedResponse(key string) *pb.TranscribeResponse {
+	data, ok := s.resultCache.Get(key)
+	if !ok {
+		return nil
+	}
+	response := &pb.TranscribeResponse{}
+	if err := proto.Unmarshal(data, response); err != nil {
+		return nil
+	}
+	if response.Metadata == nil {
+		response.Metadata = make(map[string]string)
+	}
+	response.Metadata["cache_hit"] = "true"
+	return response
+}
+
+// cacheResponse stores a response under key; caching is best effort
+func (s *Server) cacheResponse(key string, response *pb.TranscribeResponse) {
+	data, err := proto.Marshal(response)
+	if err != nil {
+		return
+	}
+	_ = s.resultCache.Put(key, data)
+}
+
+// convertSegment converts a result into a segment with its words
+func convertSegment(result *inference.Result) *pb.Segment {
+	segment := &pb.Segment{
 		Text:       result.Transcription,
+		StartTime:  result.TimestampStart,
+		EndTime:    result.TimestampEnd,
 		Confidence: result.Confidence,
-		Segments: []*pb.Segment{
-			{
-				Text:       result.Transcription,
-				StartTime:  result.TimestampStart,
-				EndTime:    result.TimestampEnd,
-				Confidence: result.Confidence,
-			},
-		},
-		Metadata: map[string]string{
-			"processing_time": fmt.Sprintf("%.3f", result.ProcessingTime),
-		},
+		Words:      make([]*pb.WordResult, len(result.Words)),
+	}
+	for i, word := range result.Words {
+		segment.Words[i] = &pb.WordResult{
+			Word:       word.Word,
+			StartTime:  word.Start,
+			EndTime:    word.End,
+			Confidence: word.Confidence,
+		}
 	}
+	for _, alternative := range result.Alternatives {
+		segment.Alternatives = append(segment.Alternatives, convertAlternative(alternative))
+	}
+	return segment
+}
 
-	return response
+// convertAlternative converts an alternative hypothesis
+func convertAlternative(alternative inference.Alternative) *pb.Alternative {
+	return &pb.Alternative{
+		Text:       alternative.Text,
+		Confidence: alternative.Confidence,
+		Score:      alternative.Score,
+	}
+}
+
+// transcriptAlternatives joins the segments' alternatives of each rank into
+// alternatives for the whole transcript; segments with fewer alternatives
+// contribute their last one. Confidences and scores are averaged, and
+// repeated texts are left out.
+func transcriptAlternatives(results []*inference.Result) []*pb.Alternative {
+	ranks := 0
+	for _, result := range results {
+		if len(result.Alternatives) > ranks {
+			ranks = len(result.Alternatives)
+		}
+	}
+
+	alternatives := make([]*pb.Alternative, 0, ranks)
+	seen := make(map[string]bool)
+	for rank := 0; rank < ranks; rank++ {
+		var texts []string
+		alternative := &pb.Alternative{}
+		segments := 0
+		for _, result := range results {
+			if len(result.Alternatives) == 0 {
+				continue
+			}
+			segment := result.Alternatives[len(result.Alternatives)-1]
+			if rank < len(result.Alternatives) {
+				segment = result.Alternatives[rank]
+			}
+			if segment.Text != "" {
+				texts = append(texts, segment.Text)
+			}
+			alternative.Confidence += segment.Confidence
+			alternative.Score += segment.Score
+			segments++
+		}
+		alternative.Text = strings.Join(texts, " ")
+		if seen[alternative.Text] {
+			continue
+		}
+		seen[alternative.Text] = true
+		alternative.Confidence /= float32(segments)
+		alternative.Score /= float32(segments)
+		alternatives = append(alternatives, alternative)
+	}
+	return alternatives
+}
+
+// shiftResult moves a result and its words later by offset seconds
+func shiftResult(result *inference.Result, offset float32) {
+	result.TimestampStart += offset
+	result.TimestampEnd += offset
+	for i := range result.Words {
+		result.Words[i].Start += offset
+		result.Words[i].End += offset
+	}
 }
 
-func (s *Server) processAudioStream(ctx context.Context, sessionID string, audioCh <-chan []float32, resultCh chan<- *inference.Result, errorCh chan<- error) {
+// resultWarnings prefixes a result's warnings with the time range they apply to
+func resultWarnings(result *inference.Result) []string {
+	warnings := make([]string, 0, len(result.Warnings))
+	for _, warning := range result.Warnings {
+		warnings = append(warnings, fmt.Sprintf("[%.1fs-%.1fs] %s", result.TimestampStart, result.TimestampEnd, warning))
+	}
+	return warnings
+}
+
+// processAudioStream re-decodes the stream's growing audio buffer as chunks
+// arrive, sending final results as hypotheses stabilize and, when enabled,
+// interim results for the text still changing
+func (s *Server) processAudioStream(ctx context.Context, sessionID string, scheduler *inference.Scheduler, options inference.DecodeOptions, interim bool, audioCh <-chan []float32, resultCh chan<- *streamResult, errorCh chan<- error) {
 	defer close(resultCh)
-	defer close(errorCh)
 
-	// TODO: Implement actual streaming audio processing
-	// This will be implemented when we integrate streaming support
-	for audioData := range audioCh {
+	send := func(result *streamResult) bool {
+		if !result.final && !interim {
+			return true
+		}
+		select {
+		case resultCh <- result:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	state := &streamState{}
+	for {
+		var audioData []float32
 		select {
+		case data, ok := <-audioCh:
+			if !ok {
+				// The stream ended: whatever is still unstable is final now
+				if final := state.flush(); final != nil {
+					send(final)
+				}
+				return
+			}
+			audioData = data
 		case <-ctx.Done():
 			return
-		default:
-			// Process audio chunk
-			result := &inference.Result{
-				Transcription:  "Placeholder transcription",
-				Confidence:     1.0,
-				TimestampStart: 0,
-				TimestampEnd:   float32(len(audioData)) / 16000,
-				ProcessingTime: 0.1,
+		}
+		state.append(audioData)
+
+		// Take the chunks that arrived during the last decode as well, so a
+		// slow decode doesn't leave the stream further and further behind
+	drain:
+		for {
+			select {
+			case more, ok := <-audioCh:
+				if !ok {
+					break drain
+				}
+				state.append(more)
+			default:
+				break drain
+			}
+		}
+
+		result, err := scheduler.SubmitWindow(ctx, state.window(options))
+		if err != nil {
+			select {
+			case errorCh <- errors.Wrapf(err, "session %s", sessionID):
+			default:
+			}
+			return
+		}
+
+		for _, streamResult := range state.update(result) {
+			if !send(streamResult) {
+				return
 			}
-			resultCh <- result
 		}
 	}
 }
 
-func (s *Server) sendResults(stream pb.TranscriptionService_TranscribeStreamServer, resultCh <-chan *inference.Result, errorCh chan error) {
+func (s *Server) sendResults(ctx context.Context, stream pb.TranscriptionService_TranscribeStreamServer, model *models.ONNXModel, resultCh <-chan *streamResult, errorCh chan error) {
 	for {
 		select {
 		case result, ok := <-resultCh:
@@ -286,15 +831,14 @@ func (s *Server) sendResults(stream pb.TranscriptionService_TranscribeStreamServ
 			response := &pb.TranscribeResponse{
 				Text:       result.Transcription,
 				Confidence: result.Confidence,
-				Segments: []*pb.Segment{
-					{
-						Text:       result.Transcription,
-						StartTime:  result.TimestampStart,
-						EndTime:    result.TimestampEnd,
-						Confidence: result.Confidence,
-					},
-				},
+				Segments:   []*pb.Segment{convertSegment(result.Result)},
+				Warnings:   resultWarnings(result.Result),
+				IsFinal:    result.final,
+			}
+			for _, alternative := range result.Alternatives {
+				response.Alternatives = append(response.Alternatives, convertAlternative(alternative))
 			}
+			setModelMetadata(response, model)
 			if err := stream.Send(response); err != nil {
 				select {
 				case errorCh <- err:
@@ -302,21 +846,37 @@ func (s *Server) sendResults(stream pb.TranscriptionService_TranscribeStreamServ
 				}
 				return
 			}
-		case <-stream.Context().Done():
+		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// convertModelToInfo describes a model from its manifest, falling back to
+// its ID as the name when it has none; bare handles without a loaded model
+// are described by their ID alone
 func (s *Server) convertModelToInfo(model *models.ONNXModel) *pb.Model {
-	return &pb.Model{
-		Id:                  model.ID,
-		Name:                "Whisper Model", // TODO: Get actual name
-		Description:         "Automatic Speech Recognition Model",
-		Languages:           []string{"en-US", "pt-BR"}, // TODO: Get actual languages
-		Size:                0,                          // TODO: Get actual size
-		SupportsStreaming:   true,
-		SupportsDiarization: false,
-		Version:             "1.0", // TODO: Get actual version
+	info := &pb.Model{
+		Id:         model.ID,
+		Name:       model.ID,
+		SampleRate: models.DefaultSampleRate,
+	}
+	if model.Model == nil {
+		return info
+	}
+
+	info.Size = model.Info.Size
+	info.SupportsStreaming = model.Info.Capabilities.Streaming
+	info.SupportsDiarization = model.Info.Capabilities.Diarization
+	info.SupportsWordTimestamps = model.Info.Capabilities.WordTimestamps
+	info.SupportsTranslation = model.Info.Capabilities.Translation
+	info.Version = model.Info.Version
+	if manifest := model.Manifest; manifest != nil {
+		info.Name = manifest.Name
+		info.Description = manifest.Description
+		info.Languages = manifest.Languages
+		info.SampleRate = int32(manifest.SampleRate)
+		info.License = manifest.License
 	}
+	return info
 }
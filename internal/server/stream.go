@@ -0,0 +1,219 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/josealecrim/audiototext/internal/inference"
+)
+
+const (
+	// streamTrimSeconds is the buffer length past which audio behind the
+	// last final word is dropped from the buffer
+	streamTrimSeconds = 15
+	// maxContextWords bounds the final text kept as decoder context
+	maxContextWords = 200
+)
+
+// streamResult is a streaming result that is either final or interim
+type streamResult struct {
+	*inference.Result
+	// final is set once the text will no longer change
+	final bool
+}
+
+// streamState re-decodes a growing buffer of stream audio and stabilizes the
+// text with local agreement: words two consecutive hypotheses agree on
+// become final, and audio behind final words is trimmed from the buffer.
+// Word times are stream times in seconds.
+type streamState struct {
+	// buffer holds the audio not yet trimmed
+	buffer []float32
+	// bufferStart is the stream time of buffer[0] in seconds
+	bufferStart float32
+	// finalEnd is the end time of the last final result
+	finalEnd float32
+	// bufferFinal are the final words decoded from the current buffer
+	bufferFinal []string
+	// pending are the non-final words of the previous hypothesis
+	pending []inference.WordResult
+	// pendingSamples is the buffer length the previous hypothesis saw
+	pendingSamples int
+	// pendingFrom is the number of words before pending in the previous hypothesis
+	pendingFrom int
+	// context holds final words trimmed from the buffer, fed to the decoder
+	context []string
+	// last is the latest hypothesis
+	last *inference.Result
+}
+
+// append adds stream audio to the buffer
+func (s *streamState) append(audioData []float32) {
+	s.buffer = append(s.buffer, audioData...)
+}
+
+// window returns the buffer to decode with the trimmed final text as context
+func (s *streamState) window(options inference.DecodeOptions) *inference.Window {
+	if len(s.context) > 0 {
+		options.PreviousText = strings.Join(s.context, " ")
+	}
+	return &inference.Window{Audio: s.buffer, Options: options}
+}
+
+// update takes the hypothesis decoded from the current buffer and returns
+// the final result for newly agreed words, if any, followed by an interim
+// result for the words still unstable, if any
+func (s *streamState) update(result *inference.Result) []*streamResult {
+	s.last = result
+	words := make([]inference.WordResult, len(result.Words))
+	for i, word := range result.Words {
+		word.Start += s.bufferStart
+		word.End += s.bufferStart
+		words[i] = word
+	}
+
+	// Words already final are not emitted again
+	skip := len(s.bufferFinal)
+	if skip > len(words) {
+		skip = len(words)
+	}
+	hypothesis := words[skip:]
+
+	agreed := commonPrefix(s.pending, hypothesis)
+	var results []*streamResult
+	if agreed > 0 {
+		results = append(results, s.commit(result, hypothesis[:agreed], skip, hypothesis[agreed-1].End, agreed == len(hypothesis)))
+	}
+
+	unstable := hypothesis[agreed:]
+	bufferEnd := s.bufferStart + float32(len(s.buffer))/sampleRate
+
+	switch {
+	case len(s.buffer) >= windowDuration*sampleRate:
+		// The buffer fills the model's window: the rest has to be final now
+		if len(unstable) > 0 {
+			results = append(results, s.commit(result, unstable, skip+agreed, bufferEnd, true))
+			unstable = nil
+		}
+		s.trim(len(s.buffer))
+	case len(unstable) == 0:
+		// Both hypotheses saw the same words in the audio up to the previous
+		// decode, so that audio is done with
+		s.trim(s.pendingSamples)
+	case float32(len(s.buffer))/sampleRate > streamTrimSeconds && len(s.bufferFinal) > 0:
+		s.trim(int((s.finalEnd - s.bufferStart) * sampleRate))
+	}
+
+	s.pending = unstable
+	s.pendingSamples = len(s.buffer)
+	s.pendingFrom = skip + agreed
+
+	if len(unstable) > 0 {
+		results = append(results, &streamResult{Result: &inference.Result{
+			Transcription:  joinWords(unstable),
+			Confidence:     inference.MeanWordConfidence(unstable, result.Confidence),
+			TimestampStart: s.finalEnd,
+			TimestampEnd:   bufferEnd,
+			ProcessingTime: result.ProcessingTime,
+			Words:          unstable,
+		}})
+	}
+	return results
+}
+
+// flush makes the words still unstable final at the end of the stream
+func (s *streamState) flush() *streamResult {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	end := s.bufferStart + float32(len(s.buffer))/sampleRate
+	return s.commit(s.last, s.pending, s.pendingFrom, end, true)
+}
+
+// commit makes words of result, which follow its first from words, final up
+// to end and returns their result; last is set when the words end the hypothesis
+func (s *streamState) commit(result *inference.Result, words []inference.WordResult, from int, end float32, last bool) *streamResult {
+	if end < s.finalEnd {
+		end = s.finalEnd
+	}
+
+	// Alternatives cover the whole buffer; take the span of the final words
+	// from each, aligned by word count
+	var alternatives []inference.Alternative
+	seen := make(map[string]bool)
+	for _, alternative := range result.Alternatives {
+		altWords := strings.Fields(alternative.Text)
+		start, stop := from, from+len(words)
+		if start > len(altWords) {
+			start = len(altWords)
+		}
+		if last || stop > len(altWords) {
+			stop = len(altWords)
+		}
+		alternative.Text = strings.Join(altWords[start:stop], " ")
+		if seen[alternative.Text] {
+			continue
+		}
+		seen[alternative.Text] = true
+		alternatives = append(alternatives, alternative)
+	}
+
+	final := &streamResult{
+		Result: &inference.Result{
+			Transcription:  joinWords(words),
+			Confidence:     inference.MeanWordConfidence(words, result.Confidence),
+			TimestampStart: s.finalEnd,
+			TimestampEnd:   end,
+			ProcessingTime: result.ProcessingTime,
+			Warnings:       result.Warnings,
+			Words:          words,
+			Alternatives:   alternatives,
+		},
+		final: true,
+	}
+
+	s.finalEnd = end
+	for _, word := range words {
+		s.bufferFinal = append(s.bufferFinal, word.Word)
+	}
+	s.pending = nil
+	return final
+}
+
+// trim drops the first n samples of the buffer, moving the final words
+// decoded from them into the decoder context
+func (s *streamState) trim(n int) {
+	if n <= 0 {
+		return
+	}
+	if n > len(s.buffer) {
+		n = len(s.buffer)
+	}
+
+	s.buffer = append([]float32(nil), s.buffer[n:]...)
+	s.bufferStart += float32(n) / sampleRate
+
+	s.context = append(s.context, s.bufferFinal...)
+	if len(s.context) > maxContextWords {
+		s.context = s.context[len(s.context)-maxContextWords:]
+	}
+	s.bufferFinal = nil
+}
+
+// joinWords returns the text of words
+func joinWords(words []inference.WordResult) string {
+	texts := make([]string, len(words))
+	for i, word := range words {
+		texts[i] = word.Word
+	}
+	return strings.Join(texts, " ")
+}
+
+// commonPrefix returns the number of leading words a and b share, ignoring
+// case and surrounding punctuation
+func commonPrefix(a, b []inference.WordResult) int {
+	n := 0
+	for n < len(a) && n < len(b) && inference.NormalizeWord(a[n].Word) == inference.NormalizeWord(b[n].Word) {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,54 @@
+package audio_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/josealecrim/audiototext/internal/audio"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+// buildWAV encodes interleaved 16-bit samples as a PCM WAV file
+func buildWAV(samples []int16, channels, sampleRate int) []byte {
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, samples)
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+data.Len()))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1))
+	binary.Write(&buf, binary.LittleEndian, uint16(channels))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*channels*2))
+	binary.Write(&buf, binary.LittleEndian, uint16(channels*2))
+	binary.Write(&buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(data.Len()))
+	buf.Write(data.Bytes())
+	return buf.Bytes()
+}
+
+func TestDecodeWAVStereo(t *testing.T) {
+	// Left and right channels are averaged into mono
+	wav := buildWAV([]int16{16384, 0, -16384, -16384}, 2, 8000)
+
+	decoded, err := audio.DecodeWAV(wav)
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, 8000, decoded.SampleRate)
+	helpers.AssertEqual(t, 2, len(decoded.Samples))
+	helpers.AssertEqual(t, float32(0.25), decoded.Samples[0])
+	helpers.AssertEqual(t, float32(-0.5), decoded.Samples[1])
+}
+
+func TestResample(t *testing.T) {
+	in := &audio.Audio{Samples: make([]float32, 8000), SampleRate: 8000}
+
+	up := audio.Resample(in, 16000)
+	helpers.AssertEqual(t, 16000, len(up.Samples))
+
+	down := audio.Resample(&audio.Audio{Samples: make([]float32, 44100), SampleRate: 44100}, 16000)
+	helpers.AssertEqual(t, 16000, len(down.Samples))
+}
@@ -0,0 +1,146 @@
+package helpers
+
+import (
+	"sort"
+
+	"github.com/josealecrim/audiototext/internal/models"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ONNXTensor is an input or output of an ONNXGraph
+type ONNXTensor struct {
+	Name  string
+	Type  models.DataType
+	Shape []models.Dim
+}
+
+// ONNXGraph describes a minimal ONNX model for tests; Encode writes it as
+// a ModelProto that the registry can inspect
+type ONNXGraph struct {
+	IRVersion int64
+	Opset     int64
+	Inputs    []ONNXTensor
+	Outputs   []ONNXTensor
+	// Payload is stored as the raw data of a uint8 initializer, so models
+	// with different payloads have different checksums
+	Payload  []byte
+	Metadata map[string]string
+}
+
+// WhisperGraph returns a Whisper-like graph taking a [batch, 80, 3000]
+// log-mel spectrogram
+func WhisperGraph(payload []byte) ONNXGraph {
+	return ONNXGraph{
+		IRVersion: 8,
+		Opset:     17,
+		Inputs:    []ONNXTensor{{Name: "input_features", Type: models.DataFloat, Shape: []models.Dim{{Param: "batch", Dynamic: true}, {Value: 80}, {Value: 3000}}}},
+		Outputs:   []ONNXTensor{{Name: "last_hidden_state", Type: models.DataFloat, Shape: []models.Dim{{Param: "batch", Dynamic: true}, {Value: 1500}, {Value: 384}}}},
+		Payload:   payload,
+	}
+}
+
+// CTCGraph returns a wav2vec2-like graph taking a [batch, samples] waveform
+func CTCGraph(payload []byte) ONNXGraph {
+	return ONNXGraph{
+		IRVersion: 8,
+		Opset:     17,
+		Inputs:    []ONNXTensor{{Name: "input_values", Type: models.DataFloat, Shape: []models.Dim{{Param: "batch", Dynamic: true}, {Param: "samples", Dynamic: true}}}},
+		Outputs:   []ONNXTensor{{Name: "logits", Type: models.DataFloat, Shape: []models.Dim{{Param: "batch", Dynamic: true}, {Dynamic: true}, {Value: 32}}}},
+		Payload:   payload,
+	}
+}
+
+// ONNXModel returns the bytes of a Whisper-like model carrying payload
+func ONNXModel(payload []byte) []byte {
+	return WhisperGraph(payload).Encode()
+}
+
+// CTCModel returns the bytes of a CTC model carrying payload
+func CTCModel(payload []byte) []byte {
+	return CTCGraph(payload).Encode()
+}
+
+// Encode returns the graph as a serialized ModelProto
+func (g ONNXGraph) Encode() []byte {
+	var graph []byte
+	// A single node without fields; only the count is inspected
+	graph = appendMessage(graph, 1, nil)
+	graph = appendString(graph, 2, "test-graph")
+
+	var initializer []byte
+	initializer = protowire.AppendTag(initializer, 1, protowire.VarintType)
+	initializer = protowire.AppendVarint(initializer, uint64(len(g.Payload)))
+	initializer = protowire.AppendTag(initializer, 2, protowire.VarintType)
+	initializer = protowire.AppendVarint(initializer, uint64(models.DataUint8))
+	initializer = appendString(initializer, 8, "payload")
+	initializer = appendMessage(initializer, 9, g.Payload)
+	graph = appendMessage(graph, 5, initializer)
+
+	for _, input := range g.Inputs {
+		graph = appendMessage(graph, 11, encodeValueInfo(input))
+	}
+	for _, output := range g.Outputs {
+		graph = appendMessage(graph, 12, encodeValueInfo(output))
+	}
+
+	var model []byte
+	model = protowire.AppendTag(model, 1, protowire.VarintType)
+	model = protowire.AppendVarint(model, uint64(g.IRVersion))
+	model = appendString(model, 2, "audiototext-tests")
+	var opset []byte
+	opset = appendString(opset, 1, "")
+	opset = protowire.AppendTag(opset, 2, protowire.VarintType)
+	opset = protowire.AppendVarint(opset, uint64(g.Opset))
+	model = appendMessage(model, 8, opset)
+	// Sorted so that the same graph always encodes to the same bytes
+	keys := make([]string, 0, len(g.Metadata))
+	for key := range g.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var entry []byte
+		entry = appendString(entry, 1, key)
+		entry = appendString(entry, 2, g.Metadata[key])
+		model = appendMessage(model, 14, entry)
+	}
+	return appendMessage(model, 7, graph)
+}
+
+// encodeValueInfo encodes a tensor as a ValueInfoProto
+func encodeValueInfo(tensor ONNXTensor) []byte {
+	var shape []byte
+	for _, dim := range tensor.Shape {
+		var encoded []byte
+		switch {
+		case !dim.Dynamic:
+			encoded = protowire.AppendTag(encoded, 1, protowire.VarintType)
+			encoded = protowire.AppendVarint(encoded, uint64(dim.Value))
+		case dim.Param != "":
+			encoded = appendString(encoded, 2, dim.Param)
+		}
+		shape = appendMessage(shape, 1, encoded)
+	}
+
+	var tensorType []byte
+	tensorType = protowire.AppendTag(tensorType, 1, protowire.VarintType)
+	tensorType = protowire.AppendVarint(tensorType, uint64(tensor.Type))
+	if tensor.Shape != nil {
+		tensorType = appendMessage(tensorType, 2, shape)
+	}
+
+	var info []byte
+	info = appendString(info, 1, tensor.Name)
+	return appendMessage(info, 2, appendMessage(nil, 1, tensorType))
+}
+
+// appendMessage appends a length-delimited field
+func appendMessage(b []byte, num protowire.Number, value []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, value)
+}
+
+// appendString appends a string field
+func appendString(b []byte, num protowire.Number, value string) []byte {
+	return appendMessage(b, num, []byte(value))
+}
@@ -0,0 +1,50 @@
+package inference_test
+
+import (
+	"testing"
+
+	"github.com/josealecrim/audiototext/internal/inference"
+	"github.com/josealecrim/audiototext/internal/inference/fake"
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+func TestBeamSearchReturnsAlternatives(t *testing.T) {
+	backend := fake.New(fake.Config{})
+	backend.SetDefault(fake.Script{
+		Text:        "the cat sat",
+		Confidences: []float64{0.9, 0.6, 0.9},
+		Alternatives: []fake.Script{
+			{Text: "the bat sat", Confidences: []float64{0.9, 0.3, 0.9}},
+			{Text: "a cat sat", Confidences: []float64{0.25, 0.9, 0.9}},
+		},
+	})
+
+	manager := newFakeManager(t, backend)
+	defer manager.CloseAllSessions()
+	model := &models.ONNXModel{Model: &models.Model{Path: "models/fake.onnx"}, ID: "fake-model"}
+
+	result := transcribeWindow(t, manager, model, &inference.Window{
+		Audio:   tone(16000, 0.1),
+		Options: inference.DecodeOptions{BeamSize: 4, MaxAlternatives: 3},
+	})
+
+	helpers.AssertEqual(t, "the cat sat", result.Transcription)
+	helpers.AssertEqual(t, 3, len(result.Alternatives))
+
+	expected := []string{"the cat sat", "the bat sat", "a cat sat"}
+	for i, alternative := range result.Alternatives {
+		helpers.AssertEqual(t, expected[i], alternative.Text)
+		if i > 0 && alternative.Score > result.Alternatives[i-1].Score {
+			t.Errorf("alternative %d scores %.3f, above the one before it", i, alternative.Score)
+		}
+	}
+	assertNear(t, "best confidence", 0.787, float64(result.Alternatives[0].Confidence))
+
+	// Without MaxAlternatives only the best hypothesis is kept
+	single := transcribeWindow(t, manager, model, &inference.Window{
+		Audio:   tone(16000, 0.1),
+		Options: inference.DecodeOptions{BeamSize: 4},
+	})
+	helpers.AssertEqual(t, 0, len(single.Alternatives))
+}
@@ -0,0 +1,84 @@
+package inference_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/inference"
+	"github.com/josealecrim/audiototext/internal/inference/fake"
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/test/helpers"
+	"github.com/pkg/errors"
+)
+
+// modelOfSize returns a model whose weights file holds size bytes
+func modelOfSize(t *testing.T, id string, size int) *models.ONNXModel {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), id+".onnx")
+	helpers.AssertNoError(t, os.WriteFile(path, make([]byte, size), 0644))
+	return &models.ONNXModel{Model: &models.Model{Path: path}, ID: id}
+}
+
+func TestMemoryBudgetEvictsIdleSessions(t *testing.T) {
+	manager := newFakeManager(t, fake.New(fake.Config{}))
+	defer manager.CloseAllSessions()
+
+	first, second := modelOfSize(t, "first", 4<<20), modelOfSize(t, "second", 4<<20)
+	footprint := inference.EstimateFootprint(first)
+	manager.SetMemoryConfig(inference.MemoryConfig{Limit: footprint * 3 / 2, RetryAfter: 2 * time.Second})
+
+	helpers.WithTimeout(t, 10*time.Second, func(ctx context.Context) {
+		session, err := manager.Acquire(ctx, first)
+		helpers.AssertNoError(t, err)
+		helpers.AssertNoError(t, manager.Release(session))
+
+		// The idle session of the first model makes room for the second
+		busy, err := manager.Acquire(ctx, second)
+		helpers.AssertNoError(t, err)
+		helpers.AssertEqual(t, 0, manager.GetPoolStats()["first"].OpenSessions)
+		helpers.AssertEqual(t, footprint, manager.MemoryStatus().Reserved)
+
+		// Sessions in use are never evicted
+		_, err = manager.Acquire(ctx, first)
+		var exhausted *inference.MemoryExhaustedError
+		if !errors.As(err, &exhausted) {
+			t.Fatalf("expected a MemoryExhaustedError, got %v", err)
+		}
+		helpers.AssertEqual(t, 2*time.Second, exhausted.RetryAfter)
+		helpers.AssertEqual(t, footprint, exhausted.Required)
+
+		helpers.AssertNoError(t, manager.Release(busy))
+		session, err = manager.Acquire(ctx, first)
+		helpers.AssertNoError(t, err)
+		helpers.AssertNoError(t, manager.Release(session))
+	})
+}
+
+func TestMemoryBudgetRejectsModelsLargerThanBudget(t *testing.T) {
+	manager := newFakeManager(t, fake.New(fake.Config{}))
+	defer manager.CloseAllSessions()
+
+	model := modelOfSize(t, "large", 4<<20)
+	manager.SetMemoryConfig(inference.MemoryConfig{Limit: inference.EstimateFootprint(model) - 1})
+
+	helpers.WithTimeout(t, 10*time.Second, func(ctx context.Context) {
+		_, err := manager.CreateSession(ctx, model, nil, nil)
+		var exhausted *inference.MemoryExhaustedError
+		if !errors.As(err, &exhausted) {
+			t.Fatalf("expected a MemoryExhaustedError, got %v", err)
+		}
+		helpers.AssertEqual(t, time.Duration(0), exhausted.RetryAfter)
+		helpers.AssertEqual(t, int64(0), manager.MemoryStatus().Reserved)
+	})
+}
+
+func TestFootprintOfBareHandle(t *testing.T) {
+	bare := &models.ONNXModel{ID: "bare"}
+	if footprint := inference.EstimateFootprint(bare); footprint <= 0 {
+		t.Errorf("expected a bare handle to cost the session overhead, got %d", footprint)
+	}
+}
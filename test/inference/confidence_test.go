@@ -0,0 +1,78 @@
+package inference_test
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/inference"
+	"github.com/josealecrim/audiototext/internal/inference/fake"
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+// assertNear fails when got is further than 0.005 from want
+func assertNear(t *testing.T, name string, want, got float64) {
+	t.Helper()
+	if math.Abs(want-got) > 0.005 {
+		t.Errorf("%s: expected %.3f, got %.3f", name, want, got)
+	}
+}
+
+func TestCalibrators(t *testing.T) {
+	temperature := inference.TemperatureCalibrator{Temperature: 2}
+	assertNear(t, "temperature", 0.75, temperature.Calibrate(0.9))
+	assertNear(t, "temperature midpoint", 0.5, temperature.Calibrate(0.5))
+
+	isotonic, err := inference.NewIsotonicCalibrator([]inference.CalibrationPoint{
+		{Raw: 0.9, Calibrated: 0.8},
+		{Raw: 0.5, Calibrated: 0.2},
+	})
+	helpers.AssertNoError(t, err)
+	assertNear(t, "isotonic below", 0.2, isotonic.Calibrate(0.1))
+	assertNear(t, "isotonic between", 0.5, isotonic.Calibrate(0.7))
+	assertNear(t, "isotonic above", 0.8, isotonic.Calibrate(0.99))
+
+	if _, err := inference.NewIsotonicCalibrator([]inference.CalibrationPoint{{Raw: 0.1, Calibrated: 0.9}, {Raw: 0.2, Calibrated: 0.1}}); err == nil {
+		t.Error("expected a decreasing table to be rejected")
+	}
+}
+
+func TestWordConfidencesAreCalibrated(t *testing.T) {
+	env := helpers.SetupTestEnv(t)
+	helpers.AssertNoError(t, os.WriteFile(filepath.Join(env.ModelDir, "calibration.json"),
+		[]byte(`{"method": "temperature", "temperature": 2}`), 0644))
+
+	backend := fake.New(fake.Config{})
+	backend.SetDefault(fake.Script{Text: "alpha beta", Confidences: []float64{0.9, 0.5}})
+
+	manager := newFakeManager(t, backend)
+	defer manager.CloseAllSessions()
+
+	model := &models.ONNXModel{Model: &models.Model{Path: filepath.Join(env.ModelDir, "model.onnx")}, ID: "calibrated"}
+	helpers.WithTimeout(t, 10*time.Second, func(ctx context.Context) {
+		session, err := manager.CreateSession(ctx, model, nil, nil)
+		helpers.AssertNoError(t, err)
+
+		result, err := inference.NewInference(session).ProcessAudio(ctx, tone(16000, 0.1))
+		helpers.AssertNoError(t, err)
+
+		helpers.AssertEqual(t, 2, len(result.Words))
+		helpers.AssertEqual(t, "alpha", result.Words[0].Word)
+		assertNear(t, "first word", 0.75, float64(result.Words[0].Confidence))
+		assertNear(t, "second word", 0.5, float64(result.Words[1].Confidence))
+		assertNear(t, "word end", 1, float64(result.Words[1].End))
+
+		// The segment's raw confidence is the geometric mean of the token probabilities
+		assertNear(t, "segment", inference.TemperatureCalibrator{Temperature: 2}.Calibrate(math.Sqrt(0.45)), float64(result.Confidence))
+	})
+
+	// A broken calibration file keeps sessions from opening
+	helpers.AssertNoError(t, os.WriteFile(filepath.Join(env.ModelDir, "calibration.json"), []byte(`{"method": "platt"}`), 0644))
+	if _, err := manager.CreateSession(context.Background(), model, nil, nil); err == nil {
+		t.Error("expected an unknown calibration method to fail")
+	}
+}
@@ -0,0 +1,153 @@
+package inference_test
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/inference"
+	"github.com/josealecrim/audiototext/internal/inference/fake"
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+// ctcRuntime emits fixed frames, each a map of label to probability
+type ctcRuntime struct {
+	labels []string
+	frames []map[string]float64
+}
+
+func (r *ctcRuntime) SetExecutionProvider(provider string) error { return nil }
+func (r *ctcRuntime) HasGPUSupport() bool                        { return false }
+func (r *ctcRuntime) Close() error                               { return nil }
+func (r *ctcRuntime) Labels() []string                           { return r.labels }
+
+func (r *ctcRuntime) RunInference(ctx context.Context, samples []float32) (*inference.InferenceResult, error) {
+	return nil, nil
+}
+
+func (r *ctcRuntime) Emissions(ctx context.Context, waveforms [][]float32) ([]*inference.Emissions, error) {
+	emissions := make([]*inference.Emissions, len(waveforms))
+	for i := range waveforms {
+		rows := make([][]float32, len(r.frames))
+		for t, frame := range r.frames {
+			rows[t] = make([]float32, len(r.labels))
+			for id, label := range r.labels {
+				p, ok := frame[label]
+				if !ok {
+					p = 1e-6
+				}
+				rows[t][id] = float32(math.Log(p))
+			}
+		}
+		emissions[i] = &inference.Emissions{LogProbs: rows, FrameDuration: 0.02}
+	}
+	return emissions, nil
+}
+
+// ctcModel returns a CTC model stored in dir
+func ctcModel(dir string) *models.ONNXModel {
+	return &models.ONNXModel{
+		Model: &models.Model{Path: filepath.Join(dir, "model.onnx"), Info: models.ModelInfo{Type: models.TypeCTC}},
+		ID:    "wav2vec2",
+	}
+}
+
+// transcribeWindow runs one window through a session of model
+func transcribeWindow(t *testing.T, manager *inference.Manager, model *models.ONNXModel, window *inference.Window) *inference.Result {
+	t.Helper()
+
+	var result *inference.Result
+	helpers.WithTimeout(t, 10*time.Second, func(ctx context.Context) {
+		session, err := manager.CreateSession(ctx, model, nil, nil)
+		helpers.AssertNoError(t, err)
+		defer manager.CloseSession(session)
+
+		results, err := inference.NewInference(session).ProcessWindows(ctx, []*inference.Window{window})
+		helpers.AssertNoError(t, err)
+		result = results[0]
+	})
+	return result
+}
+
+func TestCTCDecodingWithFrameTimestamps(t *testing.T) {
+	backend := fake.New(fake.Config{})
+	backend.SetDefault(fake.Script{Text: "hello world", Confidences: []float64{0.9, 0.6}})
+
+	manager := newFakeManager(t, backend)
+	defer manager.CloseAllSessions()
+	model := ctcModel(t.TempDir())
+
+	for _, beamSize := range []int{1, 5} {
+		// 50 frames of 20 ms: "hello|world" puts one label every 50/11 frames
+		result := transcribeWindow(t, manager, model, &inference.Window{
+			Audio:   tone(16000, 0.1),
+			Options: inference.DecodeOptions{BeamSize: beamSize},
+		})
+
+		helpers.AssertEqual(t, "hello world", result.Transcription)
+		helpers.AssertEqual(t, 2, len(result.Words))
+		assertNear(t, "first word end", 0.38, float64(result.Words[0].End))
+		assertNear(t, "second word start", 0.54, float64(result.Words[1].Start))
+		assertNear(t, "first word confidence", 0.9, float64(result.Words[0].Confidence))
+		assertNear(t, "second word confidence", 0.6, float64(result.Words[1].Confidence))
+	}
+}
+
+func TestCTCLanguageModelRescoring(t *testing.T) {
+	dir := t.TempDir()
+	arpa := "\\data\\\nngram 1=3\n\n\\1-grams:\n-1.0\tcat\n-5.0\tkat\n-2.0\t<unk>\n\n\\end\\\n"
+	helpers.AssertNoError(t, os.WriteFile(filepath.Join(dir, "lm.arpa"), []byte(arpa), 0644))
+
+	// The acoustics slightly prefer "kat"
+	runtime := &ctcRuntime{
+		labels: []string{"<pad>", "|", "a", "c", "k", "t"},
+		frames: []map[string]float64{
+			{"k": 0.55, "c": 0.45},
+			{"<pad>": 1},
+			{"a": 1},
+			{"<pad>": 1},
+			{"t": 1},
+			{"<pad>": 1},
+		},
+	}
+	manager := newFakeManager(t, fake.New(fake.Config{}))
+	manager.SetRuntimeFactory(func(modelPath string) (inference.ONNXRuntime, error) { return runtime, nil })
+	defer manager.CloseAllSessions()
+	model := ctcModel(dir)
+
+	greedy := transcribeWindow(t, manager, model, &inference.Window{Audio: tone(1600, 0.1), Options: inference.DecodeOptions{BeamSize: 1}})
+	helpers.AssertEqual(t, "kat", greedy.Transcription)
+
+	rescored := transcribeWindow(t, manager, model, &inference.Window{Audio: tone(1600, 0.1), Options: inference.DecodeOptions{BeamSize: 4}})
+	helpers.AssertEqual(t, "cat", rescored.Transcription)
+}
+
+func TestARPABackoff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lm.arpa")
+	arpa := `\data\
+ngram 1=3
+ngram 2=1
+
+\1-grams:
+-1.0	the	-0.5
+-1.5	a	-0.3
+-2.0	cat
+
+\2-grams:
+-0.2	the cat
+
+\end\
+`
+	helpers.AssertNoError(t, os.WriteFile(path, []byte(arpa), 0644))
+
+	lm, err := inference.LoadARPA(path)
+	helpers.AssertNoError(t, err)
+
+	assertNear(t, "bigram", -0.2*math.Ln10, lm.Score([]string{"The"}, "cat"))
+	assertNear(t, "backoff", (-0.3-2.0)*math.Ln10, lm.Score([]string{"a"}, "cat"))
+	assertNear(t, "unknown", -10*math.Ln10, lm.Score(nil, "dog"))
+}
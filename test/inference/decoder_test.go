@@ -0,0 +1,110 @@
+package inference_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/josealecrim/audiototext/internal/hardware"
+	"github.com/josealecrim/audiototext/internal/inference"
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+// biasRuntime slightly prefers "joan" over "joão" and records the decoder input
+type biasRuntime struct {
+	scriptedRuntime
+	mu       sync.Mutex
+	sequence []int
+}
+
+// Byte-level token text: "Ġ" is a space and "Ã£" is the UTF-8 encoding of "ã"
+var biasTokens = []string{"<|startoftranscript|>", "<|endoftext|>", "<|startofprev|>", "ĠjoÃ£o", "Ġjoan", "Ġsilva"}
+
+func newBiasRuntime() *biasRuntime {
+	return &biasRuntime{scriptedRuntime: scriptedRuntime{vocab: inference.NewVocabulary(biasTokens)}}
+}
+
+func (r *biasRuntime) DecodeStep(ctx context.Context, encoded []*inference.EncoderOutput, tokens [][]int) ([][]float32, error) {
+	logProbs := make([][]float32, len(tokens))
+	for i, sequence := range tokens {
+		r.mu.Lock()
+		r.sequence = sequence
+		r.mu.Unlock()
+
+		generated := 0
+		for j, token := range sequence {
+			if token == 0 {
+				generated = len(sequence) - j - 1
+			}
+		}
+
+		logProbs[i] = []float32{-10, -10, -10, -10, -10, -10}
+		if generated == 0 {
+			logProbs[i][4] = -1.0
+			logProbs[i][3] = -1.5
+		} else {
+			logProbs[i][1] = 0
+		}
+	}
+	return logProbs, nil
+}
+
+func TestPromptAndPhraseHints(t *testing.T) {
+	detector, err := hardware.NewDetector()
+	helpers.AssertNoError(t, err)
+
+	manager := inference.NewManager(detector)
+	defer manager.CloseAllSessions()
+
+	runtime := newBiasRuntime()
+	manager.SetRuntimeFactory(func(modelPath string) (inference.ONNXRuntime, error) {
+		return runtime, nil
+	})
+
+	model := &models.ONNXModel{Model: &models.Model{Path: "models/whisper.onnx"}, ID: "test-model"}
+	ctx := context.Background()
+	session, err := manager.CreateSession(ctx, model, nil, nil)
+	helpers.AssertNoError(t, err)
+	defer manager.CloseSession(session)
+	inf := inference.NewInference(session)
+
+	audio := make([]float32, 16000)
+
+	// Without hints the decoder follows the model
+	results, err := inf.ProcessWindows(ctx, []*inference.Window{{Audio: audio, Options: inference.DecodeOptions{BeamSize: 3}}})
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "joan", results[0].Transcription)
+
+	// A hint outweighs the small margin, and the prompt precedes the transcript
+	results, err = inf.ProcessWindows(ctx, []*inference.Window{{Audio: audio, Options: inference.DecodeOptions{
+		BeamSize: 3,
+		Prompt:   "silva",
+		Hints:    []inference.PhraseHint{{Phrase: "joão", Boost: 2}},
+	}}})
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "joão", results[0].Transcription)
+
+	runtime.mu.Lock()
+	prefix := runtime.sequence[:3]
+	runtime.mu.Unlock()
+	helpers.AssertEqual(t, 2, prefix[0])
+	helpers.AssertEqual(t, 5, prefix[1])
+	helpers.AssertEqual(t, 0, prefix[2])
+
+	// A negative hint suppresses the model's favorite
+	results, err = inf.ProcessWindows(ctx, []*inference.Window{{Audio: audio, Options: inference.DecodeOptions{
+		BeamSize: 3,
+		Hints:    []inference.PhraseHint{{Phrase: "joan", Boost: -2}},
+	}}})
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "joão", results[0].Transcription)
+
+	// Between a suppressing and a favoring hint on the same token, the larger boost wins
+	results, err = inf.ProcessWindows(ctx, []*inference.Window{{Audio: audio, Options: inference.DecodeOptions{
+		BeamSize: 3,
+		Hints:    []inference.PhraseHint{{Phrase: "joan", Boost: -2}, {Phrase: "joan", Boost: 1}},
+	}}})
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "joan", results[0].Transcription)
+}
@@ -0,0 +1,111 @@
+package inference_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/hardware"
+	"github.com/josealecrim/audiototext/internal/inference"
+	"github.com/josealecrim/audiototext/internal/inference/fake"
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/test/helpers"
+	"github.com/pkg/errors"
+)
+
+// newFakeManager creates a manager whose sessions run on backend
+func newFakeManager(t *testing.T, backend *fake.Backend) *inference.Manager {
+	t.Helper()
+
+	detector, err := hardware.NewDetector()
+	helpers.AssertNoError(t, err)
+
+	manager := inference.NewManager(detector)
+	manager.SetRuntimeFactory(backend.Factory())
+	return manager
+}
+
+// tone returns n samples filled with value
+func tone(n int, value float32) []float32 {
+	samples := make([]float32, n)
+	for i := range samples {
+		samples[i] = value
+	}
+	return samples
+}
+
+func TestFakeBackendScriptsBatchedWindows(t *testing.T) {
+	backend := fake.New(fake.Config{Latency: 10 * time.Millisecond})
+	first, second := tone(16000, 0.1), tone(16000, 0.2)
+	backend.AddFingerprint(first, fake.Script{Text: "olá mundo", Confidences: []float64{0.9, 0.8}})
+	backend.AddFingerprint(second, fake.Script{Text: "bom dia"})
+	backend.AddRange(1, 8000, fake.Script{Text: "short clip"})
+
+	manager := newFakeManager(t, backend)
+	defer manager.CloseAllSessions()
+
+	model := &models.ONNXModel{Model: &models.Model{Path: "models/fake.onnx"}, ID: "fake-model"}
+	helpers.WithTimeout(t, 10*time.Second, func(ctx context.Context) {
+		scheduler, err := manager.GetScheduler(ctx, model)
+		helpers.AssertNoError(t, err)
+
+		inputs := [][]float32{first, second, tone(4000, 0.3), tone(12000, 0.4)}
+		expected := []string{"olá mundo", "bom dia", "short clip", ""}
+
+		results := make([]*inference.Result, len(inputs))
+		errs := make(chan error, len(inputs))
+		for i, samples := range inputs {
+			go func(i int, samples []float32) {
+				var err error
+				results[i], err = scheduler.Submit(ctx, samples)
+				errs <- err
+			}(i, samples)
+		}
+		for range inputs {
+			helpers.AssertNoError(t, <-errs)
+		}
+
+		for i, result := range results {
+			helpers.AssertEqual(t, expected[i], result.Transcription)
+		}
+
+		// The decoder's confidence is the geometric mean of the token probabilities
+		if got := results[0].Confidence; got < 0.84 || got > 0.86 {
+			t.Errorf("expected confidence near 0.85, got %.3f", got)
+		}
+	})
+
+	if backend.Calls() == 0 {
+		t.Error("expected the backend to be called")
+	}
+}
+
+func TestFakeBackendInjectsFailures(t *testing.T) {
+	scriptErr := errors.New("corrupted window")
+	backend := fake.New(fake.Config{FailEvery: 2})
+	backend.SetDefault(fake.Script{Text: "fine"})
+	backend.AddRange(100, 100, fake.Script{Err: scriptErr})
+
+	manager := newFakeManager(t, backend)
+	defer manager.CloseAllSessions()
+
+	model := &models.ONNXModel{Model: &models.Model{Path: "models/fake.onnx"}, ID: "fake-model"}
+	helpers.WithTimeout(t, 10*time.Second, func(ctx context.Context) {
+		session, err := manager.CreateSession(ctx, model, nil, nil)
+		helpers.AssertNoError(t, err)
+		processor := inference.NewInference(session)
+
+		result, err := processor.ProcessAudio(ctx, tone(1600, 0.5))
+		helpers.AssertNoError(t, err)
+		helpers.AssertEqual(t, "fine", result.Transcription)
+
+		// Every second call fails
+		if _, err := processor.ProcessAudio(ctx, tone(1600, 0.5)); errors.Cause(err) != fake.ErrInjected {
+			t.Errorf("expected injected failure, got %v", err)
+		}
+
+		if _, err := processor.ProcessAudio(ctx, tone(100, 0.5)); errors.Cause(err) != scriptErr {
+			t.Errorf("expected scripted failure, got %v", err)
+		}
+	})
+}
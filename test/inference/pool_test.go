@@ -0,0 +1,127 @@
+package inference_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/hardware"
+	"github.com/josealecrim/audiototext/internal/inference"
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+func TestSessionPoolAcquireRelease(t *testing.T) {
+	detector, err := hardware.NewDetector()
+	helpers.AssertNoError(t, err)
+
+	manager := inference.NewManager(detector)
+	defer manager.CloseAllSessions()
+
+	manager.SetPoolConfig(inference.PoolConfig{
+		MinSessions: 1,
+		MaxSessions: 2,
+		IdleTimeout: time.Minute,
+	})
+
+	model := &models.ONNXModel{Model: &models.Model{Path: "models/whisper.onnx"}, ID: "test-model"}
+	ctx := context.Background()
+
+	first, err := manager.Acquire(ctx, model)
+	helpers.AssertNoError(t, err)
+	second, err := manager.Acquire(ctx, model)
+	helpers.AssertNoError(t, err)
+
+	if first == second {
+		t.Fatal("concurrent acquires should return distinct sessions")
+	}
+
+	// The pool is at capacity, so a third acquire has to wait
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := manager.Acquire(waitCtx, model); err == nil {
+		t.Fatal("acquire should block while the pool is at capacity")
+	}
+
+	helpers.AssertNoError(t, manager.Release(first))
+
+	// A released session is reused instead of opening a new one
+	third, err := manager.Acquire(ctx, model)
+	helpers.AssertNoError(t, err)
+	if third != first {
+		t.Error("expected the released session to be reused")
+	}
+
+	stats := manager.GetPoolStats()[model.ID]
+	helpers.AssertEqual(t, 2, stats.OpenSessions)
+	helpers.AssertEqual(t, 2, stats.InUseSessions)
+
+	helpers.AssertNoError(t, manager.Release(second))
+	helpers.AssertNoError(t, manager.Release(third))
+
+	// Idle pooled sessions still hold the model's files
+	helpers.AssertEqual(t, true, manager.HasSessions(model.ID))
+	helpers.AssertNoError(t, manager.ClosePool(model.ID))
+	helpers.AssertEqual(t, false, manager.HasSessions(model.ID))
+}
+
+func TestReplacedModelDrainsItsPool(t *testing.T) {
+	detector, err := hardware.NewDetector()
+	helpers.AssertNoError(t, err)
+
+	manager := inference.NewManager(detector)
+	defer manager.CloseAllSessions()
+	manager.SetPoolConfig(inference.PoolConfig{MinSessions: 1, MaxSessions: 2, IdleTimeout: time.Minute})
+
+	var mu sync.Mutex
+	var loaded []string
+	manager.SetRuntimeFactory(func(modelPath string) (inference.ONNXRuntime, error) {
+		mu.Lock()
+		loaded = append(loaded, modelPath)
+		mu.Unlock()
+		return newScriptedRuntime(), nil
+	})
+
+	old := &models.ONNXModel{Model: &models.Model{Path: "models/old/whisper.onnx"}, ID: "test-model"}
+	restored := &models.ONNXModel{Model: &models.Model{Path: "models/restored/whisper.onnx"}, ID: "test-model"}
+	ctx := context.Background()
+
+	scheduler, err := manager.GetScheduler(ctx, old)
+	helpers.AssertNoError(t, err)
+	inFlight, err := manager.Acquire(ctx, old)
+	helpers.AssertNoError(t, err)
+
+	helpers.AssertNoError(t, manager.ReplaceModel(restored))
+
+	// The session in use keeps the version it loaded until it is released
+	if inFlight.Model != old {
+		t.Error("expected the session in use to keep the replaced model")
+	}
+	helpers.AssertEqual(t, true, manager.HasSessions(old.ID))
+
+	// The running scheduler and callers holding the old handle get the new version
+	_, err = scheduler.Submit(ctx, make([]float32, 16000))
+	helpers.AssertNoError(t, err)
+	session, err := manager.Acquire(ctx, old)
+	helpers.AssertNoError(t, err)
+	if session.Model != restored {
+		t.Error("expected new sessions to load the restored model")
+	}
+	helpers.AssertNoError(t, manager.Release(session))
+	current, err := manager.GetScheduler(ctx, restored)
+	helpers.AssertNoError(t, err)
+	if current != scheduler {
+		t.Error("expected the scheduler to survive the replacement")
+	}
+
+	mu.Lock()
+	helpers.AssertEqual(t, "models/restored/whisper.onnx", loaded[len(loaded)-1])
+	mu.Unlock()
+
+	// Releasing the last old session closes it instead of keeping it warm
+	helpers.AssertNoError(t, manager.Release(inFlight))
+	helpers.AssertEqual(t, 1, manager.GetPoolStats()[old.ID].OpenSessions)
+	helpers.AssertNoError(t, manager.ClosePool(old.ID))
+	helpers.AssertEqual(t, false, manager.HasSessions(old.ID))
+}
@@ -0,0 +1,117 @@
+package inference_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/josealecrim/audiototext/internal/hardware"
+	"github.com/josealecrim/audiototext/internal/inference"
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+// flakyRuntime refuses CUDA at init and fails OpenVINO at inference time;
+// requestErr, when set, fails every inference the same way on any provider
+type flakyRuntime struct {
+	provider   string
+	requestErr error
+}
+
+func (r *flakyRuntime) SetExecutionProvider(provider string) error {
+	if provider == string(inference.CUDAExecutionProvider) {
+		return errors.New("CUDA driver not found")
+	}
+	r.provider = provider
+	return nil
+}
+
+func (r *flakyRuntime) HasGPUSupport() bool { return false }
+
+func (r *flakyRuntime) RunInference(ctx context.Context, samples []float32) (*inference.InferenceResult, error) {
+	if r.requestErr != nil {
+		return nil, r.requestErr
+	}
+	if r.provider == string(inference.OpenVINOExecutionProvider) {
+		return nil, &inference.ProviderError{Err: errors.New("device lost")}
+	}
+	return &inference.InferenceResult{Text: r.provider}, nil
+}
+
+func (r *flakyRuntime) Close() error { return nil }
+
+func TestProviderFallbackChain(t *testing.T) {
+	detector, err := hardware.NewDetector()
+	helpers.AssertNoError(t, err)
+
+	manager := inference.NewManager(detector)
+	defer manager.CloseAllSessions()
+	manager.SetRuntimeFactory(func(modelPath string) (inference.ONNXRuntime, error) {
+		return &flakyRuntime{}, nil
+	})
+
+	model := &models.ONNXModel{Model: &models.Model{Path: "models/whisper.onnx"}, ID: "test-model"}
+	config := &inference.SessionConfig{
+		ExecutionProviders: []inference.ExecutionProvider{
+			inference.CUDAExecutionProvider,
+			inference.OpenVINOExecutionProvider,
+			inference.CPUExecutionProvider,
+		},
+	}
+
+	ctx := context.Background()
+	session, err := manager.CreateSession(ctx, model, config, nil)
+	helpers.AssertNoError(t, err)
+	defer manager.CloseSession(session)
+
+	// CUDA fails to initialize, so the session starts on OpenVINO
+	provider, fallbacks := session.ProviderInfo()
+	helpers.AssertEqual(t, inference.OpenVINOExecutionProvider, provider)
+	helpers.AssertEqual(t, 1, len(fallbacks))
+	helpers.AssertEqual(t, "init", fallbacks[0].Stage)
+
+	// OpenVINO fails at runtime, so inference is retried on CPU
+	result, err := inference.NewInference(session).ProcessAudio(ctx, make([]float32, 16000))
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, string(inference.CPUExecutionProvider), result.Transcription)
+
+	provider, fallbacks = session.ProviderInfo()
+	helpers.AssertEqual(t, inference.CPUExecutionProvider, provider)
+	helpers.AssertEqual(t, 2, len(fallbacks))
+	helpers.AssertEqual(t, "runtime", fallbacks[1].Stage)
+	helpers.AssertEqual(t, inference.OpenVINOExecutionProvider, fallbacks[1].Provider)
+}
+
+func TestRequestErrorsDoNotFallBack(t *testing.T) {
+	detector, err := hardware.NewDetector()
+	helpers.AssertNoError(t, err)
+
+	manager := inference.NewManager(detector)
+	defer manager.CloseAllSessions()
+	requestErr := errors.New("malformed input")
+	manager.SetRuntimeFactory(func(modelPath string) (inference.ONNXRuntime, error) {
+		return &flakyRuntime{requestErr: requestErr}, nil
+	})
+
+	model := &models.ONNXModel{Model: &models.Model{Path: "models/whisper.onnx"}, ID: "test-model"}
+	config := &inference.SessionConfig{
+		ExecutionProviders: []inference.ExecutionProvider{
+			inference.OpenVINOExecutionProvider,
+			inference.CPUExecutionProvider,
+		},
+	}
+
+	ctx := context.Background()
+	session, err := manager.CreateSession(ctx, model, config, nil)
+	helpers.AssertNoError(t, err)
+	defer manager.CloseSession(session)
+
+	// The request's own error comes back and the session stays on its provider
+	_, err = inference.NewInference(session).ProcessAudio(ctx, make([]float32, 16000))
+	if !errors.Is(err, requestErr) {
+		t.Fatalf("expected the request error, got %v", err)
+	}
+	provider, fallbacks := session.ProviderInfo()
+	helpers.AssertEqual(t, inference.OpenVINOExecutionProvider, provider)
+	helpers.AssertEqual(t, 0, len(fallbacks))
+}
@@ -0,0 +1,85 @@
+package inference_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/josealecrim/audiototext/internal/hardware"
+	"github.com/josealecrim/audiototext/internal/inference"
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+// silentRuntime decodes to a low-confidence word while predicting no speech
+type silentRuntime struct {
+	scriptedRuntime
+}
+
+func (r *silentRuntime) DecodeStep(ctx context.Context, encoded []*inference.EncoderOutput, tokens [][]int) ([][]float32, error) {
+	logProbs, err := r.scriptedRuntime.DecodeStep(ctx, encoded, tokens)
+	for i, sequence := range tokens {
+		if len(sequence) == 1 {
+			logProbs[i][2] = -0.1 // <|nospeech|>
+			logProbs[i][3] = -2   // "Ġuh", still the best text token
+		}
+	}
+	return logProbs, err
+}
+
+func runSafeguarded(t *testing.T, runtime inference.ONNXRuntime, language string) *inference.Result {
+	t.Helper()
+
+	detector, err := hardware.NewDetector()
+	helpers.AssertNoError(t, err)
+
+	manager := inference.NewManager(detector)
+	t.Cleanup(func() { manager.CloseAllSessions() })
+	manager.SetRuntimeFactory(func(modelPath string) (inference.ONNXRuntime, error) {
+		return runtime, nil
+	})
+
+	model := &models.ONNXModel{Model: &models.Model{Path: "models/whisper.onnx"}, ID: "test-model"}
+	ctx := context.Background()
+	session, err := manager.CreateSession(ctx, model, nil, nil)
+	helpers.AssertNoError(t, err)
+	t.Cleanup(func() { manager.CloseSession(session) })
+
+	results, err := inference.NewInference(session).ProcessWindows(ctx, []*inference.Window{{
+		Audio:   make([]float32, 16000),
+		Options: inference.DecodeOptions{Language: language},
+	}})
+	helpers.AssertNoError(t, err)
+	return results[0]
+}
+
+func TestSafeguardsTruncateRepetition(t *testing.T) {
+	tokens := []string{"<|startoftranscript|>", "<|endoftext|>", "Ġthank", "Ġyou"}
+	result := runSafeguarded(t, newScriptedRuntimeFor(tokens, []int{2, 3, 2, 3, 2, 3, 2, 3, 1}), "en-US")
+
+	helpers.AssertEqual(t, "thank you", result.Transcription)
+	if len(result.Warnings) != 1 || !strings.HasPrefix(result.Warnings[0], "repetition:") {
+		t.Errorf("expected a repetition warning, got %v", result.Warnings)
+	}
+}
+
+func TestSafeguardsRemoveHallucination(t *testing.T) {
+	tokens := []string{"<|startoftranscript|>", "<|endoftext|>", "ĠObrigado", "Ġpor", "Ġassistir", "."}
+	result := runSafeguarded(t, newScriptedRuntimeFor(tokens, []int{2, 3, 4, 5, 1}), "pt-BR")
+
+	helpers.AssertEqual(t, "", result.Transcription)
+	if len(result.Warnings) != 1 || !strings.HasPrefix(result.Warnings[0], "hallucination:") {
+		t.Errorf("expected a hallucination warning, got %v", result.Warnings)
+	}
+}
+
+func TestSafeguardsGateNoSpeech(t *testing.T) {
+	tokens := []string{"<|startoftranscript|>", "<|endoftext|>", "<|nospeech|>", "Ġuh"}
+	runtime := &silentRuntime{scriptedRuntime: *newScriptedRuntimeFor(tokens, []int{3, 1})}
+	result := runSafeguarded(t, runtime, "en")
+
+	helpers.AssertEqual(t, "", result.Transcription)
+	if len(result.Warnings) != 1 || !strings.HasPrefix(result.Warnings[0], "no_speech:") {
+		t.Errorf("expected a no-speech warning, got %v", result.Warnings)
+	}
+}
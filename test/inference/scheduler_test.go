@@ -0,0 +1,178 @@
+package inference_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/hardware"
+	"github.com/josealecrim/audiototext/internal/inference"
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+func newTestScheduler(t *testing.T, batchConfig inference.BatchConfig) *inference.Scheduler {
+	detector, err := hardware.NewDetector()
+	helpers.AssertNoError(t, err)
+
+	manager := inference.NewManager(detector)
+	t.Cleanup(func() { manager.CloseAllSessions() })
+
+	model := &models.ONNXModel{Model: &models.Model{Path: "models/whisper.onnx"}, ID: "test-model"}
+	session, err := manager.CreateSession(context.Background(), model, nil, &batchConfig)
+	helpers.AssertNoError(t, err)
+	t.Cleanup(func() { manager.CloseSession(session) })
+
+	return inference.NewScheduler(model.ID, inference.NewInference(session).ProcessWindows, batchConfig, 2)
+}
+
+func TestSchedulerRoutesResults(t *testing.T) {
+	scheduler := newTestScheduler(t, inference.BatchConfig{
+		MaxBatchSize:        8,
+		DynamicBatching:     true,
+		MaxLatencyMs:        20,
+		PreferredBatchSizes: []int{1, 2, 4, 8},
+	})
+	defer scheduler.Stop()
+
+	helpers.WithTimeout(t, 10*time.Second, func(ctx context.Context) {
+		// Windows of different lengths let us check each caller gets its own result
+		numWindows := 11
+		results := make([]*inference.Result, numWindows)
+		errs := make([]error, numWindows)
+
+		var wg sync.WaitGroup
+		for i := 0; i < numWindows; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], errs[i] = scheduler.Submit(ctx, make([]float32, (i+1)*1600))
+			}(i)
+		}
+		wg.Wait()
+
+		for i := 0; i < numWindows; i++ {
+			helpers.AssertNoError(t, errs[i])
+			expected := float32((i+1)*1600) / 16000
+			if results[i].TimestampEnd != expected {
+				t.Errorf("window %d: expected end %.2f, got %.2f", i, expected, results[i].TimestampEnd)
+			}
+		}
+
+		if depth := scheduler.QueueDepth(); depth != 0 {
+			t.Errorf("expected empty queue, got %d", depth)
+		}
+	})
+}
+
+func TestSchedulerStop(t *testing.T) {
+	scheduler := newTestScheduler(t, inference.BatchConfig{
+		MaxBatchSize:    4,
+		DynamicBatching: true,
+		MaxLatencyMs:    10,
+	})
+	scheduler.Stop()
+
+	_, err := scheduler.Submit(context.Background(), make([]float32, 16000))
+	if err != inference.ErrSchedulerStopped {
+		t.Errorf("expected ErrSchedulerStopped, got %v", err)
+	}
+}
+
+// countingRuntime runs a whole window per call and counts the calls
+type countingRuntime struct {
+	calls *int64
+}
+
+func (r *countingRuntime) SetExecutionProvider(provider string) error { return nil }
+
+func (r *countingRuntime) HasGPUSupport() bool { return false }
+
+func (r *countingRuntime) RunInference(ctx context.Context, samples []float32) (*inference.InferenceResult, error) {
+	atomic.AddInt64(r.calls, 1)
+	return &inference.InferenceResult{Text: "hello", Confidence: 1}, nil
+}
+
+func (r *countingRuntime) Close() error { return nil }
+
+// submitConcurrently submits n one second windows at once and waits for them
+func submitConcurrently(t *testing.T, ctx context.Context, scheduler *inference.Scheduler, n int) {
+	t.Helper()
+
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := scheduler.Submit(ctx, make([]float32, 16000))
+			errs <- err
+		}()
+	}
+	for i := 0; i < n; i++ {
+		helpers.AssertNoError(t, <-errs)
+	}
+}
+
+func TestSchedulerDoesNotRunPaddedWindows(t *testing.T) {
+	detector, err := hardware.NewDetector()
+	helpers.AssertNoError(t, err)
+
+	manager := inference.NewManager(detector)
+	defer manager.CloseAllSessions()
+	var calls int64
+	manager.SetRuntimeFactory(func(modelPath string) (inference.ONNXRuntime, error) {
+		return &countingRuntime{calls: &calls}, nil
+	})
+	manager.SetBatchConfig(inference.BatchConfig{
+		MaxBatchSize:        4,
+		DynamicBatching:     true,
+		MaxLatencyMs:        50,
+		PreferredBatchSizes: []int{4},
+	})
+
+	model := &models.ONNXModel{Model: &models.Model{Path: "models/whisper.onnx"}, ID: "test-model"}
+	helpers.WithTimeout(t, 10*time.Second, func(ctx context.Context) {
+		scheduler, err := manager.GetScheduler(ctx, model)
+		helpers.AssertNoError(t, err)
+
+		// Every batch is padded to 4 windows, but only the submitted ones run
+		submitConcurrently(t, ctx, scheduler, 3)
+		helpers.AssertEqual(t, int64(3), atomic.LoadInt64(&calls))
+	})
+}
+
+func TestSchedulerStatsCountOnlySubmittedWindows(t *testing.T) {
+	detector, err := hardware.NewDetector()
+	helpers.AssertNoError(t, err)
+
+	manager := inference.NewManager(detector)
+	defer manager.CloseAllSessions()
+	var calls int64
+	manager.SetRuntimeFactory(func(modelPath string) (inference.ONNXRuntime, error) {
+		return &countingRuntime{calls: &calls}, nil
+	})
+	manager.SetBatchConfig(inference.BatchConfig{
+		MaxBatchSize:        8,
+		DynamicBatching:     true,
+		MaxLatencyMs:        50,
+		PreferredBatchSizes: []int{8},
+	})
+
+	model := &models.ONNXModel{Model: &models.Model{Path: "models/whisper.onnx"}, ID: "test-model"}
+	helpers.WithTimeout(t, 10*time.Second, func(ctx context.Context) {
+		scheduler, err := manager.GetScheduler(ctx, model)
+		helpers.AssertNoError(t, err)
+		submitConcurrently(t, ctx, scheduler, 3)
+	})
+
+	stats := manager.GetStats()[model.ID]
+	helpers.AssertEqual(t, int64(3), stats.TotalInferences)
+	helpers.AssertEqual(t, 3.0, stats.AudioSecondsProcessed)
+	if stats.AverageBatchSize > 3 {
+		t.Errorf("expected at most 3 windows per batch, got %.2f", stats.AverageBatchSize)
+	}
+	expectedFactor := stats.TotalProcessingTime / 3
+	if diff := stats.RealTimeFactor - expectedFactor; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("expected a real time factor of %g, got %g", expectedFactor, stats.RealTimeFactor)
+	}
+}
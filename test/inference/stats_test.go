@@ -0,0 +1,51 @@
+package inference_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/josealecrim/audiototext/internal/hardware"
+	"github.com/josealecrim/audiototext/internal/inference"
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+func TestInferenceStats(t *testing.T) {
+	detector, err := hardware.NewDetector()
+	helpers.AssertNoError(t, err)
+
+	manager := inference.NewManager(detector)
+	defer manager.CloseAllSessions()
+
+	model := &models.ONNXModel{Model: &models.Model{Path: "models/whisper.onnx"}, ID: "test-model"}
+	session, err := manager.CreateSession(context.Background(), model, nil, nil)
+	helpers.AssertNoError(t, err)
+	defer manager.CloseSession(session)
+
+	inf := inference.NewInference(session)
+	ctx := context.Background()
+
+	// One single window and one batch of three, one second each
+	_, err = inf.ProcessAudio(ctx, make([]float32, 16000))
+	helpers.AssertNoError(t, err)
+	_, err = inf.ProcessBatch(ctx, [][]float32{make([]float32, 16000), make([]float32, 16000), make([]float32, 16000)})
+	helpers.AssertNoError(t, err)
+
+	stats := inf.GetStats()
+	helpers.AssertEqual(t, int64(4), stats.TotalInferences)
+	helpers.AssertEqual(t, int64(2), stats.TotalBatches)
+	helpers.AssertEqual(t, 2.0, stats.AverageBatchSize)
+	helpers.AssertEqual(t, 4.0, stats.AudioSecondsProcessed)
+
+	if stats.LatencyP99 < stats.LatencyP50 {
+		t.Errorf("p99 %.6f should not be below p50 %.6f", stats.LatencyP99, stats.LatencyP50)
+	}
+	if stats.RealTimeFactor <= 0 {
+		t.Errorf("expected a positive real-time factor, got %f", stats.RealTimeFactor)
+	}
+
+	// Input tensors are released once inference returns, but the peak remembers them
+	if stats.PeakMemoryUsage < stats.CurrentMemoryUsage+3*16000*4 {
+		t.Errorf("peak %d should cover the batch input tensor on top of current %d", stats.PeakMemoryUsage, stats.CurrentMemoryUsage)
+	}
+}
@@ -0,0 +1,140 @@
+package inference_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/josealecrim/audiototext/internal/hardware"
+	"github.com/josealecrim/audiototext/internal/inference"
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+// scriptedRuntime decodes every window to the same token sequence
+type scriptedRuntime struct {
+	vocab  *inference.Vocabulary
+	size   int
+	script []int
+}
+
+func newScriptedRuntime() *scriptedRuntime {
+	return newScriptedRuntimeFor([]string{"<|startoftranscript|>", "<|endoftext|>", "Ġhello", "Ġworld"}, []int{2, 3, 1})
+}
+
+func newScriptedRuntimeFor(tokens []string, script []int) *scriptedRuntime {
+	return &scriptedRuntime{vocab: inference.NewVocabulary(tokens), size: len(tokens), script: script}
+}
+
+func (r *scriptedRuntime) SetExecutionProvider(provider string) error { return nil }
+func (r *scriptedRuntime) HasGPUSupport() bool                        { return false }
+func (r *scriptedRuntime) Close() error                               { return nil }
+func (r *scriptedRuntime) Tokenizer() inference.Tokenizer             { return r.vocab }
+
+func (r *scriptedRuntime) RunInference(ctx context.Context, samples []float32) (*inference.InferenceResult, error) {
+	return nil, nil
+}
+
+func (r *scriptedRuntime) Encode(ctx context.Context, features []*inference.Features) ([]*inference.EncoderOutput, error) {
+	outputs := make([]*inference.EncoderOutput, len(features))
+	for i := range features {
+		outputs[i] = &inference.EncoderOutput{}
+	}
+	return outputs, nil
+}
+
+func (r *scriptedRuntime) DecodeStep(ctx context.Context, encoded []*inference.EncoderOutput, tokens [][]int) ([][]float32, error) {
+	logProbs := make([][]float32, len(tokens))
+	for i, sequence := range tokens {
+		logProbs[i] = make([]float32, r.size)
+		for j := range logProbs[i] {
+			logProbs[i][j] = -10
+		}
+		logProbs[i][r.script[len(sequence)-1]] = 0
+	}
+	return logProbs, nil
+}
+
+func TestTraceRecordsPipelineStages(t *testing.T) {
+	detector, err := hardware.NewDetector()
+	helpers.AssertNoError(t, err)
+
+	manager := inference.NewManager(detector)
+	defer manager.CloseAllSessions()
+	manager.SetRuntimeFactory(func(modelPath string) (inference.ONNXRuntime, error) {
+		return newScriptedRuntime(), nil
+	})
+	manager.SetProfiling(true)
+
+	model := &models.ONNXModel{Model: &models.Model{Path: "models/whisper.onnx"}, ID: "test-model"}
+	ctx := context.Background()
+
+	scheduler, err := manager.GetScheduler(ctx, model)
+	helpers.AssertNoError(t, err)
+
+	traceCtx, trace := manager.StartTrace(ctx, "req-1", model.ID)
+	if trace == nil {
+		t.Fatal("expected a trace while profiling is enabled")
+	}
+
+	result, err := scheduler.Submit(traceCtx, make([]float32, 16000))
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "hello world", result.Transcription)
+
+	counts := make(map[string]int)
+	for _, span := range trace.Spans() {
+		counts[span.Name]++
+	}
+	helpers.AssertEqual(t, 1, counts[inference.StageQueue])
+	helpers.AssertEqual(t, 1, counts[inference.StageFeatureExtraction])
+	helpers.AssertEqual(t, 1, counts[inference.StageEncoder])
+	helpers.AssertEqual(t, 3, counts[inference.StageDecoderStep])
+	helpers.AssertEqual(t, 1, counts[inference.StagePostProcessing])
+
+	// The export is valid Chrome trace-event JSON
+	var buf bytes.Buffer
+	helpers.AssertNoError(t, inference.WriteChromeTrace(&buf, manager.Tracer().Recent()))
+
+	var exported struct {
+		TraceEvents []struct {
+			Name  string `json:"name"`
+			Phase string `json:"ph"`
+		} `json:"traceEvents"`
+	}
+	helpers.AssertNoError(t, json.Unmarshal(buf.Bytes(), &exported))
+	helpers.AssertEqual(t, len(trace.Spans())+1, len(exported.TraceEvents))
+
+	// Without profiling no trace is started
+	manager.SetProfiling(false)
+	if _, trace := manager.StartTrace(ctx, "req-2", model.ID); trace != nil {
+		t.Error("expected no trace while profiling is disabled")
+	}
+}
+
+func TestProfilingTogglesWhileSessionsOpen(t *testing.T) {
+	detector, err := hardware.NewDetector()
+	helpers.AssertNoError(t, err)
+
+	manager := inference.NewManager(detector)
+	defer manager.CloseAllSessions()
+	manager.SetRuntimeFactory(func(modelPath string) (inference.ONNXRuntime, error) {
+		return newScriptedRuntime(), nil
+	})
+	model := &models.ONNXModel{Model: &models.Model{Path: "models/whisper.onnx"}, ID: "test-model"}
+
+	// Run with -race: sessions copy the defaults while profiling flips
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			manager.SetProfiling(i%2 == 0)
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		session, err := manager.CreateSession(context.Background(), model, nil, nil)
+		helpers.AssertNoError(t, err)
+		helpers.AssertNoError(t, manager.CloseSession(session))
+	}
+	<-done
+}
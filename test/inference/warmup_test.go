@@ -0,0 +1,37 @@
+package inference_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/josealecrim/audiototext/internal/hardware"
+	"github.com/josealecrim/audiototext/internal/inference"
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+func TestWarmupRunsPreferredBatchSizes(t *testing.T) {
+	detector, err := hardware.NewDetector()
+	helpers.AssertNoError(t, err)
+
+	manager := inference.NewManager(detector)
+	defer manager.CloseAllSessions()
+
+	model := &models.ONNXModel{Model: &models.Model{Path: "models/whisper.onnx"}, ID: "test-model"}
+	helpers.AssertNoError(t, manager.Warmup(context.Background(), []*models.ONNXModel{model}))
+
+	result, ok := manager.GetWarmupResults()[model.ID]
+	if !ok {
+		t.Fatal("expected a warmup result for the model")
+	}
+	helpers.AssertNoError(t, result.Err)
+
+	for _, size := range []int{1, 2, 4, 8, 16, 32} {
+		if _, ok := result.BatchLatencies[size]; !ok {
+			t.Errorf("batch size %d was not warmed up", size)
+		}
+	}
+
+	// Warmup traffic is not counted as real inferences
+	helpers.AssertEqual(t, int64(0), manager.GetStats()[model.ID].TotalInferences)
+}
@@ -0,0 +1,136 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/internal/models/registry"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+func TestParseVersion(t *testing.T) {
+	ordered := []string{"0.9.0", "1.2.0", "1.3.0-alpha", "1.3.0-rc.1", "1.3.0-rc.2", "1.3.0-rc.10", "v1.3.0", "1.10.0", "2.0.0"}
+	var versions []models.Version
+	for _, input := range ordered {
+		version, err := models.ParseVersion(input)
+		helpers.AssertNoError(t, err)
+		versions = append(versions, version)
+	}
+	for i := 1; i < len(versions); i++ {
+		helpers.AssertEqual(t, -1, versions[i-1].Compare(versions[i]))
+		helpers.AssertEqual(t, 1, versions[i].Compare(versions[i-1]))
+	}
+	helpers.AssertEqual(t, "1.3.0", versions[6].String())
+	helpers.AssertEqual(t, false, versions[3].Stable())
+
+	for _, input := range []string{"", "1.3", "1.3.0.1", "01.3.0", "latest", "1.3.0-"} {
+		if _, err := models.ParseVersion(input); err == nil {
+			t.Errorf("expected %q to be rejected", input)
+		}
+	}
+}
+
+func TestFamilyResolvesToLatestStableVersion(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	for _, modelID := range []string{"whisper-base@1.2.0", "whisper-base@1.3.0", "whisper-base@1.10.0-rc.1", "wav2vec2"} {
+		writeModel(t, cfg.CacheDir, modelID, helpers.ONNXModel([]byte(modelID)))
+	}
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+
+	model, err := reg.GetModel("whisper-base")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "whisper-base@1.3.0", model.ID)
+	helpers.AssertEqual(t, "1.3.0", model.Info.Version)
+
+	// An exact ID pins the version, prereleases included
+	model, err = reg.GetModel("whisper-base@1.10.0-rc.1")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "1.10.0-rc.1", model.Info.Version)
+
+	_, err = reg.GetModel("whisper-base@9.9.9")
+	if !errors.Is(err, models.ErrNotRegistered) {
+		t.Errorf("expected an unknown version to be not registered, got %v", err)
+	}
+
+	aliases := reg.Aliases()
+	helpers.AssertEqual(t, 1, len(aliases))
+	helpers.AssertEqual(t, "whisper-base", aliases[0].Name)
+	helpers.AssertEqual(t, "whisper-base@1.3.0", aliases[0].Target)
+	helpers.AssertEqual(t, true, aliases[0].Implicit)
+
+	// Versioned IDs need semantic versions
+	writeModel(t, cfg.CacheDir, "whisper-base@latest", helpers.ONNXModel([]byte("weights")))
+	if _, err := reg.Register(models.ModelInfo{ID: "whisper-base@latest"}); err == nil {
+		t.Error("expected a non-semantic version to be rejected")
+	}
+}
+
+func TestAliasesRetargetAndRollBack(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	for _, modelID := range []string{"whisper-base@1.2.0", "whisper-base@1.3.0", "whisper-base@1.4.0", "wav2vec2"} {
+		writeModel(t, cfg.CacheDir, modelID, helpers.ONNXModel([]byte(modelID)))
+	}
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+
+	alias, err := reg.SetAlias("default", "whisper-base@1.3.0")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, 0, len(alias.Previous))
+	_, err = reg.SetAlias("default", "wav2vec2")
+	helpers.AssertNoError(t, err)
+	model, err := reg.GetModel("default")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "wav2vec2", model.ID)
+
+	alias, err = reg.RollbackAlias("default")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "whisper-base@1.3.0", alias.Target)
+	model, err = reg.GetModel("default")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "whisper-base@1.3.0", model.ID)
+
+	// Without history, rolling back walks down the family's versions
+	alias, err = reg.RollbackAlias("default")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "whisper-base@1.2.0", alias.Target)
+	if _, err := reg.RollbackAlias("default"); err == nil {
+		t.Error("expected rolling back past the oldest version to fail")
+	}
+
+	// Rolling back a family pins it to the previous version
+	alias, err = reg.RollbackAlias("whisper-base")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "whisper-base@1.3.0", alias.Target)
+	helpers.AssertEqual(t, false, alias.Implicit)
+
+	for name, target := range map[string]string{
+		"default":       "missing@1.0.0",
+		"bad@name":      "wav2vec2",
+		"wav2vec2":      "whisper-base@1.2.0",
+		"":              "wav2vec2",
+		"/leading":      "wav2vec2",
+		"whisper-large": "whisper-base",
+	} {
+		if _, err := reg.SetAlias(name, target); err == nil {
+			t.Errorf("expected alias %q -> %q to be rejected", name, target)
+		}
+	}
+
+	// Pinned aliases survive a restart; removing one unpins the family
+	reopened, err := registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+	aliases := reopened.Aliases()
+	helpers.AssertEqual(t, 2, len(aliases))
+	helpers.AssertEqual(t, "whisper-base@1.2.0", aliases[0].Target)
+	helpers.AssertEqual(t, "whisper-base@1.3.0", aliases[1].Target)
+
+	helpers.AssertNoError(t, reopened.RemoveAlias("whisper-base"))
+	model, err = reopened.GetModel("whisper-base")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "whisper-base@1.4.0", model.ID)
+	if err := reopened.RemoveAlias("whisper-base"); !errors.Is(err, models.ErrNotRegistered) {
+		t.Errorf("expected removing an unpinned alias to fail, got %v", err)
+	}
+}
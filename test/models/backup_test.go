@@ -0,0 +1,229 @@
+package models_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/internal/models/cache"
+	"github.com/josealecrim/audiototext/internal/models/onnx"
+	"github.com/josealecrim/audiototext/internal/models/registry"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+// replaceWeights swaps a model's weights the way a download does, writing
+// a new file and renaming it over the old one
+func replaceWeights(t *testing.T, dir, modelID string, weights []byte) {
+	t.Helper()
+
+	path := filepath.Join(dir, filepath.FromSlash(modelID), "model.onnx")
+	helpers.AssertNoError(t, os.WriteFile(path+".part", weights, 0644))
+	helpers.AssertNoError(t, os.Rename(path+".part", path))
+}
+
+// backupFile returns the path of a file inside a backup
+func backupFile(dir string, backup cache.Backup, name string) string {
+	return filepath.Join(dir, cache.BackupDir, backup.ModelID, backup.ID, name)
+}
+
+func TestBackupAndRestoreModel(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	writeModel(t, cfg.CacheDir, "whisper-v1", helpers.ONNXModel([]byte("v1 weights")))
+	helpers.AssertNoError(t, os.WriteFile(filepath.Join(cfg.CacheDir, "whisper-v1", models.ManifestFile), []byte(`{"name": "Whisper"}`), 0644))
+
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+	var replaced []*models.ONNXModel
+	reg.SetOnReplace(func(model *models.ONNXModel) error {
+		replaced = append(replaced, model)
+		return nil
+	})
+
+	backup, err := reg.CreateBackup("whisper-v1", "ana", "before upgrading to v2")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "ana", backup.CreatedBy)
+	helpers.AssertEqual(t, "before upgrading to v2", backup.Reason)
+	helpers.AssertEqual(t, checksum(helpers.ONNXModel([]byte("v1 weights"))), backup.Checksum)
+	helpers.AssertEqual(t, "manifest.json model.onnx", strings.Join(backup.Files, " "))
+	if time.Since(backup.Created) > time.Minute {
+		t.Errorf("expected the backup to record when it was made, got %v", backup.Created)
+	}
+
+	// The weights are hard-linked rather than copied
+	active, err := os.Stat(filepath.Join(cfg.CacheDir, "whisper-v1", "model.onnx"))
+	helpers.AssertNoError(t, err)
+	saved, err := os.Stat(backupFile(cfg.CacheDir, backup, "model.onnx"))
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, true, os.SameFile(active, saved))
+
+	backups, err := reg.ListBackups("whisper-v1")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, 1, len(backups))
+	helpers.AssertEqual(t, backup.ID, backups[0].ID)
+
+	// Roll an update, then open the new weights as an in-flight session would
+	replaceWeights(t, cfg.CacheDir, "whisper-v1", helpers.ONNXModel([]byte("v2 weights")))
+	updated, err := reg.Register(models.ModelInfo{ID: "whisper-v1", Version: "v2"})
+	helpers.AssertNoError(t, err)
+	inFlight, err := os.Open(updated.Path)
+	helpers.AssertNoError(t, err)
+	defer inFlight.Close()
+
+	restored, err := reg.RestoreBackup("whisper-v1", backup.ID, "ana")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, checksum(helpers.ONNXModel([]byte("v1 weights"))), restored.Info.Checksum)
+	current, err := reg.GetModel("whisper-v1")
+	helpers.AssertNoError(t, err)
+	if current != restored {
+		t.Error("expected the registry to serve the restored model")
+	}
+	// Inference is told about each new handle so its sessions follow
+	helpers.AssertEqual(t, 2, len(replaced))
+	if replaced[0] != updated || replaced[1] != restored {
+		t.Error("expected the update and the restore to replace the served model")
+	}
+
+	// Holders of the previous handle keep the version they opened
+	helpers.AssertEqual(t, checksum(helpers.ONNXModel([]byte("v2 weights"))), updated.Info.Checksum)
+	data, err := io.ReadAll(inFlight)
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, string(helpers.ONNXModel([]byte("v2 weights"))), string(data))
+
+	helpers.AssertEqual(t, updated.Path, restored.Path)
+	data, err = os.ReadFile(restored.Path)
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, string(helpers.ONNXModel([]byte("v1 weights"))), string(data))
+
+	// The replaced version was backed up so the restore can be undone
+	backups, err = reg.ListBackups("whisper-v1")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, 2, len(backups))
+	helpers.AssertEqual(t, checksum(helpers.ONNXModel([]byte("v2 weights"))), backups[0].Checksum)
+	if !strings.Contains(backups[0].Reason, backup.ID) {
+		t.Errorf("expected the automatic backup to name the restored one, got %q", backups[0].Reason)
+	}
+
+	// A reopened registry serves the restored version
+	reopened, err := registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+	model, err := reopened.GetModel("whisper-v1")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, checksum(helpers.ONNXModel([]byte("v1 weights"))), model.Info.Checksum)
+	helpers.AssertEqual(t, 0, len(reopened.Invalid()))
+}
+
+func TestRestoreRejectsCorruptOrUnknownBackups(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	writeModel(t, cfg.CacheDir, "whisper-v1", helpers.ONNXModel([]byte("v1 weights")))
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+
+	backup, err := reg.CreateBackup("whisper-v1", "", "nightly")
+	helpers.AssertNoError(t, err)
+	if backup.CreatedBy == "" {
+		t.Error("expected a backup without an author to be signed by the system user")
+	}
+
+	for _, backupID := range []string{"", "missing", "../whisper-v1"} {
+		if _, err := reg.RestoreBackup("whisper-v1", backupID, "ana"); err == nil {
+			t.Errorf("expected backup %q to be rejected", backupID)
+		}
+	}
+
+	// Replace the saved weights without touching the active ones they are linked to
+	path := backupFile(cfg.CacheDir, backup, "model.onnx")
+	helpers.AssertNoError(t, os.Remove(path))
+	helpers.AssertNoError(t, os.WriteFile(path, []byte("bit rot"), 0644))
+
+	if _, err := reg.RestoreBackup("whisper-v1", backup.ID, "ana"); !errors.Is(err, models.ErrChecksumMismatch) {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+	model, err := reg.GetModel("whisper-v1")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, checksum(helpers.ONNXModel([]byte("v1 weights"))), model.Info.Checksum)
+	backups, err := reg.ListBackups("whisper-v1")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, 1, len(backups))
+}
+
+func TestInvalidBackupLeavesTheModelServing(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	writeModel(t, cfg.CacheDir, "whisper-v1", helpers.ONNXModel([]byte("v1 weights")))
+	manifestPath := filepath.Join(cfg.CacheDir, "whisper-v1", models.ManifestFile)
+	helpers.AssertNoError(t, os.WriteFile(manifestPath, []byte(`{"name": "Whisper"}`), 0644))
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+
+	backup, err := reg.CreateBackup("whisper-v1", "ana", "nightly")
+	helpers.AssertNoError(t, err)
+
+	// The weights still match their checksum, but the graph doesn't match the manifest
+	path := backupFile(cfg.CacheDir, backup, models.ManifestFile)
+	helpers.AssertNoError(t, os.Remove(path))
+	manifest := `{"name": "Whisper", "inputs": [{"name": "input_features", "shape": [-1, 128, 3000]}]}`
+	helpers.AssertNoError(t, os.WriteFile(path, []byte(manifest), 0644))
+
+	if _, err := reg.RestoreBackup("whisper-v1", backup.ID, "ana"); !errors.Is(err, onnx.ErrInvalidModel) {
+		t.Fatalf("expected the backup to be rejected as an invalid model, got %v", err)
+	}
+
+	model, err := reg.GetModel("whisper-v1")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "Whisper", model.Manifest.Name)
+	helpers.AssertEqual(t, 0, len(reg.Invalid()))
+	data, err := os.ReadFile(manifestPath)
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, `{"name": "Whisper"}`, string(data))
+
+	// Nothing was replaced, so nothing needed a backup
+	backups, err := reg.ListBackups("whisper-v1")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, 1, len(backups))
+	staged, err := os.ReadDir(filepath.Join(cfg.CacheDir, cache.BackupDir, ".staging"))
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, 0, len(staged))
+}
+
+func TestBackupsArePrunedByRetention(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	writeModel(t, cfg.CacheDir, "whisper-v1", helpers.ONNXModel([]byte("v1 weights")))
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir, KeepBackups: 2})
+	helpers.AssertNoError(t, err)
+
+	var created []cache.Backup
+	for i := 0; i < 4; i++ {
+		backup, err := reg.CreateBackup("whisper-v1", "ana", "nightly")
+		helpers.AssertNoError(t, err)
+		created = append(created, backup)
+	}
+
+	// Only the newest backups are kept
+	backups, err := reg.ListBackups("whisper-v1")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, 2, len(backups))
+	helpers.AssertEqual(t, created[3].ID, backups[0].ID)
+	helpers.AssertEqual(t, created[2].ID, backups[1].ID)
+
+	// Backups older than the retention period are removed, except the latest
+	reg, err = registry.New(models.Config{CachePath: cfg.CacheDir, BackupRetention: "1ms"})
+	helpers.AssertNoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	pruned, err := reg.PruneBackups()
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, 1, len(pruned))
+	helpers.AssertEqual(t, created[2].ID, pruned[0].ID)
+
+	// Backups outlive the model they were made from
+	helpers.AssertNoError(t, reg.Remove("whisper-v1"))
+	backups, err = reg.ListBackups("whisper-v1")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, 1, len(backups))
+	restored, err := reg.RestoreBackup("whisper-v1", backups[0].ID, "ana")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, checksum(helpers.ONNXModel([]byte("v1 weights"))), restored.Info.Checksum)
+}
@@ -0,0 +1,138 @@
+package models_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/internal/models/cache"
+	"github.com/josealecrim/audiototext/internal/models/registry"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+// checksum returns the hex SHA-256 of data
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// mirror configures an HTTP source laid out like the Hugging Face hub
+func mirror(url string) []models.SourceConfig {
+	return []models.SourceConfig{{Kind: models.SourceHTTP, URL: url}}
+}
+
+// quarantined returns the entries of the cache's quarantine directory
+func quarantined(t *testing.T, dir string) []os.DirEntry {
+	t.Helper()
+
+	entries, err := os.ReadDir(filepath.Join(dir, cache.QuarantineDir))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("reading quarantine: %v", err)
+	}
+	return entries
+}
+
+func TestDownloadVerifiesChecksum(t *testing.T) {
+	weights := helpers.ONNXModel([]byte("downloaded weights"))
+	mux := http.NewServeMux()
+	for version, sum := range map[string]string{"1.0.0": checksum(weights), "2.0.0": checksum(helpers.ONNXModel([]byte("other weights")))} {
+		base := fmt.Sprintf("/openai/whisper-small-%s/resolve/main/", version)
+		manifest := fmt.Sprintf(`{"name": "Whisper Small", "sha256": %q}`, sum)
+		mux.HandleFunc(base+"manifest.json", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(manifest)) })
+		mux.HandleFunc(base+"model.onnx", func(w http.ResponseWriter, r *http.Request) { w.Write(weights) })
+	}
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cfg := helpers.SetupTestEnv(t)
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir, Sources: mirror(server.URL), DownloadTimeout: 10 * time.Second})
+	helpers.AssertNoError(t, err)
+
+	model, err := reg.Download(context.Background(), models.TypeWhisper, "1.0.0")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, checksum(weights), model.Info.Checksum)
+	helpers.AssertEqual(t, "Whisper Small", model.Manifest.Name)
+
+	// Weights that don't match the manifest never replace the model file
+	_, err = reg.Download(context.Background(), models.TypeWhisper, "2.0.0")
+	if !errors.Is(err, models.ErrChecksumMismatch) {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+	dir := reg.Dir("whisper@2.0.0")
+	for _, name := range []string{"model.onnx", "model.onnx.part"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected no %s after a failed download, got %v", name, err)
+		}
+	}
+	if _, err := reg.GetModel("whisper@2.0.0"); err == nil {
+		t.Error("expected the corrupt download not to be registered")
+	}
+}
+
+func TestCorruptModelsAreQuarantined(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	writeModel(t, cfg.CacheDir, "changed", helpers.ONNXModel([]byte("weights")))
+	writeModel(t, cfg.CacheDir, "rotted", helpers.ONNXModel([]byte("weights")))
+
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, 0, len(reg.Invalid()))
+
+	// A rewritten file is caught when the registry loads it again
+	helpers.AssertNoError(t, os.WriteFile(filepath.Join(reg.Dir("changed"), "model.onnx"), []byte("new weights"), 0644))
+
+	// Bit rot keeps the size and modification time, so only Verify notices it
+	rotted := filepath.Join(reg.Dir("rotted"), "model.onnx")
+	stat, err := os.Stat(rotted)
+	helpers.AssertNoError(t, err)
+	helpers.AssertNoError(t, os.WriteFile(rotted, helpers.ONNXModel([]byte("WEIGHTS")), 0644))
+	helpers.AssertNoError(t, os.Chtimes(rotted, stat.ModTime(), stat.ModTime()))
+
+	reopened, err := registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+	if err := reopened.Invalid()["changed"]; !errors.Is(err, models.ErrChecksumMismatch) {
+		t.Fatalf("expected the changed model to fail verification, got %v", err)
+	}
+	_, err = reopened.GetModel("rotted")
+	helpers.AssertNoError(t, err)
+
+	failed := reopened.Verify()
+	helpers.AssertEqual(t, 1, len(failed))
+	if !errors.Is(failed["rotted"], models.ErrChecksumMismatch) {
+		t.Fatalf("expected the rotted model to fail verification, got %v", failed["rotted"])
+	}
+	if _, err := reopened.GetModel("rotted"); err == nil {
+		t.Error("expected the rotted model to stop being served")
+	}
+
+	// Both moved to the quarantine, which the registry doesn't adopt back
+	helpers.AssertEqual(t, 2, len(quarantined(t, cfg.CacheDir)))
+	reopened, err = registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+	list, err := reopened.ListModels()
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, 0, len(list))
+}
+
+func TestRegistryRejectsManifestChecksumMismatch(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	writeModel(t, cfg.CacheDir, "whisper-tiny", helpers.ONNXModel([]byte("weights")))
+	manifest := fmt.Sprintf(`{"name": "Whisper Tiny", "sha256": %q}`, checksum([]byte("other weights")))
+	helpers.AssertNoError(t, os.WriteFile(filepath.Join(cfg.CacheDir, "whisper-tiny", "manifest.json"), []byte(manifest), 0644))
+
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+	if err := reg.Invalid()["whisper-tiny"]; !errors.Is(err, models.ErrChecksumMismatch) {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+	helpers.AssertEqual(t, 1, len(quarantined(t, cfg.CacheDir)))
+}
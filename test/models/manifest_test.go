@@ -0,0 +1,101 @@
+package models_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/internal/models/registry"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+// writeManifest writes a manifest into a model's directory
+func writeManifest(t *testing.T, dir, modelID, manifest string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(modelID), models.ManifestFile)
+	helpers.AssertNoError(t, os.WriteFile(path, []byte(manifest), 0644))
+}
+
+func TestRegistryLoadsManifests(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	writeModel(t, cfg.CacheDir, "whisper-small", helpers.ONNXModel([]byte("weights")), "vocab.json")
+	writeManifest(t, cfg.CacheDir, "whisper-small", `{
+		"name": "Whisper Small",
+		"description": "Multilingual speech recognition",
+		"languages": ["en", "pt-BR"],
+		"capabilities": {"streaming": true, "translation": true},
+		"tokenizer_files": ["vocab.json"],
+		"license": "MIT"
+	}`)
+
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, 0, len(reg.Invalid()))
+
+	model, err := reg.GetModel("whisper-small")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "Whisper Small", model.Manifest.Name)
+	helpers.AssertEqual(t, "MIT", model.Manifest.License)
+	helpers.AssertEqual(t, models.DefaultSampleRate, model.Manifest.SampleRate)
+	helpers.AssertEqual(t, models.FeatureLogMel, model.Manifest.FeatureType)
+	helpers.AssertEqual(t, true, model.Info.Capabilities.Translation)
+	helpers.AssertEqual(t, false, model.Info.Capabilities.Diarization)
+}
+
+func TestRegistryRejectsInvalidManifests(t *testing.T) {
+	manifests := map[string]string{
+		"no-name":           `{"languages": ["en"]}`,
+		"bad-language":      `{"name": "Bad", "languages": ["english!"]}`,
+		"missing-tokenizer": `{"name": "Missing", "tokenizer_files": ["vocab.json"]}`,
+		"escaping-file":     `{"name": "Escaping", "tokenizer_files": ["../vocab.json"]}`,
+		"wrong-features":    `{"name": "Wrong", "feature_type": "waveform"}`,
+		"malformed":         `{"name": `,
+		"negative-opset":    `{"name": "Negative", "opset": -1}`,
+		"unknown-dtype":     `{"name": "Unknown", "inputs": [{"name": "input_features", "dtype": "float7"}]}`,
+		"unnamed-tensor":    `{"name": "Unnamed", "outputs": [{"dtype": "float32"}]}`,
+	}
+
+	cfg := helpers.SetupTestEnv(t)
+	for modelID, manifest := range manifests {
+		writeModel(t, cfg.CacheDir, modelID, helpers.ONNXModel([]byte("weights")))
+		writeManifest(t, cfg.CacheDir, modelID, manifest)
+	}
+	writeModel(t, cfg.CacheDir, "valid", helpers.ONNXModel([]byte("weights")))
+
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+
+	invalid := reg.Invalid()
+	for modelID := range manifests {
+		if invalid[modelID] == nil {
+			t.Errorf("expected model %s to be rejected", modelID)
+		}
+		if _, err := reg.GetModel(modelID); err == nil {
+			t.Errorf("expected model %s not to be served", modelID)
+		}
+	}
+	_, err = reg.GetModel("valid")
+	helpers.AssertNoError(t, err)
+}
+
+func TestSupportsLanguage(t *testing.T) {
+	cases := []struct {
+		languages []string
+		language  string
+		want      bool
+	}{
+		{nil, "pt-BR", true},
+		{[]string{"pt-BR"}, "", true},
+		{[]string{"pt-BR"}, "pt-br", true},
+		{[]string{"pt-BR"}, "pt", true},
+		{[]string{"pt"}, "pt-BR", true},
+		{[]string{"pt-PT"}, "pt-BR", false},
+		{[]string{"en", "es"}, "pt", false},
+	}
+	for _, c := range cases {
+		if got := models.SupportsLanguage(c.languages, c.language); got != c.want {
+			t.Errorf("SupportsLanguage(%v, %q) = %v, want %v", c.languages, c.language, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package models_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/internal/models/onnx"
+	"github.com/josealecrim/audiototext/internal/models/registry"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+func TestInspectReadsTheGraph(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	spec := helpers.WhisperGraph(testWeights(300 << 10))
+	spec.Metadata = map[string]string{"sample_rate": "16000", "author": "tests"}
+	path := filepath.Join(cfg.TempDir, "model.onnx")
+	helpers.AssertNoError(t, os.WriteFile(path, spec.Encode(), 0644))
+
+	graph, err := onnx.Inspect(path)
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, int64(8), graph.IRVersion)
+	opset, ok := graph.Opset("ai.onnx")
+	helpers.AssertEqual(t, true, ok)
+	helpers.AssertEqual(t, int64(17), opset)
+	helpers.AssertEqual(t, "audiototext-tests", graph.ProducerName)
+	helpers.AssertEqual(t, "test-graph", graph.Name)
+	helpers.AssertEqual(t, 1, graph.Nodes)
+	helpers.AssertEqual(t, "16000", graph.Metadata["sample_rate"])
+	helpers.AssertEqual(t, "tests", graph.Metadata["author"])
+
+	input, ok := graph.Input("input_features")
+	helpers.AssertEqual(t, true, ok)
+	helpers.AssertEqual(t, "input_features float32[batch,80,3000]", input.String())
+	helpers.AssertEqual(t, int64(-1), input.Dims()[0])
+	output, ok := graph.Output("last_hidden_state")
+	helpers.AssertEqual(t, true, ok)
+	helpers.AssertEqual(t, "last_hidden_state float32[batch,1500,384]", output.String())
+
+	// The weights are sized without being read
+	helpers.AssertEqual(t, 1, len(graph.Initializers))
+	helpers.AssertEqual(t, models.DataUint8, graph.Initializers[0].Type)
+	helpers.AssertEqual(t, int64(300<<10), graph.Initializers[0].Bytes)
+	helpers.AssertEqual(t, int64(300<<10), graph.InitializerBytes)
+}
+
+func TestInspectRejectsMalformedFiles(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	model := helpers.ONNXModel([]byte("weights"))
+	for name, data := range map[string][]byte{
+		"text":      []byte("not a model"),
+		"truncated": model[:len(model)-10],
+		"no graph":  model[:2],
+	} {
+		path := filepath.Join(cfg.TempDir, name+".onnx")
+		helpers.AssertNoError(t, os.WriteFile(path, data, 0644))
+		if _, err := onnx.Inspect(path); !errors.Is(err, onnx.ErrInvalidModel) {
+			t.Errorf("%s: expected an invalid model, got %v", name, err)
+		}
+	}
+}
+
+func TestValidateChecksGraphAgainstManifest(t *testing.T) {
+	manifest := func() *models.Manifest {
+		return &models.Manifest{
+			Name:        "Whisper Tiny",
+			SampleRate:  16000,
+			FeatureType: models.FeatureLogMel,
+			Opset:       17,
+			Inputs:      []models.TensorSpec{{Name: "input_features", DType: "float32", Shape: []int64{-1, 80, 3000}}},
+			Outputs:     []models.TensorSpec{{Name: "last_hidden_state", DType: "float32"}},
+		}
+	}
+	inspect := func(spec helpers.ONNXGraph) *models.Graph {
+		path := filepath.Join(t.TempDir(), "model.onnx")
+		helpers.AssertNoError(t, os.WriteFile(path, spec.Encode(), 0644))
+		graph, err := onnx.Inspect(path)
+		helpers.AssertNoError(t, err)
+		return graph
+	}
+
+	whisper := inspect(helpers.WhisperGraph(nil))
+	helpers.AssertNoError(t, onnx.Validate(whisper, manifest(), models.TypeWhisper))
+	helpers.AssertNoError(t, onnx.Validate(whisper, nil, models.TypeWhisper))
+	helpers.AssertNoError(t, onnx.Validate(inspect(helpers.CTCGraph(nil)), nil, models.TypeCTC))
+
+	newOpset := helpers.WhisperGraph(nil)
+	newOpset.Opset = 99
+	oldIR := helpers.WhisperGraph(nil)
+	oldIR.IRVersion = 2
+	otherRate := helpers.WhisperGraph(nil)
+	otherRate.Metadata = map[string]string{"sample_rate": "8000"}
+	for name, test := range map[string]struct {
+		graph     *models.Graph
+		manifest  func(*models.Manifest)
+		modelType models.ModelType
+	}{
+		"unsupported opset": {graph: inspect(newOpset)},
+		"unsupported IR":    {graph: inspect(oldIR)},
+		"waveform for mel":  {graph: inspect(helpers.CTCGraph(nil))},
+		"mel for ctc":       {graph: whisper, manifest: func(m *models.Manifest) { m.FeatureType = "" }, modelType: models.TypeCTC},
+		"other sample rate": {graph: inspect(otherRate)},
+		"other opset":       {graph: whisper, manifest: func(m *models.Manifest) { m.Opset = 13 }},
+		"missing input":     {graph: whisper, manifest: func(m *models.Manifest) { m.Inputs[0].Name = "input_values" }},
+		"other dtype":       {graph: whisper, manifest: func(m *models.Manifest) { m.Outputs[0].DType = "float16" }},
+		"other rank":        {graph: whisper, manifest: func(m *models.Manifest) { m.Inputs[0].Shape = []int64{-1, 80} }},
+		"other mel bins":    {graph: whisper, manifest: func(m *models.Manifest) { m.Inputs[0].Shape[1] = 128 }},
+		"missing output":    {graph: whisper, manifest: func(m *models.Manifest) { m.Outputs = append(m.Outputs, models.TensorSpec{Name: "logits"}) }},
+	} {
+		m := manifest()
+		if test.manifest != nil {
+			test.manifest(m)
+		}
+		if test.modelType == "" {
+			test.modelType = models.TypeWhisper
+		}
+		if err := onnx.Validate(test.graph, m, test.modelType); !errors.Is(err, onnx.ErrInvalidModel) {
+			t.Errorf("%s: expected the graph to be rejected, got %v", name, err)
+		}
+	}
+
+	// Every problem is reported at once
+	m := manifest()
+	m.Opset = 13
+	m.Outputs[0].DType = "int64"
+	err := onnx.Validate(whisper, m, models.TypeWhisper)
+	if err == nil || !strings.Contains(err.Error(), "opset 13") || !strings.Contains(err.Error(), "int64") {
+		t.Errorf("expected both problems to be reported, got %v", err)
+	}
+}
+
+func TestRegistryRejectsGraphsBeforeServing(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	writeModel(t, cfg.CacheDir, "whisper-tiny", helpers.ONNXModel([]byte("weights")))
+	writeModel(t, cfg.CacheDir, "mismatched", helpers.ONNXModel([]byte("weights")))
+	manifest := `{"name": "Mismatched", "inputs": [{"name": "input_features", "shape": [-1, 128, 3000]}]}`
+	helpers.AssertNoError(t, os.WriteFile(filepath.Join(cfg.CacheDir, "mismatched", "manifest.json"), []byte(manifest), 0644))
+	writeModel(t, cfg.CacheDir, "garbage", []byte("not a model"))
+
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+
+	model, err := reg.GetModel("whisper-tiny")
+	helpers.AssertNoError(t, err)
+	if model.Graph == nil {
+		t.Fatal("expected the registry to describe the graph")
+	}
+	helpers.AssertEqual(t, "input_features", model.Graph.Inputs[0].Name)
+
+	invalid := reg.Invalid()
+	helpers.AssertEqual(t, 2, len(invalid))
+	for _, modelID := range []string{"mismatched", "garbage"} {
+		if !errors.Is(invalid[modelID], onnx.ErrInvalidModel) {
+			t.Errorf("expected %s to be rejected as an invalid model, got %v", modelID, invalid[modelID])
+		}
+		if _, err := reg.GetModel(modelID); err == nil {
+			t.Errorf("expected %s not to be served", modelID)
+		}
+	}
+}
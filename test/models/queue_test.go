@@ -0,0 +1,222 @@
+package models_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/internal/models/download"
+	"github.com/josealecrim/audiototext/internal/models/registry"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+// gatedServer serves weights only as the test releases each request,
+// recording the order they arrived in and how many ran at once
+type gatedServer struct {
+	gate chan struct{}
+
+	mu       sync.Mutex
+	order    []string
+	inFlight int
+	maxSeen  int
+}
+
+func newGatedServer(t *testing.T) (*gatedServer, string) {
+	t.Helper()
+
+	gated := &gatedServer{gate: make(chan struct{})}
+	server := httptest.NewServer(gated)
+	t.Cleanup(server.Close)
+	return gated, server.URL
+}
+
+func (s *gatedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if path.Base(r.URL.Path) != "model.onnx" {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	s.order = append(s.order, path.Dir(r.URL.Path))
+	s.inFlight++
+	if s.inFlight > s.maxSeen {
+		s.maxSeen = s.inFlight
+	}
+	s.mu.Unlock()
+
+	<-s.gate
+	w.Write(helpers.ONNXModel([]byte("weights of " + r.URL.Path)))
+
+	s.mu.Lock()
+	s.inFlight--
+	s.mu.Unlock()
+}
+
+// arrived returns the model directories requested so far
+func (s *gatedServer) arrived() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.order...)
+}
+
+// waitForRequests waits until n requests for weights have arrived
+func (s *gatedServer) waitForRequests(t *testing.T, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.arrived()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d requests, got %v", n, s.arrived())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConcurrentDownloadsOfAModelShareOneJob(t *testing.T) {
+	gated, url := newGatedServer(t)
+	cfg := helpers.SetupTestEnv(t)
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir, Sources: mirror(url)})
+	helpers.AssertNoError(t, err)
+
+	results := make(chan *models.ONNXModel, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			model, err := reg.Download(context.Background(), models.TypeWhisper, "1.0.0")
+			if err != nil {
+				t.Errorf("download failed: %v", err)
+			}
+			results <- model
+		}()
+	}
+
+	gated.waitForRequests(t, 1)
+	close(gated.gate)
+
+	first := <-results
+	for i := 0; i < 2; i++ {
+		if model := <-results; model != first {
+			t.Errorf("expected every caller to get the same registered model")
+		}
+	}
+	helpers.AssertEqual(t, 1, len(gated.arrived()))
+}
+
+func TestAbandonedFetchDoesNotCancelAJoinedDownload(t *testing.T) {
+	// The first caller gives up just as a second one joins its download;
+	// repeated because the two race
+	for i := 0; i < 20; i++ {
+		gated, url := newGatedServer(t)
+		reg, err := registry.New(models.Config{CachePath: t.TempDir(), Sources: mirror(url)})
+		helpers.AssertNoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		abandoned := make(chan error, 1)
+		go func() {
+			_, err := reg.Download(ctx, models.TypeWhisper, "1.0.0")
+			abandoned <- err
+		}()
+		gated.waitForRequests(t, 1)
+
+		joined := make(chan error, 1)
+		go func() {
+			_, err := reg.Download(context.Background(), models.TypeWhisper, "1.0.0")
+			joined <- err
+		}()
+		cancel()
+		if err := <-abandoned; !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected the first caller to give up, got %v", err)
+		}
+
+		// Either the second caller joined in time and the download goes on,
+		// or it found none and started its own
+		close(gated.gate)
+		helpers.AssertNoError(t, <-joined)
+	}
+}
+
+func TestDownloadQueueHonorsLimitAndPriority(t *testing.T) {
+	gated, url := newGatedServer(t)
+	cfg := helpers.SetupTestEnv(t)
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir, Sources: mirror(url), ConcurrentDownloads: 1})
+	helpers.AssertNoError(t, err)
+
+	// 1.0.0 takes the only worker; the rest wait in the queue
+	running, err := reg.Enqueue(download.Request{Type: models.TypeWhisper, Version: "1.0.0"})
+	helpers.AssertNoError(t, err)
+	gated.waitForRequests(t, 1)
+
+	low, err := reg.Enqueue(download.Request{Type: models.TypeWhisper, Version: "2.0.0"})
+	helpers.AssertNoError(t, err)
+	canceled, err := reg.Enqueue(download.Request{Type: models.TypeWhisper, Version: "3.0.0"})
+	helpers.AssertNoError(t, err)
+	high, err := reg.Enqueue(download.Request{Type: models.TypeWhisper, Version: "4.0.0", Priority: 5})
+	helpers.AssertNoError(t, err)
+
+	progress, err := reg.Progress(low.ID())
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "na fila", progress.Status)
+
+	// A queued download can be canceled before it starts
+	helpers.AssertNoError(t, reg.CancelDownload(canceled.ID()))
+	if _, err := canceled.Wait(context.Background()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the canceled download to fail with context.Canceled, got %v", err)
+	}
+
+	close(gated.gate)
+	for _, job := range []*download.Job{running, low, high} {
+		_, err := job.Wait(context.Background())
+		helpers.AssertNoError(t, err)
+	}
+
+	gated.mu.Lock()
+	helpers.AssertEqual(t, 1, gated.maxSeen)
+	gated.mu.Unlock()
+	helpers.AssertEqual(t,
+		"[/openai/whisper-small-1.0.0/resolve/main /openai/whisper-small-4.0.0/resolve/main /openai/whisper-small-2.0.0/resolve/main]",
+		fmt.Sprint(gated.arrived()))
+
+	_, err = reg.GetModel("whisper@3.0.0")
+	if err == nil {
+		t.Error("expected the canceled model not to be registered")
+	}
+}
+
+func TestDownloadProgressIsPushedToSubscribers(t *testing.T) {
+	gated, url := newGatedServer(t)
+	cfg := helpers.SetupTestEnv(t)
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir, Sources: mirror(url)})
+	helpers.AssertNoError(t, err)
+
+	job, err := reg.Enqueue(download.Request{Type: models.TypeWhisper, Version: "1.0.0"})
+	helpers.AssertNoError(t, err)
+	updates, _ := job.Subscribe()
+
+	close(gated.gate)
+	var statuses []models.Progress
+	helpers.WithTimeout(t, 5*time.Second, func(ctx context.Context) {
+		for progress := range updates {
+			statuses = append(statuses, progress)
+		}
+	})
+
+	last := statuses[len(statuses)-1]
+	helpers.AssertEqual(t, "concluído", last.Status)
+	helpers.AssertEqual(t, float64(100), last.Percentage)
+
+	// Subscribing after the download finished gets the final progress and a closed channel
+	updates, unsubscribe := job.Subscribe()
+	defer unsubscribe()
+	final, ok := <-updates
+	helpers.AssertEqual(t, true, ok)
+	helpers.AssertEqual(t, "concluído", final.Status)
+	if _, ok := <-updates; ok {
+		t.Error("expected the channel of a finished download to be closed")
+	}
+}
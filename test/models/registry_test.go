@@ -0,0 +1,97 @@
+package models_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/internal/models/registry"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+// writeModel writes a model directory with the given weights and auxiliary files
+func writeModel(t *testing.T, dir, modelID string, weights []byte, aux ...string) {
+	t.Helper()
+
+	modelDir := filepath.Join(dir, filepath.FromSlash(modelID))
+	helpers.AssertNoError(t, os.MkdirAll(modelDir, 0755))
+	helpers.AssertNoError(t, os.WriteFile(filepath.Join(modelDir, "model.onnx"), weights, 0644))
+	for _, name := range aux {
+		helpers.AssertNoError(t, os.WriteFile(filepath.Join(modelDir, name), []byte("{}"), 0644))
+	}
+}
+
+func TestRegistryAdoptsModelDirectories(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	weights := helpers.ONNXModel([]byte("weights"))
+	writeModel(t, cfg.CacheDir, "whisper-tiny", weights)
+	writeModel(t, cfg.CacheDir, "wav2vec2", helpers.CTCModel([]byte("ctc weights")), "ctc.json")
+
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+
+	list, err := reg.ListModels()
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, 2, len(list))
+	helpers.AssertEqual(t, "wav2vec2", list[0].ID)
+	helpers.AssertEqual(t, "whisper-tiny", list[1].ID)
+
+	whisper, err := reg.GetModel("whisper-tiny")
+	helpers.AssertNoError(t, err)
+	sum := sha256.Sum256(weights)
+	helpers.AssertEqual(t, filepath.Join(cfg.CacheDir, "whisper-tiny", "model.onnx"), whisper.Path)
+	helpers.AssertEqual(t, hex.EncodeToString(sum[:]), whisper.Info.Checksum)
+	helpers.AssertEqual(t, int64(len(weights)), whisper.Info.Size)
+	helpers.AssertEqual(t, models.FormatONNX, whisper.Info.Format)
+	helpers.AssertEqual(t, models.TypeWhisper, whisper.Info.Type)
+	helpers.AssertEqual(t, true, whisper.Info.Capabilities.Streaming)
+
+	ctc, err := reg.GetModel("wav2vec2")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, models.TypeCTC, ctc.Info.Type)
+	helpers.AssertEqual(t, true, ctc.Info.Capabilities.WordTimestamps)
+
+	if _, err := reg.GetModel("missing"); err == nil {
+		t.Error("expected an error for an unregistered model")
+	}
+}
+
+func TestRegistryPersistsModels(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+
+	modelID := "openai/whisper-tiny"
+	weights := helpers.ONNXModel([]byte("weights"))
+	writeModel(t, cfg.CacheDir, modelID, weights)
+	_, err = reg.Register(models.ModelInfo{
+		ID:           modelID,
+		Version:      "v3",
+		Capabilities: models.Capabilities{Streaming: true, Diarization: true},
+	})
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, int64(len(weights)), reg.Size())
+
+	// A reopened registry serves the model from its index
+	reopened, err := registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+	model, err := reopened.GetModel(modelID)
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "v3", model.Info.Version)
+	helpers.AssertEqual(t, true, model.Info.Capabilities.Diarization)
+
+	// Removing deletes the model's directory
+	helpers.AssertNoError(t, reopened.Remove(modelID))
+	if _, err := os.Stat(reopened.Dir(modelID)); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be deleted, got %v", reopened.Dir(modelID), err)
+	}
+
+	reopened, err = registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+	if _, err := reopened.GetModel(modelID); err == nil {
+		t.Error("expected the removed model to stay removed")
+	}
+}
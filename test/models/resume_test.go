@@ -0,0 +1,187 @@
+package models_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/internal/models/registry"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+// flakyServer serves a model's weights with Range support, dropping the
+// connection partway through the first responses
+type flakyServer struct {
+	mu       sync.Mutex
+	weights  []byte
+	etag     string
+	manifest bool
+	// drops is how many more responses are cut short
+	drops int
+	// cutAt is where the next cut response stops
+	cutAt int64
+	// ranges records the Range header of every request for the weights
+	ranges []string
+}
+
+func (s *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch filepath.Base(r.URL.Path) {
+	case "manifest.json":
+		if !s.manifest {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"name": "Whisper Small", "sha256": %q}`, checksum(s.weights))
+	case "model.onnx":
+		s.ranges = append(s.ranges, r.Header.Get("Range"))
+		content := io.ReadSeeker(bytes.NewReader(s.weights))
+		if s.drops > 0 {
+			content = &cutReader{Reader: bytes.NewReader(s.weights), cutAt: s.cutAt}
+			s.drops--
+			s.cutAt += int64(len(s.weights)) / 4
+		}
+		w.Header().Set("ETag", s.etag)
+		http.ServeContent(w, r, "model.onnx", time.Time{}, content)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// requests returns the Range headers received so far
+func (s *flakyServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ranges...)
+}
+
+// cutReader fails once reading reaches cutAt, leaving the response short
+type cutReader struct {
+	*bytes.Reader
+	cutAt int64
+}
+
+func (r *cutReader) Read(p []byte) (int, error) {
+	pos := r.Size() - int64(r.Len())
+	if pos >= r.cutAt {
+		return 0, errors.New("connection dropped")
+	}
+	if int64(len(p)) > r.cutAt-pos {
+		p = p[:r.cutAt-pos]
+	}
+	return r.Reader.Read(p)
+}
+
+// newFlakyServer serves weights, cutting the first drops responses a
+// quarter further into the file each time
+func newFlakyServer(t *testing.T, weights []byte, drops int) (*flakyServer, string) {
+	t.Helper()
+
+	flaky := &flakyServer{weights: weights, etag: `"v1"`, manifest: true, drops: drops, cutAt: int64(len(weights)) / 4}
+	server := httptest.NewServer(flaky)
+	t.Cleanup(server.Close)
+	return flaky, server.URL
+}
+
+// testWeights returns size bytes of non-repeating weights
+func testWeights(size int) []byte {
+	weights := make([]byte, size)
+	for i := range weights {
+		weights[i] = byte(i * 7 / 3)
+	}
+	return weights
+}
+
+func TestDownloadResumesAfterDroppedConnections(t *testing.T) {
+	weights := helpers.ONNXModel(testWeights(256 << 10))
+	flaky, url := newFlakyServer(t, weights, 3)
+
+	cfg := helpers.SetupTestEnv(t)
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir, Sources: mirror(url), Retries: 3, RetryBackoff: time.Millisecond})
+	helpers.AssertNoError(t, err)
+
+	model, err := reg.Download(context.Background(), models.TypeWhisper, "1.0.0")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, checksum(weights), model.Info.Checksum)
+
+	// Every retry asks only for what the previous attempt didn't get
+	quarter := len(weights) / 4
+	expected := []string{"", fmt.Sprintf("bytes=%d-", quarter), fmt.Sprintf("bytes=%d-", 2*quarter), fmt.Sprintf("bytes=%d-", 3*quarter)}
+	helpers.AssertEqual(t, fmt.Sprint(expected), fmt.Sprint(flaky.requests()))
+
+	for _, name := range []string{"model.onnx.part", "model.onnx.part.json"} {
+		if _, err := os.Stat(filepath.Join(reg.Dir(model.ID), name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be cleaned up, got %v", name, err)
+		}
+	}
+}
+
+func TestDownloadResumesAcrossRestarts(t *testing.T) {
+	weights := helpers.ONNXModel(testWeights(256 << 10))
+	flaky, url := newFlakyServer(t, weights, 1)
+	config := models.Config{Sources: mirror(url), RetryBackoff: time.Millisecond}
+
+	cfg := helpers.SetupTestEnv(t)
+	config.CachePath = cfg.CacheDir
+	reg, err := registry.New(config)
+	helpers.AssertNoError(t, err)
+
+	// Without retries the first drop fails the download
+	if _, err := reg.Download(context.Background(), models.TypeWhisper, "1.0.0"); err == nil {
+		t.Fatal("expected the dropped download to fail")
+	}
+
+	// A new process sees the partial download and picks it up
+	restarted, err := registry.New(config)
+	helpers.AssertNoError(t, err)
+	progress, err := restarted.Progress("whisper@1.0.0")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "interrompido", progress.Status)
+	helpers.AssertEqual(t, int64(len(weights)/4), progress.Current)
+	helpers.AssertEqual(t, int64(len(weights)), progress.Total)
+
+	model, err := restarted.Download(context.Background(), models.TypeWhisper, "1.0.0")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, checksum(weights), model.Info.Checksum)
+	helpers.AssertEqual(t, fmt.Sprintf("bytes=%d-", len(weights)/4), flaky.requests()[1])
+}
+
+func TestDownloadRestartsWhenTheFileChanges(t *testing.T) {
+	weights := helpers.ONNXModel(testWeights(256 << 10))
+	flaky, url := newFlakyServer(t, weights, 1)
+	flaky.mu.Lock()
+	flaky.manifest = false
+	flaky.mu.Unlock()
+
+	cfg := helpers.SetupTestEnv(t)
+	config := models.Config{CachePath: cfg.CacheDir, Sources: mirror(url), RetryBackoff: time.Millisecond}
+	reg, err := registry.New(config)
+	helpers.AssertNoError(t, err)
+	if _, err := reg.Download(context.Background(), models.TypeWhisper, "1.0.0"); err == nil {
+		t.Fatal("expected the dropped download to fail")
+	}
+
+	// A new upload doesn't match the partial file's ETag, so the server
+	// sends the whole file instead of splicing two versions together
+	updated := helpers.ONNXModel(bytes.Repeat([]byte("new weights "), 1000))
+	flaky.mu.Lock()
+	flaky.weights = updated
+	flaky.etag = `"v2"`
+	flaky.mu.Unlock()
+
+	model, err := reg.Download(context.Background(), models.TypeWhisper, "1.0.0")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, checksum(updated), model.Info.Checksum)
+}
@@ -0,0 +1,257 @@
+package models_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/internal/models/registry"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+func TestParseSize(t *testing.T) {
+	for input, expected := range map[string]int64{
+		"":        0,
+		"1024":    1024,
+		"10GB":    10_000_000_000,
+		"10GiB":   10 << 30,
+		"512 MiB": 512 << 20,
+		"1.5TB":   1_500_000_000_000,
+		"100kb":   100_000,
+	} {
+		size, err := models.ParseSize(input)
+		helpers.AssertNoError(t, err)
+		helpers.AssertEqual(t, expected, size)
+	}
+
+	for _, input := range []string{"10XB", "GB", "-1GB", "ten"} {
+		if _, err := models.ParseSize(input); err == nil {
+			t.Errorf("expected %q to be rejected", input)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	day := 24 * time.Hour
+	for input, expected := range map[string]time.Duration{
+		"":        0,
+		"30d":     30 * day,
+		"2w":      14 * day,
+		"1w3d12h": 10*day + 12*time.Hour,
+		"90m":     90 * time.Minute,
+		"1.5d":    36 * time.Hour,
+	} {
+		duration, err := models.ParseDuration(input)
+		helpers.AssertNoError(t, err)
+		helpers.AssertEqual(t, expected, duration)
+	}
+
+	for _, input := range []string{"3 days", "d", "30", "1y"} {
+		if _, err := models.ParseDuration(input); err == nil {
+			t.Errorf("expected %q to be rejected", input)
+		}
+	}
+}
+
+func TestCollectAppliesRetentionPolicy(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	day := 24 * time.Hour
+	for modelID, model := range map[string]struct {
+		size int
+		age  time.Duration
+	}{
+		"new":  {30, 0},
+		"mid":  {5, 2 * day},
+		"old":  {5, 40 * day},
+		"busy": {5, 50 * day},
+	} {
+		writeModel(t, cfg.CacheDir, modelID, helpers.ONNXModel(bytes.Repeat([]byte{1}, model.size)))
+		modified := time.Now().Add(-model.age)
+		helpers.AssertNoError(t, os.Chtimes(filepath.Join(cfg.CacheDir, modelID, "model.onnx"), modified, modified))
+	}
+
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir, MaxCacheSize: "20B", RetentionPeriod: "30d", KeepLatest: 1})
+	helpers.AssertNoError(t, err)
+	reg.SetInUse(func(modelID string) bool { return modelID == "busy" })
+
+	decisions, err := reg.Collect()
+	helpers.AssertNoError(t, err)
+	sort.Slice(decisions, func(i, j int) bool { return decisions[i].ModelID < decisions[j].ModelID })
+	helpers.AssertEqual(t, 4, len(decisions))
+
+	// Models outside the policy are removed unless in use or among the latest
+	expected := map[string]bool{"busy": false, "mid": true, "new": false, "old": true}
+	for _, decision := range decisions {
+		helpers.AssertEqual(t, expected[decision.ModelID], decision.Evicted)
+		if decision.Reason == "" || decision.String() == "" {
+			t.Errorf("expected decision for %s to explain itself", decision.ModelID)
+		}
+
+		_, err := reg.GetModel(decision.ModelID)
+		_, statErr := os.Stat(reg.Dir(decision.ModelID))
+		if decision.Evicted && (err == nil || !os.IsNotExist(statErr)) {
+			t.Errorf("expected %s to be removed", decision.ModelID)
+		}
+		if !decision.Evicted && (err != nil || statErr != nil) {
+			t.Errorf("expected %s to be kept: %v, %v", decision.ModelID, err, statErr)
+		}
+	}
+
+	// Using a model counts toward its retention
+	modified := time.Now().Add(-40 * day)
+	writeModel(t, cfg.CacheDir, "reused", helpers.ONNXModel([]byte("w")))
+	helpers.AssertNoError(t, os.Chtimes(filepath.Join(cfg.CacheDir, "reused", "model.onnx"), modified, modified))
+	reg, err = registry.New(models.Config{CachePath: cfg.CacheDir, RetentionPeriod: "30d"})
+	helpers.AssertNoError(t, err)
+	_, err = reg.GetModel("reused")
+	helpers.AssertNoError(t, err)
+	decisions, err = reg.Collect()
+	helpers.AssertNoError(t, err)
+	for _, decision := range decisions {
+		if decision.ModelID == "reused" {
+			t.Errorf("expected the recently used model to be kept, got %s", decision)
+		}
+	}
+}
+
+func TestCollectKeepsAliasTargets(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	modified := time.Now().Add(-40 * 24 * time.Hour)
+	for _, modelID := range []string{"whisper-base@1.2.0", "whisper-base@1.3.0", "wav2vec2", "stale"} {
+		writeModel(t, cfg.CacheDir, modelID, helpers.ONNXModel([]byte(modelID)))
+		helpers.AssertNoError(t, os.Chtimes(filepath.Join(cfg.CacheDir, modelID, "model.onnx"), modified, modified))
+	}
+
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir, RetentionPeriod: "30d"})
+	helpers.AssertNoError(t, err)
+	_, err = reg.SetAlias("default", "whisper-base@1.2.0")
+	helpers.AssertNoError(t, err)
+	_, err = reg.SetAlias("default", "wav2vec2")
+	helpers.AssertNoError(t, err)
+
+	decisions, err := reg.Collect()
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, 4, len(decisions))
+
+	// Pinned targets, including the ones kept for rollback, survive their
+	// retention; a family's latest version doesn't
+	expected := map[string]string{
+		"wav2vec2":           "alvo do apelido default",
+		"whisper-base@1.2.0": "alvo anterior do apelido default",
+		"whisper-base@1.3.0": "",
+		"stale":              "",
+	}
+	for _, decision := range decisions {
+		helpers.AssertEqual(t, expected[decision.ModelID], decision.KeptBecause)
+		helpers.AssertEqual(t, expected[decision.ModelID] == "", decision.Evicted)
+	}
+
+	_, err = reg.RollbackAlias("default")
+	helpers.AssertNoError(t, err)
+	model, err := reg.GetModel("default")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "whisper-base@1.2.0", model.ID)
+}
+
+func TestCollectEvictsTheOnlyVersionOfAFamily(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	writeModel(t, cfg.CacheDir, "whisper@1.0.0", helpers.ONNXModel([]byte("weights")))
+	modified := time.Now().Add(-40 * 24 * time.Hour)
+	helpers.AssertNoError(t, os.Chtimes(filepath.Join(cfg.CacheDir, "whisper@1.0.0", "model.onnx"), modified, modified))
+
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir, RetentionPeriod: "30d"})
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, true, reg.Aliases()[0].Implicit)
+
+	decisions, err := reg.Collect()
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, 1, len(decisions))
+	helpers.AssertEqual(t, true, decisions[0].Evicted)
+	if _, err := reg.GetModel("whisper"); err == nil {
+		t.Error("expected the evicted family to no longer resolve")
+	}
+}
+
+func TestRemoveRefusesProtectedModels(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	for _, modelID := range []string{"whisper-base@1.2.0", "whisper-base@1.3.0", "busy", "idle"} {
+		writeModel(t, cfg.CacheDir, modelID, helpers.ONNXModel([]byte(modelID)))
+	}
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+	busy := true
+	reg.SetInUse(func(modelID string) bool { return busy && modelID == "busy" })
+	_, err = reg.SetAlias("default", "whisper-base@1.2.0")
+	helpers.AssertNoError(t, err)
+	_, err = reg.SetAlias("default", "whisper-base@1.3.0")
+	helpers.AssertNoError(t, err)
+
+	for _, modelID := range []string{"busy", "whisper-base@1.2.0", "whisper-base@1.3.0"} {
+		if err := reg.Remove(modelID); !errors.Is(err, models.ErrInUse) {
+			t.Errorf("expected %s not to be removed, got %v", modelID, err)
+		}
+		if _, err := os.Stat(reg.Dir(modelID)); err != nil {
+			t.Errorf("expected %s to be kept on disk: %v", modelID, err)
+		}
+	}
+	helpers.AssertNoError(t, reg.Remove("idle"))
+
+	// Once nothing protects it, the model can go
+	busy = false
+	helpers.AssertNoError(t, reg.Remove("busy"))
+}
+
+func TestSessionsOpenedDuringRemovalKeepTheModel(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	for _, modelID := range []string{"collected", "removed"} {
+		writeModel(t, cfg.CacheDir, modelID, helpers.ONNXModel([]byte(modelID)))
+	}
+	modified := time.Now().Add(-40 * 24 * time.Hour)
+	helpers.AssertNoError(t, os.Chtimes(filepath.Join(cfg.CacheDir, "collected", "model.onnx"), modified, modified))
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir, RetentionPeriod: "30d"})
+	helpers.AssertNoError(t, err)
+
+	// A session opens after the first check, while the model is being removed
+	checks := map[string]int{}
+	reg.SetInUse(func(modelID string) bool {
+		checks[modelID]++
+		return checks[modelID] > 1
+	})
+
+	decisions, err := reg.Collect()
+	helpers.AssertNoError(t, err)
+	for _, decision := range decisions {
+		if decision.ModelID == "collected" && (decision.Evicted || decision.KeptBecause == "") {
+			t.Errorf("expected the model to be kept, got %s", decision)
+		}
+	}
+	if err := reg.Remove("removed"); !errors.Is(err, models.ErrInUse) {
+		t.Errorf("expected the model not to be removed, got %v", err)
+	}
+
+	for _, modelID := range []string{"collected", "removed"} {
+		helpers.AssertEqual(t, 2, checks[modelID])
+		if _, err := reg.GetModel(modelID); err != nil {
+			t.Errorf("expected %s to be kept: %v", modelID, err)
+		}
+	}
+}
+
+func TestInvalidRetentionPolicyIsRejected(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	for _, config := range []models.Config{
+		{CachePath: cfg.CacheDir, MaxCacheSize: "lots"},
+		{CachePath: cfg.CacheDir, RetentionPeriod: "a month"},
+		{CachePath: cfg.CacheDir, BackupRetention: "forever"},
+		{CachePath: cfg.CacheDir, KeepBackups: -1},
+	} {
+		if _, err := registry.New(config); err == nil {
+			t.Errorf("expected %+v to be rejected", config)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package models_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/internal/models/download"
+	"github.com/josealecrim/audiototext/internal/models/registry"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+func TestDownloadTriesSourcesInPriorityOrder(t *testing.T) {
+	// The mirror is tried first but only has whisper@2.0.0
+	var mu sync.Mutex
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path != "/whisper/2.0.0/model.onnx" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(helpers.ONNXModel([]byte("mirrored weights")))
+	}))
+	defer server.Close()
+
+	// An NFS share has whisper@1.0.0
+	share := t.TempDir()
+	helpers.AssertNoError(t, os.MkdirAll(filepath.Join(share, "whisper@1.0.0"), 0755))
+	helpers.AssertNoError(t, os.WriteFile(filepath.Join(share, "whisper@1.0.0", "model.onnx"), helpers.ONNXModel([]byte("shared weights")), 0644))
+
+	cfg := helpers.SetupTestEnv(t)
+	reg, err := registry.New(models.Config{
+		CachePath: cfg.CacheDir,
+		Sources: []models.SourceConfig{
+			{Name: "nfs", Kind: models.SourceDir, URL: share, Priority: 2},
+			{Name: "mirror", Kind: models.SourceHTTP, URL: server.URL, Path: "{type}/{version}/{file}", Priority: 1},
+		},
+	})
+	helpers.AssertNoError(t, err)
+
+	model, err := reg.Download(context.Background(), models.TypeWhisper, "1.0.0")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, checksum(helpers.ONNXModel([]byte("shared weights"))), model.Info.Checksum)
+	mu.Lock()
+	helpers.AssertEqual(t, "/whisper/1.0.0/manifest.json /whisper/1.0.0/model.onnx", strings.Join(requested, " "))
+	mu.Unlock()
+
+	model, err = reg.Download(context.Background(), models.TypeWhisper, "2.0.0")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, checksum(helpers.ONNXModel([]byte("mirrored weights"))), model.Info.Checksum)
+
+	// Downloads are versions of their type's family
+	helpers.AssertEqual(t, "whisper@2.0.0", model.ID)
+	latest, err := reg.GetModel("whisper")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "whisper@2.0.0", latest.ID)
+	if _, err := reg.Download(context.Background(), models.TypeWhisper, "v2"); err == nil {
+		t.Error("expected a non-semantic version to be rejected")
+	}
+
+	// A model no source has fails with every source's reason
+	_, err = reg.Download(context.Background(), models.TypeCTC, "1.0.0")
+	if !errors.Is(err, download.ErrNotFound) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+	for _, name := range []string{"mirror", "nfs"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected the error to mention source %s, got %v", name, err)
+		}
+	}
+}
+
+func TestHTTPSourceSendsAuthHeaders(t *testing.T) {
+	t.Setenv("MIRROR_TOKEN", "s3cr3t")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if filepath.Base(r.URL.Path) == "model.onnx" {
+			w.Write(helpers.ONNXModel([]byte("private weights")))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	cfg := helpers.SetupTestEnv(t)
+	source := models.SourceConfig{Kind: models.SourceHTTP, URL: server.URL, Headers: map[string]string{"Authorization": "Bearer $MIRROR_TOKEN"}}
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir, Sources: []models.SourceConfig{source}})
+	helpers.AssertNoError(t, err)
+
+	model, err := reg.Download(context.Background(), models.TypeWhisper, "1.0.0")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, checksum(helpers.ONNXModel([]byte("private weights"))), model.Info.Checksum)
+
+	// Without the token the mirror refuses the download
+	source.Headers = nil
+	reg, err = registry.New(models.Config{CachePath: t.TempDir(), Sources: []models.SourceConfig{source}})
+	helpers.AssertNoError(t, err)
+	if _, err := reg.Download(context.Background(), models.TypeWhisper, "1.0.0"); err == nil {
+		t.Fatal("expected the download to be refused without credentials")
+	}
+}
+
+func TestS3SourceSignsRequests(t *testing.T) {
+	weights := helpers.ONNXModel([]byte("bucket weights"))
+	var mu sync.Mutex
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		authorization = r.Header.Get("Authorization")
+		mu.Unlock()
+		if r.Header.Get("X-Amz-Date") == "" || r.Header.Get("X-Amz-Content-Sha256") != "UNSIGNED-PAYLOAD" {
+			http.Error(w, "missing signature headers", http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/models/prod/whisper@1.0.0/model.onnx" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", `"abc123"`)
+		http.ServeContent(w, r, "model.onnx", time.Time{}, bytes.NewReader(weights))
+	}))
+	defer server.Close()
+
+	cfg := helpers.SetupTestEnv(t)
+	reg, err := registry.New(models.Config{
+		CachePath: cfg.CacheDir,
+		Sources: []models.SourceConfig{{
+			Kind:      models.SourceS3,
+			URL:       server.URL,
+			Bucket:    "models",
+			Path:      "prod/{id}/{file}",
+			Region:    "sa-east-1",
+			AccessKey: "AKIDEXAMPLE",
+			SecretKey: "secret",
+		}},
+	})
+	helpers.AssertNoError(t, err)
+
+	model, err := reg.Download(context.Background(), models.TypeWhisper, "1.0.0")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, checksum(weights), model.Info.Checksum)
+
+	mu.Lock()
+	defer mu.Unlock()
+	prefix := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/" + time.Now().UTC().Format("20060102") + "/sa-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="
+	if !strings.HasPrefix(authorization, prefix) || len(authorization) != len(prefix)+64 {
+		t.Errorf("unexpected Authorization header %q", authorization)
+	}
+}
+
+func TestInvalidSourcesAreRejected(t *testing.T) {
+	for name, source := range map[string]models.SourceConfig{
+		"unknown kind":      {Kind: "ftp", URL: "ftp://mirror"},
+		"missing URL":       {Kind: models.SourceHTTP},
+		"missing S3 bucket": {Kind: models.SourceS3, URL: "http://minio:9000"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := registry.New(models.Config{CachePath: t.TempDir(), Sources: []models.SourceConfig{source}})
+			if err == nil {
+				t.Error("expected the registry to reject the source")
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package resultcache_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/resultcache"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+func TestKeyCoversAudioModelAndSettings(t *testing.T) {
+	samples := []float32{0.1, 0.2, 0.3}
+	key := resultcache.Key(samples, "whisper", "sha256:aa", []byte("pt-BR"))
+
+	helpers.AssertEqual(t, key, resultcache.Key([]float32{0.1, 0.2, 0.3}, "whisper", "sha256:aa", []byte("pt-BR")))
+	for name, other := range map[string]string{
+		"audio":    resultcache.Key([]float32{0.1, 0.2, 0.4}, "whisper", "sha256:aa", []byte("pt-BR")),
+		"model":    resultcache.Key(samples, "whisper-small", "sha256:aa", []byte("pt-BR")),
+		"checksum": resultcache.Key(samples, "whisper", "sha256:bb", []byte("pt-BR")),
+		"settings": resultcache.Key(samples, "whisper", "sha256:aa", []byte("en-US")),
+	} {
+		if other == key {
+			t.Errorf("changing the %s kept the same key", name)
+		}
+	}
+
+	// Audio longer than a hashing block still covers every sample
+	long := make([]float32, 5000)
+	longKey := resultcache.Key(long, "whisper", "sha256:aa", nil)
+	helpers.AssertEqual(t, longKey, resultcache.Key(make([]float32, 5000), "whisper", "sha256:aa", nil))
+	long[len(long)-1] = 1
+	if resultcache.Key(long, "whisper", "sha256:aa", nil) == longKey {
+		t.Error("changing the last sample kept the same key")
+	}
+	if resultcache.Key(long[:4999], "whisper", "sha256:aa", nil) == longKey {
+		t.Error("dropping the last sample kept the same key")
+	}
+}
+
+func TestMemoryTierEvictsLeastRecentlyUsed(t *testing.T) {
+	cache, err := resultcache.New(resultcache.Config{MaxEntries: 2})
+	helpers.AssertNoError(t, err)
+
+	helpers.AssertNoError(t, cache.Put("a", []byte("1")))
+	helpers.AssertNoError(t, cache.Put("b", []byte("2")))
+	cache.Get("a")
+	helpers.AssertNoError(t, cache.Put("c", []byte("3")))
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("expected the least recently used entry to be evicted")
+	}
+	value, ok := cache.Get("a")
+	helpers.AssertEqual(t, true, ok)
+	helpers.AssertEqual(t, "1", string(value))
+
+	stats := cache.Stats()
+	helpers.AssertEqual(t, int64(2), stats.Hits)
+	helpers.AssertEqual(t, int64(1), stats.Misses)
+	helpers.AssertEqual(t, 2, stats.Entries)
+}
+
+func TestEntriesExpire(t *testing.T) {
+	cache, err := resultcache.New(resultcache.Config{TTL: 50 * time.Millisecond, Dir: t.TempDir()})
+	helpers.AssertNoError(t, err)
+
+	helpers.AssertNoError(t, cache.Put("a", []byte("1")))
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("expected a fresh entry to be cached")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := cache.Get("a"); ok {
+		t.Error("expected the entry to expire")
+	}
+	helpers.AssertEqual(t, int64(0), cache.Stats().DiskBytes)
+}
+
+func TestDiskTierSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := resultcache.New(resultcache.Config{Dir: dir, MaxDiskBytes: 10})
+	helpers.AssertNoError(t, err)
+
+	helpers.AssertNoError(t, cache.Put("old", []byte("12345")))
+	time.Sleep(10 * time.Millisecond)
+	helpers.AssertNoError(t, cache.Put("new", []byte("67890")))
+	time.Sleep(10 * time.Millisecond)
+	// Past the disk limit, the oldest entry goes
+	helpers.AssertNoError(t, cache.Put("newest", []byte("abc")))
+
+	restarted, err := resultcache.New(resultcache.Config{Dir: dir, MaxDiskBytes: 10})
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, int64(8), restarted.Stats().DiskBytes)
+
+	if _, ok := restarted.Get("old"); ok {
+		t.Error("expected the oldest entry to be evicted from disk")
+	}
+	value, ok := restarted.Get("new")
+	helpers.AssertEqual(t, true, ok)
+	helpers.AssertEqual(t, "67890", string(value))
+	// The disk hit is now in memory
+	helpers.AssertEqual(t, 1, restarted.Stats().Entries)
+}
+
+func TestDiskTierDropsUnfinishedWrites(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := resultcache.New(resultcache.Config{Dir: dir, MaxDiskBytes: 1 << 20})
+	helpers.AssertNoError(t, err)
+	helpers.AssertNoError(t, cache.Put("done", []byte("12345")))
+
+	// A write interrupted before its rename leaves only a temporary file
+	partial := filepath.Join(dir, "partial.result.123.tmp")
+	helpers.AssertNoError(t, os.WriteFile(partial, []byte("12"), 0644))
+
+	restarted, err := resultcache.New(resultcache.Config{Dir: dir, MaxDiskBytes: 1 << 20})
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, int64(5), restarted.Stats().DiskBytes)
+	if _, err := os.Stat(partial); !os.IsNotExist(err) {
+		t.Errorf("expected the unfinished write to be removed, got %v", err)
+	}
+
+	files, err := os.ReadDir(dir)
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, 1, len(files))
+}
+
+func TestDiskTierStaysConsistentUnderConcurrency(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := resultcache.New(resultcache.Config{Dir: dir, MaxEntries: 4, MaxDiskBytes: 200})
+	helpers.AssertNoError(t, err)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				key := fmt.Sprintf("w%d-%d", worker, i)
+				value := []byte(key + "-value")
+				if err := cache.Put(key, value); err != nil {
+					t.Error(err)
+					return
+				}
+				// Older keys are read back from disk while others write and evict
+				older := fmt.Sprintf("w%d-%d", worker, i/2)
+				if got, ok := cache.Get(older); ok && string(got) != older+"-value" {
+					t.Errorf("expected %s to hold its own value, got %q", older, got)
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	// The accounted size matches the files left on disk
+	files, err := os.ReadDir(dir)
+	helpers.AssertNoError(t, err)
+	var size int64
+	for _, file := range files {
+		info, err := file.Info()
+		helpers.AssertNoError(t, err)
+		size += info.Size()
+	}
+	helpers.AssertEqual(t, size, cache.Stats().DiskBytes)
+	if size > 200 {
+		t.Errorf("expected the disk tier to stay within its limit, got %d bytes", size)
+	}
+}
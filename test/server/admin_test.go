@@ -0,0 +1,74 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/internal/models/registry"
+	"github.com/josealecrim/audiototext/internal/server"
+	"github.com/josealecrim/audiototext/test/helpers"
+)
+
+// adminRequest sends a request to the admin endpoints and decodes the JSON
+// response into out, returning the status code
+func adminRequest(t *testing.T, url, method, path, body string, out interface{}) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url+path, strings.NewReader(body))
+	helpers.AssertNoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	helpers.AssertNoError(t, err)
+	defer resp.Body.Close()
+
+	if out != nil && resp.StatusCode < 300 {
+		helpers.AssertNoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp.StatusCode
+}
+
+func TestAdminRetargetsAndRollsBackAliases(t *testing.T) {
+	cfg := helpers.SetupTestEnv(t)
+	for _, modelID := range []string{"whisper-base@1.2.0", "whisper-base@1.3.0"} {
+		dir := filepath.Join(cfg.CacheDir, modelID)
+		helpers.AssertNoError(t, os.MkdirAll(dir, 0755))
+		helpers.AssertNoError(t, os.WriteFile(filepath.Join(dir, "model.onnx"), helpers.ONNXModel([]byte(modelID)), 0644))
+	}
+	reg, err := registry.New(models.Config{CachePath: cfg.CacheDir})
+	helpers.AssertNoError(t, err)
+
+	admin := httptest.NewServer(server.AdminHandler(reg))
+	defer admin.Close()
+
+	var aliases []models.Alias
+	helpers.AssertEqual(t, http.StatusOK, adminRequest(t, admin.URL, http.MethodGet, "/admin/aliases", "", &aliases))
+	helpers.AssertEqual(t, 1, len(aliases))
+	helpers.AssertEqual(t, "whisper-base@1.3.0", aliases[0].Target)
+
+	var alias models.Alias
+	helpers.AssertEqual(t, http.StatusOK, adminRequest(t, admin.URL, http.MethodPut, "/admin/aliases/default", `{"target": "whisper-base@1.3.0"}`, &alias))
+	helpers.AssertEqual(t, "whisper-base@1.3.0", alias.Target)
+	model, err := reg.GetModel("default")
+	helpers.AssertNoError(t, err)
+	helpers.AssertEqual(t, "whisper-base@1.3.0", model.ID)
+
+	helpers.AssertEqual(t, http.StatusOK, adminRequest(t, admin.URL, http.MethodPost, "/admin/aliases/default/rollback", "", &alias))
+	helpers.AssertEqual(t, "whisper-base@1.2.0", alias.Target)
+	helpers.AssertEqual(t, http.StatusOK, adminRequest(t, admin.URL, http.MethodGet, "/admin/aliases/default", "", &alias))
+	helpers.AssertEqual(t, "whisper-base@1.2.0", alias.Target)
+
+	helpers.AssertEqual(t, http.StatusNoContent, adminRequest(t, admin.URL, http.MethodDelete, "/admin/aliases/default", "", nil))
+	helpers.AssertEqual(t, http.StatusNotFound, adminRequest(t, admin.URL, http.MethodGet, "/admin/aliases/default", "", nil))
+
+	// Bad requests are rejected without touching the aliases
+	helpers.AssertEqual(t, http.StatusNotFound, adminRequest(t, admin.URL, http.MethodPut, "/admin/aliases/default", `{"target": "missing@1.0.0"}`, nil))
+	helpers.AssertEqual(t, http.StatusBadRequest, adminRequest(t, admin.URL, http.MethodPut, "/admin/aliases/default", `not json`, nil))
+	helpers.AssertEqual(t, http.StatusBadRequest, adminRequest(t, admin.URL, http.MethodPut, "/admin/aliases/bad@name", `{"target": "whisper-base@1.2.0"}`, nil))
+	helpers.AssertEqual(t, http.StatusMethodNotAllowed, adminRequest(t, admin.URL, http.MethodPost, "/admin/aliases", "", nil))
+	helpers.AssertEqual(t, 1, len(reg.Aliases()))
+}
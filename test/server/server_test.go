@@ -0,0 +1,431 @@
+package server_test
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josealecrim/audiototext/internal/hardware"
+	"github.com/josealecrim/audiototext/internal/inference"
+	"github.com/josealecrim/audiototext/internal/inference/fake"
+	"github.com/josealecrim/audiototext/internal/models"
+	"github.com/josealecrim/audiototext/internal/resultcache"
+	"github.com/josealecrim/audiototext/internal/server"
+	pb "github.com/josealecrim/audiototext/pkg/transcription"
+	"github.com/josealecrim/audiototext/test/helpers"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	testModelID  = "fake-model"
+	largeModelID = "large-model"
+	bareModelID  = "bare-model"
+)
+
+// catalog serves a fixed set of models
+type catalog map[string]*models.ONNXModel
+
+func (c catalog) GetModel(modelID string) (*models.ONNXModel, error) {
+	model, ok := c[modelID]
+	if !ok {
+		return nil, errors.Errorf("model %s not found", modelID)
+	}
+	return model, nil
+}
+
+func (c catalog) ListModels() ([]*models.ONNXModel, error) {
+	list := make([]*models.ONNXModel, 0, len(c))
+	for _, model := range c {
+		list = append(list, model)
+	}
+	return list, nil
+}
+
+// startServer serves a transcription server backed by backend over an
+// in-memory connection and returns a client for it; configure functions
+// run on the server before it is warmed up
+func startServer(t *testing.T, backend *fake.Backend, configure ...func(*server.Server)) pb.TranscriptionServiceClient {
+	t.Helper()
+
+	detector, err := hardware.NewDetector()
+	helpers.AssertNoError(t, err)
+
+	manager := inference.NewManager(detector)
+	manager.SetRuntimeFactory(backend.Factory())
+	manager.SetBatchConfig(inference.BatchConfig{
+		MaxBatchSize:        4,
+		DynamicBatching:     true,
+		MaxLatencyMs:        10,
+		PreferredBatchSizes: []int{1, 4},
+	})
+	t.Cleanup(func() { manager.CloseAllSessions() })
+
+	models := catalog{
+		testModelID: {
+			Model: &models.Model{
+				Path:     "models/fake.onnx",
+				Info:     models.ModelInfo{Version: "1.0.0"},
+				Manifest: &models.Manifest{Name: "Fake", Languages: []string{"pt-BR"}, SampleRate: 16000},
+			},
+			ID: testModelID,
+		},
+		// largeModelID never fits in the memory budget
+		largeModelID: {
+			Model: &models.Model{
+				Path:     "models/large.onnx",
+				Info:     models.ModelInfo{Size: 1 << 50},
+				Manifest: &models.Manifest{Name: "Large", Languages: []string{"en"}, SampleRate: 16000},
+			},
+			ID: largeModelID,
+		},
+		// bareModelID is a handle whose model was never loaded
+		bareModelID: {ID: bareModelID},
+	}
+	srv := server.NewServer(manager, models)
+	for _, f := range configure {
+		f(srv)
+	}
+	helpers.AssertNoError(t, srv.Warmup(context.Background(), []string{testModelID}))
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	pb.RegisterTranscriptionServiceServer(grpcServer, srv)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	helpers.AssertNoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewTranscriptionServiceClient(conn)
+}
+
+// pcm16 encodes n samples of a constant level as headerless 16-bit PCM
+func pcm16(n int, level int16) []byte {
+	data := make([]byte, 2*n)
+	for i := 0; i < n; i++ {
+		binary.LittleEndian.PutUint16(data[2*i:], uint16(level))
+	}
+	return data
+}
+
+func TestTranscribeEndToEnd(t *testing.T) {
+	backend := fake.New(fake.Config{Latency: 5 * time.Millisecond})
+	backend.AddRange(16000, 16000, fake.Script{Text: "primeira janela"})
+	client := startServer(t, backend)
+
+	helpers.WithTimeout(t, 30*time.Second, func(ctx context.Context) {
+		response, err := client.Transcribe(ctx, &pb.TranscribeRequest{
+			AudioData: pcm16(16000, 1000),
+			Format:    pb.AudioFormat_AUDIO_FORMAT_WAV,
+			Config:    &pb.TranscriptionConfig{ModelId: testModelID, Language: "pt-BR"},
+		})
+		helpers.AssertNoError(t, err)
+
+		helpers.AssertEqual(t, "primeira janela", response.Text)
+		helpers.AssertEqual(t, 1, len(response.Segments))
+		helpers.AssertEqual(t, float32(1), response.Segments[0].EndTime)
+
+		// The response names the exact model version that served it
+		helpers.AssertEqual(t, testModelID, response.Metadata["model_id"])
+		helpers.AssertEqual(t, "1.0.0", response.Metadata["model_version"])
+	})
+}
+
+// streamChunks sends the chunks, 16 kHz mono PCM16 sample counts, over a
+// stream and returns every response received
+func streamChunks(t *testing.T, ctx context.Context, client pb.TranscriptionServiceClient, interim bool, chunks ...int) []*pb.TranscribeResponse {
+	t.Helper()
+
+	stream, err := client.TranscribeStream(ctx)
+	helpers.AssertNoError(t, err)
+
+	config := &pb.TranscriptionConfig{ModelId: testModelID, Language: "en-US", EnableInterimResults: interim}
+	for i, n := range chunks {
+		request := &pb.TranscribeRequest{AudioData: pcm16(n, int16(100*(i+1)))}
+		if i == 0 {
+			request.Config = config
+		}
+		helpers.AssertNoError(t, stream.Send(request))
+
+		// Let each chunk be decoded on its own
+		time.Sleep(100 * time.Millisecond)
+	}
+	helpers.AssertNoError(t, stream.CloseSend())
+
+	var responses []*pb.TranscribeResponse
+	for {
+		response, err := stream.Recv()
+		if err == io.EOF {
+			return responses
+		}
+		helpers.AssertNoError(t, err)
+		responses = append(responses, response)
+	}
+}
+
+// finalTexts checks final results advance monotonically and returns their texts
+func finalTexts(t *testing.T, responses []*pb.TranscribeResponse) []string {
+	t.Helper()
+
+	var texts []string
+	var end float32
+	for _, response := range responses {
+		if !response.IsFinal {
+			continue
+		}
+		segment := response.Segments[0]
+		if segment.StartTime != end || segment.EndTime < segment.StartTime {
+			t.Errorf("final %q spans %.2f-%.2f after %.2f", response.Text, segment.StartTime, segment.EndTime, end)
+		}
+		end = segment.EndTime
+		texts = append(texts, response.Text)
+	}
+	return texts
+}
+
+func TestTranscribeStreamLocalAgreement(t *testing.T) {
+	// The buffer is re-decoded as it grows by 8000 samples per chunk
+	backend := fake.New(fake.Config{})
+	backend.AddRange(8000, 8000, fake.Script{Text: "the quick"})
+	backend.AddRange(16000, 16000, fake.Script{Text: "the quick brown"})
+	backend.AddRange(24000, 24000, fake.Script{Text: "the quick brown fox"})
+	client := startServer(t, backend)
+
+	helpers.WithTimeout(t, 30*time.Second, func(ctx context.Context) {
+		responses := streamChunks(t, ctx, client, true, 8000, 8000, 8000)
+
+		var interim []string
+		for _, response := range responses {
+			if !response.IsFinal {
+				interim = append(interim, response.Text)
+			}
+		}
+		helpers.AssertEqual(t, "the quick|brown|fox", strings.Join(interim, "|"))
+		helpers.AssertEqual(t, "the quick|brown|fox", strings.Join(finalTexts(t, responses), "|"))
+
+		last := responses[len(responses)-1]
+		helpers.AssertEqual(t, true, last.IsFinal)
+		helpers.AssertEqual(t, float32(1.5), last.Segments[0].EndTime)
+		helpers.AssertEqual(t, "1.0.0", last.Metadata["model_version"])
+	})
+}
+
+func TestTranscribeStreamTrimsFinalAudio(t *testing.T) {
+	// Once two hypotheses agree on the whole text, the audio behind it is
+	// trimmed, so the third chunk is decoded from a 12000 sample buffer
+	backend := fake.New(fake.Config{})
+	backend.AddRange(8000, 8000, fake.Script{Text: "hello"})
+	backend.AddRange(16000, 16000, fake.Script{Text: "hello"})
+	backend.AddRange(12000, 12000, fake.Script{Text: "world"})
+	client := startServer(t, backend)
+
+	helpers.WithTimeout(t, 30*time.Second, func(ctx context.Context) {
+		responses := streamChunks(t, ctx, client, false, 8000, 8000, 4000)
+
+		for _, response := range responses {
+			if !response.IsFinal {
+				t.Errorf("unexpected interim result %q", response.Text)
+			}
+		}
+		helpers.AssertEqual(t, "hello|world", strings.Join(finalTexts(t, responses), "|"))
+		helpers.AssertEqual(t, float32(1.25), responses[len(responses)-1].Segments[0].EndTime)
+	})
+}
+
+func TestTranscribeStreamReportsFinalWindowFailure(t *testing.T) {
+	backend := fake.New(fake.Config{})
+	backend.AddRange(8000, 8000, fake.Script{Text: "the quick"})
+	backend.AddRange(16000, 16000, fake.Script{Err: errors.New("decoder crashed")})
+	client := startServer(t, backend)
+
+	helpers.WithTimeout(t, 30*time.Second, func(ctx context.Context) {
+		stream, err := client.TranscribeStream(ctx)
+		helpers.AssertNoError(t, err)
+
+		config := &pb.TranscriptionConfig{ModelId: testModelID, Language: "en-US"}
+		helpers.AssertNoError(t, stream.Send(&pb.TranscribeRequest{AudioData: pcm16(8000, 100), Config: config}))
+		time.Sleep(100 * time.Millisecond)
+		helpers.AssertNoError(t, stream.Send(&pb.TranscribeRequest{AudioData: pcm16(8000, 200)}))
+		helpers.AssertNoError(t, stream.CloseSend())
+
+		// The stream ends with the failure instead of a silent OK
+		for {
+			_, err = stream.Recv()
+			if err != nil {
+				break
+			}
+		}
+		helpers.AssertEqual(t, codes.Internal, status.Code(err))
+	})
+}
+
+func TestCanceledStreamsStopTheirGoroutines(t *testing.T) {
+	backend := fake.New(fake.Config{})
+	backend.SetDefault(fake.Script{Text: "hello"})
+	client := startServer(t, backend)
+
+	helpers.WithTimeout(t, 30*time.Second, func(ctx context.Context) {
+		// A finished stream first, so the connection's own goroutines are running
+		streamChunks(t, ctx, client, false, 8000)
+		before := runtime.NumGoroutine()
+
+		config := &pb.TranscriptionConfig{ModelId: testModelID, Language: "en-US"}
+		for i := 0; i < 5; i++ {
+			streamCtx, cancel := context.WithCancel(ctx)
+			stream, err := client.TranscribeStream(streamCtx)
+			helpers.AssertNoError(t, err)
+			helpers.AssertNoError(t, stream.Send(&pb.TranscribeRequest{AudioData: pcm16(8000, 100), Config: config}))
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for runtime.NumGoroutine() > before {
+			if time.Now().After(deadline) {
+				t.Fatalf("canceled streams left %d goroutines running", runtime.NumGoroutine()-before)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
+
+func TestTranscribeCachesRepeatedAudio(t *testing.T) {
+	backend := fake.New(fake.Config{Latency: 200 * time.Millisecond})
+	backend.SetDefault(fake.Script{Text: "gravação repetida"})
+
+	cache, err := resultcache.New(resultcache.Config{MaxEntries: 10})
+	helpers.AssertNoError(t, err)
+	client := startServer(t, backend, func(srv *server.Server) { srv.SetResultCache(cache) })
+
+	helpers.WithTimeout(t, 30*time.Second, func(ctx context.Context) {
+		config := &pb.TranscriptionConfig{ModelId: testModelID, Language: "pt-BR"}
+		transcribe := func(config *pb.TranscriptionConfig) (*pb.TranscribeResponse, time.Duration) {
+			start := time.Now()
+			response, err := client.Transcribe(ctx, &pb.TranscribeRequest{AudioData: pcm16(16000, 700), Config: config})
+			helpers.AssertNoError(t, err)
+			return response, time.Since(start)
+		}
+
+		first, _ := transcribe(config)
+		helpers.AssertEqual(t, "false", first.Metadata["cache_hit"])
+		calls := backend.Calls()
+
+		second, elapsed := transcribe(config)
+		helpers.AssertEqual(t, "true", second.Metadata["cache_hit"])
+		helpers.AssertEqual(t, first.Text, second.Text)
+		helpers.AssertEqual(t, calls, backend.Calls())
+		if elapsed >= 200*time.Millisecond {
+			t.Errorf("cache hit took %v, longer than inference", elapsed)
+		}
+
+		// Other settings and the bypass flag go to the model
+		other, _ := transcribe(&pb.TranscriptionConfig{ModelId: testModelID, Language: "pt-BR", InitialPrompt: "gravação"})
+		helpers.AssertEqual(t, "false", other.Metadata["cache_hit"])
+		bypassed, _ := transcribe(&pb.TranscriptionConfig{ModelId: testModelID, Language: "pt-BR", BypassCache: true})
+		helpers.AssertEqual(t, "false", bypassed.Metadata["cache_hit"])
+		helpers.AssertEqual(t, calls+2, backend.Calls())
+	})
+}
+
+func TestTranscribeReturnsAlternatives(t *testing.T) {
+	backend := fake.New(fake.Config{})
+	backend.SetDefault(fake.Script{
+		Text:         "bom dia",
+		Confidences:  []float64{0.9, 0.8},
+		Alternatives: []fake.Script{{Text: "bom tia", Confidences: []float64{0.9, 0.4}}},
+	})
+	client := startServer(t, backend)
+
+	helpers.WithTimeout(t, 30*time.Second, func(ctx context.Context) {
+		response, err := client.Transcribe(ctx, &pb.TranscribeRequest{
+			AudioData: pcm16(16000, 1000),
+			Config:    &pb.TranscriptionConfig{ModelId: testModelID, Language: "pt-BR", MaxAlternatives: 2},
+		})
+		helpers.AssertNoError(t, err)
+
+		helpers.AssertEqual(t, "bom dia", response.Text)
+		helpers.AssertEqual(t, 2, len(response.Segments[0].Alternatives))
+		helpers.AssertEqual(t, "bom tia", response.Segments[0].Alternatives[1].Text)
+		helpers.AssertEqual(t, 2, len(response.Alternatives))
+		helpers.AssertEqual(t, "bom dia", response.Alternatives[0].Text)
+		helpers.AssertEqual(t, "bom tia", response.Alternatives[1].Text)
+		if response.Alternatives[1].Confidence >= response.Alternatives[0].Confidence {
+			t.Errorf("expected the second alternative to be less confident, got %.3f >= %.3f",
+				response.Alternatives[1].Confidence, response.Alternatives[0].Confidence)
+		}
+
+		_, err = client.Transcribe(ctx, &pb.TranscribeRequest{
+			AudioData: pcm16(16000, 1000),
+			Config:    &pb.TranscriptionConfig{ModelId: testModelID, MaxAlternatives: 11},
+		})
+		helpers.AssertEqual(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestTranscribeRejectsModelsOverMemoryBudget(t *testing.T) {
+	client := startServer(t, fake.New(fake.Config{}))
+
+	helpers.WithTimeout(t, 30*time.Second, func(ctx context.Context) {
+		_, err := client.Transcribe(ctx, &pb.TranscribeRequest{
+			AudioData: pcm16(16000, 1000),
+			Config:    &pb.TranscriptionConfig{ModelId: largeModelID, Language: "pt-BR"},
+		})
+		helpers.AssertEqual(t, codes.ResourceExhausted, status.Code(err))
+
+		// The loaded model keeps serving
+		_, err = client.Transcribe(ctx, &pb.TranscribeRequest{
+			AudioData: pcm16(16000, 1000),
+			Config:    &pb.TranscriptionConfig{ModelId: testModelID, Language: "pt-BR"},
+		})
+		helpers.AssertNoError(t, err)
+	})
+}
+
+func TestGetModelsFiltersByLanguage(t *testing.T) {
+	client := startServer(t, fake.New(fake.Config{}))
+
+	helpers.WithTimeout(t, 30*time.Second, func(ctx context.Context) {
+		for filter, expected := range map[string][]string{
+			"":      {bareModelID, testModelID, largeModelID},
+			"pt":    {bareModelID, testModelID},
+			"en-US": {bareModelID, largeModelID},
+			"de":    {bareModelID},
+		} {
+			response, err := client.GetModels(ctx, &pb.GetModelsRequest{LanguageFilter: filter})
+			helpers.AssertNoError(t, err)
+
+			var ids []string
+			for _, model := range response.Models {
+				ids = append(ids, model.Id)
+			}
+			sort.Strings(ids)
+			if strings.Join(ids, ",") != strings.Join(expected, ",") {
+				t.Errorf("filter %q: expected models %v, got %v", filter, expected, ids)
+			}
+		}
+
+		// A bare handle has no languages, so it serves any of them
+		response, err := client.GetModels(ctx, &pb.GetModelsRequest{LanguageFilter: "pt-BR"})
+		helpers.AssertNoError(t, err)
+		sort.Slice(response.Models, func(i, j int) bool { return response.Models[i].Id > response.Models[j].Id })
+		helpers.AssertEqual(t, "Fake", response.Models[0].Name)
+		helpers.AssertEqual(t, int32(16000), response.Models[0].SampleRate)
+		helpers.AssertEqual(t, bareModelID, response.Models[1].Name)
+	})
+}